@@ -17,61 +17,77 @@ ON CONFLICT (id) DO NOTHING;
 -- ===================
 -- Products
 -- ===================
-INSERT INTO products (id, name, description, price, inventory, available) VALUES
-('1', 'MacBook Pro', '14-inch MacBook Pro with M3 chip', 1999.99, 10, true),
-('2', 'iPhone 15', 'Latest iPhone with A17 chip', 999.99, 25, true),
-('3', 'AirPods Pro', 'Wireless earbuds with noise cancellation', 249.99, 50, true),
-('4', 'iPad Pro', '12.9-inch iPad Pro with M2 chip', 1099.99, 15, true),
-('5', 'Apple Watch', 'Series 9 GPS + Cellular', 499.99, 30, true),
-('6', 'Magic Keyboard', 'Wireless keyboard for Mac', 199.99, 20, true),
-('7', 'Studio Display', '27-inch 5K Retina display', 1599.99, 0, false),
-('8', 'AirTag', 'Bluetooth tracking device', 29.99, 100, true),
-('9', 'HomePod mini', 'Smart speaker with Siri', 99.99, 25, true),
-('10', 'Mac Studio', 'Compact pro desktop with M2 Max', 3999.99, 5, true),
-('11', 'iPhone 14', 'Previous generation iPhone', 699.99, 40, true),
-('12', 'MacBook Air M2', '13-inch lightweight laptop', 1199.99, 12, true),
-('13', 'Magic Mouse', 'Wireless multi-touch mouse', 79.99, 35, true),
-('14', 'Apple Pencil', '2nd generation stylus for iPad', 129.99, 45, true),
-('15', 'Mac mini', 'Compact desktop computer', 599.99, 18, true)
+-- Prices are in cents (USD)
+INSERT INTO products (id, name, description, price_amount, inventory, available) VALUES
+('1', 'MacBook Pro', '14-inch MacBook Pro with M3 chip', 199999, 10, true),
+('2', 'iPhone 15', 'Latest iPhone with A17 chip', 99999, 25, true),
+('3', 'AirPods Pro', 'Wireless earbuds with noise cancellation', 24999, 50, true),
+('4', 'iPad Pro', '12.9-inch iPad Pro with M2 chip', 109999, 15, true),
+('5', 'Apple Watch', 'Series 9 GPS + Cellular', 49999, 30, true),
+('6', 'Magic Keyboard', 'Wireless keyboard for Mac', 19999, 20, true),
+('7', 'Studio Display', '27-inch 5K Retina display', 159999, 0, false),
+('8', 'AirTag', 'Bluetooth tracking device', 2999, 100, true),
+('9', 'HomePod mini', 'Smart speaker with Siri', 9999, 25, true),
+('10', 'Mac Studio', 'Compact pro desktop with M2 Max', 399999, 5, true),
+('11', 'iPhone 14', 'Previous generation iPhone', 69999, 40, true),
+('12', 'MacBook Air M2', '13-inch lightweight laptop', 119999, 12, true),
+('13', 'Magic Mouse', 'Wireless multi-touch mouse', 7999, 35, true),
+('14', 'Apple Pencil', '2nd generation stylus for iPad', 12999, 45, true),
+('15', 'Mac mini', 'Compact desktop computer', 59999, 18, true)
 ON CONFLICT (id) DO NOTHING;
 
+-- ===================
+-- Product variants
+-- ===================
+-- One variant per product, with the product ID as SKU
+INSERT INTO product_variants (id, product_id, sku, inventory, available)
+SELECT 'var_' || id, id, id, inventory, available FROM products
+ON CONFLICT DO NOTHING;
+
+-- Opening stock, so every variant's ledger adds up to its inventory
+INSERT INTO inventory_ledger (id, sku, product_id, kind, quantity, change, balance, reason, actor)
+SELECT 'led_opening_' || id, sku, product_id, 'ADJUSTMENT', inventory, inventory, inventory, 'opening balance', 'system'
+FROM product_variants
+WHERE inventory > 0
+ON CONFLICT DO NOTHING;
+
 -- ===================
 -- Orders
 -- ===================
-INSERT INTO orders (id, user_id, quantity, total_price, status, created_at) VALUES
-('1', '1', 1, 1999.99, 'completed', NOW()),
-('2', '2', 2, 1999.98, 'pending', NOW()),
-('3', '3', 1, 249.99, 'shipped', NOW()),
-('4', '4', 1, 1099.99, 'shipped', NOW()),
-('5', '5', 2, 999.98, 'completed', NOW()),
-('6', '6', 1, 199.99, 'pending', NOW()),
-('7', '4', 1, 1599.99, 'completed', NOW()),
-('8', '8', 4, 119.96, 'shipped', NOW()),
-('9', '9', 1, 99.99, 'cancelled', NOW()),
-('10', '10', 1, 3999.99, 'pending', NOW())
+INSERT INTO orders (id, user_id, total_price_amount, status, created_at) VALUES
+('1', '1', 199999, 'DELIVERED', NOW()),
+('2', '2', 224997, 'PENDING', NOW()),
+('3', '3', 24999, 'SHIPPED', NOW()),
+('4', '4', 109999, 'SHIPPED', NOW()),
+('5', '5', 99998, 'DELIVERED', NOW()),
+('6', '6', 19999, 'PENDING', NOW()),
+('7', '4', 159999, 'DELIVERED', NOW()),
+('8', '8', 11996, 'SHIPPED', NOW()),
+('9', '9', 9999, 'CANCELLED', NOW()),
+('10', '10', 399999, 'PENDING', NOW())
 ON CONFLICT (id) DO NOTHING;
 
 -- ===================
--- Order ↔ Product associations
+-- Order line items
 -- ===================
-INSERT INTO order_products (order_id, product_id) VALUES
-('1', '1'),
+INSERT INTO order_line_items (id, order_id, product_id, sku, quantity, unit_price_amount) VALUES
+('1_1', '1', '1', '1', 1, 199999),
 
-('2', '2'),
-('2', '3'),
+('2_1', '2', '2', '2', 2, 99999),
+('2_2', '2', '3', '3', 1, 24999),
 
-('3', '3'),
+('3_1', '3', '3', '3', 1, 24999),
 
-('4', '4'),
-('7', '7'),
+('4_1', '4', '4', '4', 1, 109999),
+('7_1', '7', '7', '7', 1, 159999),
 
-('5', '5'),
+('5_1', '5', '5', '5', 2, 49999),
 
-('6', '6'),
+('6_1', '6', '6', '6', 1, 19999),
 
-('8', '8'),
+('8_1', '8', '8', '8', 4, 2999),
 
-('9', '9'),
+('9_1', '9', '9', '9', 1, 9999),
 
-('10', '10')
-ON CONFLICT DO NOTHING;
\ No newline at end of file
+('10_1', '10', '10', '10', 1, 399999)
+ON CONFLICT DO NOTHING;
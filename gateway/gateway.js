@@ -9,6 +9,7 @@ const {
   ApolloServerPluginLandingPageProductionDefault,
 } = require("@apollo/server/plugin/landingPage/default");
 const { readFileSync } = require("fs");
+const { randomUUID } = require("crypto");
 
 function sleep(ms) {
   return new Promise((resolve) => setTimeout(resolve, ms));
@@ -57,8 +58,16 @@ async function startServer() {
       buildService: ({ url }) =>
         new RetryableDataSource({
           url,
-          willSendRequest: ({ request }) => {
+          willSendRequest: ({ request, context }) => {
             request.http.headers.set("apollo-federation-include-trace", "ftv1");
+            // Subgraphs verify the caller's access token themselves
+            if (context.authorization) {
+              request.http.headers.set("authorization", context.authorization);
+            }
+            // One ID per client request, so subgraph logs can be matched up
+            if (context.requestId) {
+              request.http.headers.set("x-request-id", context.requestId);
+            }
           },
         }),
     });
@@ -106,7 +115,17 @@ async function startServer() {
       res.status(200).send("ok");
     });
 
-    app.use("/graphql", cors(), express.json(), expressMiddleware(server));
+    app.use(
+      "/graphql",
+      cors(),
+      express.json(),
+      expressMiddleware(server, {
+        context: async ({ req }) => ({
+          authorization: req.headers.authorization,
+          requestId: req.headers["x-request-id"] || randomUUID(),
+        }),
+      }),
+    );
 
     // End of express middleware
 
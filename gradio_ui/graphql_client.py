@@ -1,5 +1,6 @@
 import requests
 import os
+import time
 
 # GRAPHQL_URL = os.getenv("GRAPHQL_URL", "https://gateway-render-e-commerce-graphql.onrender.com/query")
 # Prefer local compose env var; fall back to Render for hosted demo.
@@ -12,12 +13,52 @@ if not GRAPHQL_URL.endswith("/graphql"):
     GRAPHQL_URL += "/graphql"
 
 print("GRAPHQL_URL:", GRAPHQL_URL)
+# The UI acts as an admin. It signs in with these credentials and reuses the
+# access token until it is about to expire.
+ADMIN_EMAIL = os.getenv("GRADIO_ADMIN_EMAIL", "")
+ADMIN_PASSWORD = os.getenv("GRADIO_ADMIN_PASSWORD", "")
+
+_token = {"value": None, "expires_at": 0}
+
+
+def _access_token():
+    if not ADMIN_EMAIL:
+        return None
+    if _token["value"] and time.time() < _token["expires_at"] - 30:
+        return _token["value"]
+
+    mutation = """
+    mutation Login($email: String!, $password: String!) {
+        login(email: $email, password: $password) { accessToken expiresIn }
+    }
+    """
+    response = requests.post(
+        GRAPHQL_URL,
+        json={"query": mutation, "variables": {"email": ADMIN_EMAIL, "password": ADMIN_PASSWORD}},
+        headers={"Content-Type": "application/json"}
+    )
+    response.raise_for_status()
+    data = response.json()
+    if "errors" in data:
+        raise RuntimeError(f"login failed: {data['errors'][0]['message']}")
+
+    login = data["data"]["login"]
+    _token["value"] = login["accessToken"]
+    _token["expires_at"] = time.time() + login["expiresIn"]
+    return _token["value"]
+
+
 def gql_request(query, variables=None):
     try:
+        headers = {"Content-Type": "application/json"}
+        token = _access_token()
+        if token:
+            headers["Authorization"] = f"Bearer {token}"
+
         response = requests.post(
             GRAPHQL_URL,
             json={"query": query, "variables": variables or {}},
-            headers={"Content-Type": "application/json"}
+            headers=headers
         )
         response.raise_for_status()
         data = response.json()
@@ -100,7 +141,11 @@ def get_products_cursor(after=None, first=10):
                 node {
                     id
                     name
-                    price
+                    price {
+                        amount
+                        currency
+                        formatted
+                    }
                     description
                     inventory
                     available
@@ -126,7 +171,11 @@ def get_product_by_id(product_id):
         product(id: $id){
             id
             name
-            price
+            price {
+                amount
+                currency
+                formatted
+            }
             description
             inventory
             available
@@ -175,12 +224,20 @@ def create_order(input_data):
             id
             userId
             quantity
-            totalPrice
+            totalPrice {
+                formatted
+            }
             status
             createdAt
-            products {
-                id
-                name
+            lineItems {
+                quantity
+                unitPrice {
+                    formatted
+                }
+                product {
+                    id
+                    name
+                }
             }
         }
     }
@@ -192,16 +249,28 @@ def create_order(input_data):
 def get_orders_for_user(user_id):
     query = """
     query GetOrdersForUser($id: ID!) {
-        ordersByUser(userId: $id) {
-            id
-            userId
-            quantity
-            totalPrice
-            status
-            createdAt
-            products {
-                id
-                name
+        ordersByUser(userId: $id, first: 100) {
+            edges {
+                node {
+                    id
+                    userId
+                    quantity
+                    totalPrice {
+                        formatted
+                    }
+                    status
+                    createdAt
+                    lineItems {
+                        quantity
+                        unitPrice {
+                            formatted
+                        }
+                        product {
+                            id
+                            name
+                        }
+                    }
+                }
             }
         }
     }
@@ -112,7 +112,7 @@ def handle_list_products(after=None, first=10):
     edges = data["edges"]
 
     rows = [
-        [edge["node"]["id"], edge["node"]["name"], edge["node"]["price"]]
+        [edge["node"]["id"], edge["node"]["name"], edge["node"]["price"]["formatted"]]
         for edge in edges
     ]
 
@@ -133,7 +133,7 @@ def handle_get_product(product_id):
         f"🛍️ Product Details\n\n"
         f"ID: {p['id']}\n"
         f"Name: {p['name']}\n"
-        f"Price: ${p['price']}\n"
+        f"Price: {p['price']['formatted']}\n"
         f"Description: {p['description']}\n"
         f"Inventory: {p['inventory']}\n"
         f"Available: {p['available']}"
@@ -199,6 +199,11 @@ def handle_create_user(name, email, password, active):
 
     return message, "", "", "", False, user["id"]
     
+def format_money(amount, currency):
+    # Amounts are in minor units; only the basket preview formats locally,
+    # everything else uses the server's formatted field
+    return f"{amount / 100:.2f} {currency}"
+
 def handle_add_to_basket(product_id, quantity, basket):
     if not product_id:
         return basket, basket, "❌ Please select a product."
@@ -219,9 +224,9 @@ def handle_add_to_basket(product_id, quantity, basket):
     item = [
         product["id"],
         product["name"],
-        product["price"],
+        product["price"]["formatted"],
         int(quantity),
-        float(product["price"]) * int(quantity),
+        format_money(product["price"]["amount"] * int(quantity), product["price"]["currency"]),
     ]
 
     basket.append(item)
@@ -236,15 +241,12 @@ def handle_create_order_from_basket(user_id, basket):
     if not basket:
         return "❌ Basket is empty."
 
-    product_ids = [item[0] for item in basket]
-    total_quantity = sum(item[3] for item in basket)
-    total_price = sum(item[4] for item in basket)
+    line_items = [{"productId": item[0], "quantity": int(item[3])} for item in basket]
 
+    # The orders service prices the order itself
     input_data = {
         "userId": user_id,
-        "productIds": product_ids,
-        "quantity": total_quantity,
-        "totalPrice": total_price,
+        "lineItems": line_items,
         "status": "PENDING",
         "createdAt": datetime.utcnow().isoformat() + "Z",
     }
@@ -264,7 +266,7 @@ def handle_create_order_from_basket(user_id, basket):
         f"Order ID: {order['id']}\n"
         f"User ID: {order['userId']}\n"
         f"Quantity: {order['quantity']}\n"
-        f"Total Price: ${order['totalPrice']}\n"
+        f"Total Price: {order['totalPrice']['formatted']}\n"
         f"Status: {order['status']}"
     )
 
@@ -280,22 +282,25 @@ def handle_get_orders_for_user(user_id):
     if "error" in result:
         return f"❌ Error: {result['error']}"
     
-    orders = result.get("ordersByUser")
-    if orders is None:
+    connection = result.get("ordersByUser")
+    if connection is None:
         return "❌ Unexpected response format"
+    orders = [edge["node"] for edge in connection["edges"]]
     if len(orders)== 0:
         return "This user has no orders"
     
     messages = []
     for o in orders:
-        products = ", ".join([p["name"] for p in o["products"]])
+        products = ", ".join(
+            [f"{li['product']['name']} x{li['quantity']}" for li in o["lineItems"]]
+        )
         
         messages.append(
             f"🧾 Order {o['id']}\n"
             f"User: {o['userId']}\n"
             f"Products: {products}\n"
             f"Quantity: {o['quantity']}\n"
-            f"Total Price: ${o['totalPrice']}\n"
+            f"Total Price: {o['totalPrice']['formatted']}\n"
             f"Status: {o['status']}\n"
             f"Created At: {o['createdAt']}\n"
             f"{'-'*40}"
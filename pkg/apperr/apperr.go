@@ -0,0 +1,126 @@
+// Package apperr is the error taxonomy shared by the subgraphs. Services
+// return *Error values carrying a Code, and each subgraph's ErrorPresenter
+// turns them into GraphQL errors with that code in extensions.code. Errors
+// without a code are reported as INTERNAL with their details withheld.
+package apperr
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Code classifies an error for clients.
+type Code string
+
+const (
+	// CodeNotFound is for unknown IDs and other missing records.
+	CodeNotFound Code = "NOT_FOUND"
+	// CodeValidation is for input the client can fix.
+	CodeValidation Code = "VALIDATION"
+	// CodeConflict is for requests that clash with the current state, e.g. a
+	// stale version or too little stock.
+	CodeConflict Code = "CONFLICT"
+	// CodeUnauthenticated is for requests that need a signed-in caller.
+	CodeUnauthenticated Code = "UNAUTHENTICATED"
+	// CodeForbidden is for callers that lack the role or do not own the
+	// resource.
+	CodeForbidden Code = "FORBIDDEN"
+	// CodeInternal is for everything else. Its details stay in the logs.
+	CodeInternal Code = "INTERNAL"
+)
+
+// FieldViolation is one problem with one input field.
+type FieldViolation struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is an error with a Code. Message is shown to clients as is, so it
+// must not carry internal details; those belong in Err, which is only logged.
+type Error struct {
+	Code    Code
+	Message string
+	// Fields lists the offending input fields of a VALIDATION error.
+	Fields []FieldViolation
+	// Extensions are added to the GraphQL error's extensions, e.g. the
+	// current version of a conflicting record.
+	Extensions map[string]interface{}
+	Err        error
+}
+
+func (e *Error) Error() string { return e.Message }
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// New returns an error with a fixed message, e.g. for sentinel errors.
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Errorf formats a message like fmt.Errorf. An error wrapped with %w stays
+// reachable with errors.Is and errors.As.
+func Errorf(code Code, format string, args ...interface{}) *Error {
+	err := fmt.Errorf(format, args...)
+	return &Error{Code: code, Message: err.Error(), Err: errors.Unwrap(err)}
+}
+
+// Wrap gives err a code, keeping its message.
+func Wrap(code Code, err error) *Error {
+	return &Error{Code: code, Message: err.Error(), Err: err}
+}
+
+// NotFound returns a NOT_FOUND error.
+func NotFound(format string, args ...interface{}) *Error {
+	return Errorf(CodeNotFound, format, args...)
+}
+
+// Validation returns a VALIDATION error that is not about a single field.
+func Validation(format string, args ...interface{}) *Error {
+	return Errorf(CodeValidation, format, args...)
+}
+
+// Conflict returns a CONFLICT error.
+func Conflict(format string, args ...interface{}) *Error {
+	return Errorf(CodeConflict, format, args...)
+}
+
+// Internal hides err behind a generic INTERNAL error.
+func Internal(err error) *Error {
+	return &Error{Code: CodeInternal, Message: "internal error", Err: err}
+}
+
+// Field returns a VALIDATION error for a single input field.
+func Field(field, format string, args ...interface{}) *Error {
+	return Invalid(FieldViolation{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// Invalid returns a VALIDATION error listing every violation at once.
+func Invalid(violations ...FieldViolation) *Error {
+	parts := make([]string, len(violations))
+	for i, v := range violations {
+		parts[i] = v.Field + ": " + v.Message
+	}
+	return &Error{
+		Code:    CodeValidation,
+		Message: "invalid input: " + strings.Join(parts, "; "),
+		Fields:  violations,
+	}
+}
+
+// CodeOf returns the code of the first *Error in err's chain. Missing GORM
+// records are NOT_FOUND and anything else is INTERNAL.
+func CodeOf(err error) Code {
+	var appErr *Error
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &appErr):
+		return appErr.Code
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return CodeNotFound
+	}
+	return CodeInternal
+}
@@ -0,0 +1,128 @@
+package apperr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"gorm.io/gorm"
+)
+
+func TestCodeOf(t *testing.T) {
+	sentinel := New(CodeConflict, "insufficient stock")
+	tests := []struct {
+		name string
+		err  error
+		want Code
+	}{
+		{"nil", nil, ""},
+		{"app error", NotFound("order %s not found", "1"), CodeNotFound},
+		{"wrapped app error", fmt.Errorf("%w: variant 1", sentinel), CodeConflict},
+		{"gorm not found", fmt.Errorf("parent category: %w", gorm.ErrRecordNotFound), CodeNotFound},
+		{"plain error", errors.New("pq: relation does not exist"), CodeInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CodeOf(tt.err); got != tt.want {
+				t.Errorf("CodeOf(%v) = %q, want %q", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorfKeepsWrappedError(t *testing.T) {
+	cause := errors.New("currency mismatch")
+	err := Errorf(CodeValidation, "minPrice and maxPrice: %w", cause)
+	if !errors.Is(err, cause) {
+		t.Errorf("errors.Is(%v, cause) = false", err)
+	}
+	if err.Error() != "minPrice and maxPrice: currency mismatch" {
+		t.Errorf("message = %q", err.Error())
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	err := Invalid(
+		FieldViolation{Field: "name", Message: "is required"},
+		FieldViolation{Field: "price", Message: "must be greater than zero"},
+	)
+	if err.Code != CodeValidation || len(err.Fields) != 2 {
+		t.Fatalf("Invalid() = %+v", err)
+	}
+	if want := "invalid input: name: is required; price: must be greater than zero"; err.Message != want {
+		t.Errorf("message = %q, want %q", err.Message, want)
+	}
+}
+
+func TestPresenter(t *testing.T) {
+	ctx := WithRequestID(context.Background(), "req-1")
+	tests := []struct {
+		name        string
+		err         error
+		wantCode    Code
+		wantMessage string
+	}{
+		{"not found", NotFound("product %s not found", "1"), CodeNotFound, "product 1 not found"},
+		{"gorm not found", gorm.ErrRecordNotFound, CodeNotFound, "not found"},
+		{"field", Field("quantity", "must be greater than zero"), CodeValidation, "invalid input: quantity: must be greater than zero"},
+		{"argument", gqlerror.Errorf("time should be a string"), CodeValidation, "time should be a string"},
+		{"internal", errors.New(`pq: duplicate key value violates unique constraint "users_pkey"`), CodeInternal, "internal error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Presenter(ctx, tt.err)
+			if got.Extensions["code"] != tt.wantCode {
+				t.Errorf("code = %v, want %s", got.Extensions["code"], tt.wantCode)
+			}
+			if got.Message != tt.wantMessage {
+				t.Errorf("message = %q, want %q", got.Message, tt.wantMessage)
+			}
+			if tt.wantCode == CodeInternal && got.Extensions["requestId"] != "req-1" {
+				t.Errorf("requestId = %v, want req-1", got.Extensions["requestId"])
+			}
+		})
+	}
+}
+
+func TestPresenterExtensions(t *testing.T) {
+	err := Wrap(CodeConflict, errors.New("product 1 was changed concurrently"))
+	err.Extensions = map[string]interface{}{"currentVersion": 4}
+
+	got := Presenter(context.Background(), err)
+	if got.Extensions["code"] != CodeConflict || got.Extensions["currentVersion"] != 4 {
+		t.Errorf("extensions = %v", got.Extensions)
+	}
+}
+
+func TestRecover(t *testing.T) {
+	ctx := WithRequestID(context.Background(), "req-2")
+	got := Presenter(ctx, Recover(ctx, "not implemented"))
+	if got.Extensions["code"] != CodeInternal || got.Message != "internal error" || got.Extensions["requestId"] != "req-2" {
+		t.Errorf("Presenter(Recover()) = %+v", got)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	var got string
+	h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = RequestIDFrom(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/query", nil)
+	req.Header.Set(RequestIDHeader, "gateway-id")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if got != "gateway-id" || rec.Header().Get(RequestIDHeader) != "gateway-id" {
+		t.Errorf("request ID = %q, header %q; want the gateway's", got, rec.Header().Get(RequestIDHeader))
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/query", nil))
+	if got == "" || rec.Header().Get(RequestIDHeader) != got {
+		t.Errorf("generated request ID = %q, header %q", got, rec.Header().Get(RequestIDHeader))
+	}
+}
@@ -0,0 +1,68 @@
+package apperr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"strings"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"gorm.io/gorm"
+)
+
+// Presenter is a gqlgen ErrorPresenter that puts a Code in every error's
+// extensions.code, and the offending fields of VALIDATION errors in
+// extensions.fields. Errors gqlgen raises itself while reading arguments are
+// VALIDATION errors. Any other error without a code is logged and replaced
+// by an INTERNAL error carrying the request ID, so database and other
+// internal details never reach clients.
+func Presenter(ctx context.Context, err error) *gqlerror.Error {
+	gqlErr := graphql.DefaultErrorPresenter(ctx, err)
+
+	var appErr *Error
+	var argErr *gqlerror.Error
+	code := CodeOf(err)
+	switch {
+	case errors.As(err, &appErr):
+		if len(appErr.Fields) > 0 {
+			setExtension(gqlErr, "fields", appErr.Fields)
+		}
+		for k, v := range appErr.Extensions {
+			setExtension(gqlErr, k, v)
+		}
+	case code == CodeNotFound:
+		// GORM's "record not found" reads as a plain "not found"
+		gqlErr.Message = strings.Replace(gqlErr.Message, gorm.ErrRecordNotFound.Error(), "not found", 1)
+	case errors.As(err, &argErr):
+		code = CodeValidation
+	}
+
+	if code == CodeInternal {
+		requestID := RequestIDFrom(ctx)
+		log.Printf("❌ [%s] %s: %v", requestID, gqlErr.Path, err)
+		gqlErr.Message = "internal error"
+		gqlErr.Extensions = nil
+		if requestID != "" {
+			setExtension(gqlErr, "requestId", requestID)
+		}
+	}
+	setExtension(gqlErr, "code", code)
+	return gqlErr
+}
+
+// Recover is a gqlgen RecoverFunc. It logs the stack of a panicking
+// resolver and reports an INTERNAL error in its place.
+func Recover(ctx context.Context, p interface{}) error {
+	log.Printf("❌ [%s] panic: %v\n%s", RequestIDFrom(ctx), p, debug.Stack())
+	return Internal(fmt.Errorf("panic: %v", p))
+}
+
+func setExtension(gqlErr *gqlerror.Error, key string, value interface{}) {
+	if gqlErr.Extensions == nil {
+		gqlErr.Extensions = map[string]interface{}{}
+	}
+	gqlErr.Extensions[key] = value
+}
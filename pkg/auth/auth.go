@@ -0,0 +1,110 @@
+// Package auth carries the caller's identity from a verified access token
+// into resolvers, and implements the @auth schema directive shared by all
+// subgraphs.
+package auth
+
+import (
+	"context"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/tagaertner/e-commerce-graphql/pkg/apperr"
+)
+
+// Role is a user's role. It is bound to the Role enum in every subgraph.
+type Role string
+
+const (
+	RoleAdmin    Role = "ADMIN"
+	RoleCustomer Role = "CUSTOMER"
+)
+
+// Satisfies reports whether a caller with role r may do something that
+// requires role required. Admins satisfy every requirement.
+func (r Role) Satisfies(required Role) bool {
+	return r == RoleAdmin || r == required
+}
+
+var (
+	// ErrUnauthenticated is returned when a request carries no valid token.
+	ErrUnauthenticated error = apperr.New(apperr.CodeUnauthenticated, "authentication required")
+	// ErrForbidden is returned when the caller lacks the required role or
+	// does not own the resource.
+	ErrForbidden error = apperr.New(apperr.CodeForbidden, "not authorized")
+)
+
+// Claims are the claims carried by an access token: the user ID as the
+// subject, plus the user's role.
+type Claims struct {
+	Role Role `json:"role"`
+	jwt.RegisteredClaims
+}
+
+// Caller is the verified identity behind a request.
+type Caller struct {
+	UserID string
+	Role   Role
+}
+
+// IsAdmin reports whether the caller is an admin.
+func (c *Caller) IsAdmin() bool {
+	return c.Role == RoleAdmin
+}
+
+type callerKey struct{}
+
+// WithCaller returns a context carrying caller.
+func WithCaller(ctx context.Context, caller *Caller) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// CallerFrom returns the caller stored in ctx, if any.
+func CallerFrom(ctx context.Context) (*Caller, bool) {
+	caller, ok := ctx.Value(callerKey{}).(*Caller)
+	return caller, ok && caller != nil
+}
+
+// ActorFromContext returns the ID of the user making a change, for audit
+// records such as the inventory ledger, or "anonymous" when the request
+// carries no verified caller.
+func ActorFromContext(ctx context.Context) string {
+	if caller, ok := CallerFrom(ctx); ok {
+		return caller.UserID
+	}
+	return "anonymous"
+}
+
+// RequireSelfOrAdmin allows the request if the caller is userID or an admin.
+func RequireSelfOrAdmin(ctx context.Context, userID string) error {
+	caller, ok := CallerFrom(ctx)
+	if !ok {
+		return ErrUnauthenticated
+	}
+	if caller.IsAdmin() || caller.UserID == userID {
+		return nil
+	}
+	return ErrForbidden
+}
+
+// RequireAdmin allows the request only if the caller is an admin.
+func RequireAdmin(ctx context.Context) error {
+	caller, ok := CallerFrom(ctx)
+	if !ok {
+		return ErrUnauthenticated
+	}
+	if !caller.IsAdmin() {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// AdminFlag returns the value of an optional admin-only flag such as
+// includeDeleted. Setting it to true requires an admin.
+func AdminFlag(ctx context.Context, flag *bool) (bool, error) {
+	if flag == nil || !*flag {
+		return false, nil
+	}
+	if err := RequireAdmin(ctx); err != nil {
+		return false, err
+	}
+	return true, nil
+}
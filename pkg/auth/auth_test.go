@@ -0,0 +1,176 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func signToken(t *testing.T, key ed25519.PrivateKey, kid string, claims Claims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func testClaims(sub string, role Role) Claims {
+	now := time.Now()
+	return Claims{
+		Role: role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "test",
+			Subject:   sub,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+		},
+	}
+}
+
+func TestJWKSVerifier(t *testing.T) {
+	pub, key, _ := ed25519.GenerateKey(rand.Reader)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(NewJWKS(map[string]ed25519.PublicKey{"k1": pub}))
+	}))
+	defer srv.Close()
+
+	v := NewJWKSVerifier(srv.URL, "test")
+	caller, err := v.Verify(context.Background(), signToken(t, key, "k1", testClaims("user1", RoleCustomer)))
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if caller.UserID != "user1" || caller.Role != RoleCustomer {
+		t.Errorf("caller = %+v", caller)
+	}
+
+	wrongIssuer := testClaims("user1", RoleAdmin)
+	wrongIssuer.Issuer = "someone-else"
+	if _, err := v.Verify(context.Background(), signToken(t, key, "k1", wrongIssuer)); err == nil {
+		t.Error("token from another issuer was accepted")
+	}
+
+	_, otherKey, _ := ed25519.GenerateKey(rand.Reader)
+	if _, err := v.Verify(context.Background(), signToken(t, otherKey, "k1", testClaims("user1", RoleAdmin))); err == nil {
+		t.Error("token signed with an unknown key was accepted")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	pub, key, _ := ed25519.GenerateKey(rand.Reader)
+	v := NewStaticVerifier("test", map[string]ed25519.PublicKey{"k1": pub})
+
+	var got *Caller
+	h := Middleware(v)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = CallerFrom(r.Context())
+	}))
+
+	tests := []struct {
+		name       string
+		header     string
+		wantStatus int
+		wantCaller bool
+	}{
+		{"anonymous", "", http.StatusOK, false},
+		{"valid token", "Bearer " + signToken(t, key, "k1", testClaims("user1", RoleAdmin)), http.StatusOK, true},
+		{"bad token", "Bearer nonsense", http.StatusUnauthorized, false},
+		{"wrong scheme", "Basic dXNlcjpwYXNz", http.StatusUnauthorized, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = nil
+			req := httptest.NewRequest(http.MethodPost, "/query", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if (got != nil) != tt.wantCaller {
+				t.Errorf("caller = %+v, want caller: %v", got, tt.wantCaller)
+			}
+		})
+	}
+}
+
+func TestDirective(t *testing.T) {
+	next := func(ctx context.Context) (interface{}, error) { return "ok", nil }
+	admin := WithCaller(context.Background(), &Caller{UserID: "1", Role: RoleAdmin})
+	customer := WithCaller(context.Background(), &Caller{UserID: "2", Role: RoleCustomer})
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		requires Role
+		wantErr  error
+	}{
+		{"anonymous", context.Background(), RoleCustomer, ErrUnauthenticated},
+		{"customer as customer", customer, RoleCustomer, nil},
+		{"customer as admin", customer, RoleAdmin, ErrForbidden},
+		{"admin as customer", admin, RoleCustomer, nil},
+		{"admin as admin", admin, RoleAdmin, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Directive(tt.ctx, nil, next, tt.requires)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRequireSelfOrAdmin(t *testing.T) {
+	customer := WithCaller(context.Background(), &Caller{UserID: "2", Role: RoleCustomer})
+	if err := RequireSelfOrAdmin(customer, "2"); err != nil {
+		t.Errorf("own resource: %v", err)
+	}
+	if err := RequireSelfOrAdmin(customer, "3"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("other user's resource: %v", err)
+	}
+	admin := WithCaller(context.Background(), &Caller{UserID: "1", Role: RoleAdmin})
+	if err := RequireSelfOrAdmin(admin, "3"); err != nil {
+		t.Errorf("admin: %v", err)
+	}
+}
+
+func TestActorFromContext(t *testing.T) {
+	if got := ActorFromContext(context.Background()); got != "anonymous" {
+		t.Errorf("no caller: got %q", got)
+	}
+	customer := WithCaller(context.Background(), &Caller{UserID: "2", Role: RoleCustomer})
+	if got := ActorFromContext(customer); got != "2" {
+		t.Errorf("customer: got %q", got)
+	}
+}
+
+func TestAdminFlag(t *testing.T) {
+	set, unset := true, false
+	customer := WithCaller(context.Background(), &Caller{UserID: "2", Role: RoleCustomer})
+	if got, err := AdminFlag(customer, nil); got || err != nil {
+		t.Errorf("omitted flag: %v, %v", got, err)
+	}
+	if got, err := AdminFlag(customer, &unset); got || err != nil {
+		t.Errorf("false flag: %v, %v", got, err)
+	}
+	if _, err := AdminFlag(customer, &set); !errors.Is(err, ErrForbidden) {
+		t.Errorf("customer setting the flag: %v", err)
+	}
+	admin := WithCaller(context.Background(), &Caller{UserID: "1", Role: RoleAdmin})
+	if got, err := AdminFlag(admin, &set); !got || err != nil {
+		t.Errorf("admin setting the flag: %v, %v", got, err)
+	}
+}
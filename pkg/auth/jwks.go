@@ -0,0 +1,161 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWK is a single public key in a JSON Web Key Set.
+type JWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+}
+
+// JWKS is a JSON Web Key Set as served from /.well-known/jwks.json.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewJWKS publishes Ed25519 public keys keyed by key ID.
+func NewJWKS(keys map[string]ed25519.PublicKey) JWKS {
+	set := JWKS{Keys: make([]JWK, 0, len(keys))}
+	for kid, pub := range keys {
+		set.Keys = append(set.Keys, JWK{
+			Kty: "OKP",
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(pub),
+			Kid: kid,
+			Alg: "EdDSA",
+			Use: "sig",
+		})
+	}
+	return set
+}
+
+func (s JWKS) publicKeys() (map[string]ed25519.PublicKey, error) {
+	keys := make(map[string]ed25519.PublicKey, len(s.Keys))
+	for _, k := range s.Keys {
+		if k.Kty != "OKP" || k.Crv != "Ed25519" {
+			continue
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid key %q in JWKS", k.Kid)
+		}
+		keys[k.Kid] = ed25519.PublicKey(x)
+	}
+	return keys, nil
+}
+
+// refetchInterval limits how often an unknown key ID triggers a JWKS fetch.
+const refetchInterval = 30 * time.Second
+
+// Verifier checks access tokens offline against a set of Ed25519 public keys.
+type Verifier struct {
+	issuer string
+	fetch  func(ctx context.Context) (map[string]ed25519.PublicKey, error)
+
+	mu      sync.RWMutex
+	keys    map[string]ed25519.PublicKey
+	fetched time.Time
+}
+
+// NewStaticVerifier verifies tokens against a fixed set of keys. The users
+// service uses it with its own signing key.
+func NewStaticVerifier(issuer string, keys map[string]ed25519.PublicKey) *Verifier {
+	return &Verifier{issuer: issuer, keys: keys}
+}
+
+// NewJWKSVerifier verifies tokens against keys fetched from jwksURL. Keys are
+// fetched on first use and again whenever a token names an unknown key, so
+// rotating the signing key needs no restart.
+func NewJWKSVerifier(jwksURL, issuer string) *Verifier {
+	client := &http.Client{Timeout: 5 * time.Second}
+	return &Verifier{
+		issuer: issuer,
+		fetch: func(ctx context.Context) (map[string]ed25519.PublicKey, error) {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, jwksURL, nil)
+			if err != nil {
+				return nil, err
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				return nil, err
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				return nil, fmt.Errorf("fetching JWKS: unexpected status %s", resp.Status)
+			}
+			var set JWKS
+			if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+				return nil, err
+			}
+			return set.publicKeys()
+		},
+	}
+}
+
+// Verify checks a signed access token and returns the caller it identifies.
+func (v *Verifier) Verify(ctx context.Context, token string) (*Caller, error) {
+	var claims Claims
+	_, err := jwt.ParseWithClaims(token, &claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return v.key(ctx, kid)
+	}, jwt.WithValidMethods([]string{"EdDSA"}), jwt.WithIssuer(v.issuer), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, err
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("token has no subject")
+	}
+	return &Caller{UserID: claims.Subject, Role: claims.Role}, nil
+}
+
+func (v *Verifier) key(ctx context.Context, kid string) (ed25519.PublicKey, error) {
+	v.mu.RLock()
+	key, ok := v.keys[kid]
+	stale := time.Since(v.fetched) > refetchInterval
+	v.mu.RUnlock()
+	if ok {
+		return key, nil
+	}
+	if v.fetch == nil || !stale {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	keys, err := v.fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	v.mu.Lock()
+	v.keys, v.fetched = keys, time.Now()
+	v.mu.Unlock()
+
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// bearerToken extracts the token from an "Authorization: Bearer" header.
+func bearerToken(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
@@ -0,0 +1,126 @@
+package db
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm/logger"
+)
+
+// Config describes how a service connects to Postgres.
+type Config struct {
+	// DSN of the primary database. Writes always go here.
+	DSN string
+	// ReplicaDSN is an optional read replica. When set, plain reads are
+	// routed to it; reads inside a transaction or with row locks stay on the
+	// primary.
+	ReplicaDSN string
+
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+
+	// ConnectTimeout bounds how long Open keeps retrying. Retries back off
+	// exponentially from InitialBackoff up to MaxBackoff.
+	ConnectTimeout time.Duration
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+
+	// LogLevel and SlowQueryThreshold control what the GORM logger reports.
+	LogLevel           logger.LogLevel
+	SlowQueryThreshold time.Duration
+}
+
+// DefaultConfig returns the settings used when nothing is configured.
+func DefaultConfig() Config {
+	return Config{
+		MaxOpenConns:       10,
+		MaxIdleConns:       5,
+		ConnMaxLifetime:    30 * time.Minute,
+		ConnMaxIdleTime:    5 * time.Minute,
+		ConnectTimeout:     time.Minute,
+		InitialBackoff:     500 * time.Millisecond,
+		MaxBackoff:         10 * time.Second,
+		LogLevel:           logger.Warn,
+		SlowQueryThreshold: 200 * time.Millisecond,
+	}
+}
+
+// ConfigFromEnv reads the connection settings every service shares.
+// DATABASE_URL is preferred; without it the DSN is assembled from DB_HOST,
+// DB_PORT, POSTGRES_USER, POSTGRES_PASSWORD and POSTGRES_DB for local dev.
+// Invalid values are fatal, like any other bad configuration at startup.
+func ConfigFromEnv() Config {
+	cfg := DefaultConfig()
+
+	cfg.DSN = os.Getenv("DATABASE_URL")
+	if cfg.DSN == "" {
+		cfg.DSN = fmt.Sprintf(
+			"host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
+			os.Getenv("DB_HOST"),
+			os.Getenv("POSTGRES_USER"),
+			os.Getenv("POSTGRES_PASSWORD"),
+			os.Getenv("POSTGRES_DB"),
+			os.Getenv("DB_PORT"),
+		)
+		log.Println("🔧 Using individual DB environment variables")
+	} else {
+		log.Println("🔗 Using DATABASE_URL connection string")
+	}
+	cfg.ReplicaDSN = os.Getenv("DATABASE_REPLICA_URL")
+
+	cfg.MaxOpenConns = intEnv("DB_MAX_OPEN_CONNS", cfg.MaxOpenConns)
+	cfg.MaxIdleConns = intEnv("DB_MAX_IDLE_CONNS", cfg.MaxIdleConns)
+	cfg.ConnMaxLifetime = durationEnv("DB_CONN_MAX_LIFETIME", cfg.ConnMaxLifetime)
+	cfg.ConnMaxIdleTime = durationEnv("DB_CONN_MAX_IDLE_TIME", cfg.ConnMaxIdleTime)
+	cfg.ConnectTimeout = durationEnv("DB_CONNECT_TIMEOUT", cfg.ConnectTimeout)
+	cfg.SlowQueryThreshold = durationEnv("DB_SLOW_QUERY_THRESHOLD", cfg.SlowQueryThreshold)
+	cfg.LogLevel = logLevelEnv("DB_LOG_LEVEL", cfg.LogLevel)
+	return cfg
+}
+
+func intEnv(name string, fallback int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		log.Fatalf("❌ Invalid %s %q", name, value)
+	}
+	return n
+}
+
+func durationEnv(name string, fallback time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("❌ Invalid %s %q: %v", name, value, err)
+	}
+	return d
+}
+
+func logLevelEnv(name string, fallback logger.LogLevel) logger.LogLevel {
+	switch value := os.Getenv(name); value {
+	case "":
+		return fallback
+	case "silent":
+		return logger.Silent
+	case "error":
+		return logger.Error
+	case "warn":
+		return logger.Warn
+	case "info":
+		return logger.Info
+	default:
+		log.Fatalf("❌ Invalid %s %q: want silent, error, warn or info", name, value)
+		return fallback
+	}
+}
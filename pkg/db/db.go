@@ -0,0 +1,106 @@
+// Package db opens the Postgres connection shared by every service, with
+// retries, pool sizing, optional read replica routing and structured query
+// logging.
+package db
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"log/slog"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+// Open connects to the database described by cfg, retrying with exponential
+// backoff until the connection succeeds, cfg.ConnectTimeout passes or ctx is
+// cancelled.
+func Open(ctx context.Context, cfg Config) (*gorm.DB, error) {
+	if cfg.ConnectTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.ConnectTimeout)
+		defer cancel()
+	}
+
+	gormConfig := &gorm.Config{
+		Logger: newLogger(slog.Default().With(slog.String("component", "gorm")), cfg.LogLevel, cfg.SlowQueryThreshold),
+	}
+
+	var conn *gorm.DB
+	err := retry(ctx, cfg.InitialBackoff, cfg.MaxBackoff, func(attempt int) error {
+		db, err := gorm.Open(postgres.Open(cfg.DSN), gormConfig)
+		if err != nil {
+			log.Printf("❌ Database connection attempt %d failed: %v", attempt, err)
+			return err
+		}
+		conn = db
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := conn.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+
+	if cfg.ReplicaDSN != "" {
+		resolver := dbresolver.Register(dbresolver.Config{
+			Replicas: []gorm.Dialector{postgres.Open(cfg.ReplicaDSN)},
+		}).
+			SetMaxOpenConns(cfg.MaxOpenConns).
+			SetMaxIdleConns(cfg.MaxIdleConns).
+			SetConnMaxLifetime(cfg.ConnMaxLifetime).
+			SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+		if err := conn.Use(resolver); err != nil {
+			return nil, fmt.Errorf("configuring read replica: %w", err)
+		}
+		log.Println("🔀 Routing reads to the read replica")
+	}
+
+	log.Println("✅ Connected to PostgreSQL successfully")
+	return conn, nil
+}
+
+// MustOpen is Open for service startup: it gives up the process when the
+// database cannot be reached.
+func MustOpen(cfg Config) *gorm.DB {
+	conn, err := Open(context.Background(), cfg)
+	if err != nil {
+		log.Fatalf("❌ Could not connect to database: %v", err)
+	}
+	return conn
+}
+
+// retry calls fn until it succeeds or ctx is done, doubling the wait between
+// attempts from initial up to max.
+func retry(ctx context.Context, initial, max time.Duration, fn func(attempt int) error) error {
+	delay := initial
+	for attempt := 1; ; attempt++ {
+		err := fn(attempt)
+		if err == nil {
+			return nil
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+		case <-timer.C:
+		}
+
+		delay *= 2
+		if delay > max {
+			delay = max
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm/logger"
+)
+
+func TestRetry_BacksOffUntilSuccess(t *testing.T) {
+	var waits []time.Duration
+	last := time.Now()
+	err := retry(context.Background(), time.Millisecond, 4*time.Millisecond, func(attempt int) error {
+		now := time.Now()
+		if attempt > 1 {
+			waits = append(waits, now.Sub(last))
+		}
+		last = now
+		if attempt < 5 {
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+
+	// 1ms, 2ms, 4ms, then capped at 4ms
+	want := []time.Duration{time.Millisecond, 2 * time.Millisecond, 4 * time.Millisecond, 4 * time.Millisecond}
+	if len(waits) != len(want) {
+		t.Fatalf("waited %d times, want %d", len(waits), len(want))
+	}
+	for i, w := range want {
+		if waits[i] < w {
+			t.Errorf("wait %d = %v, want at least %v", i, waits[i], w)
+		}
+	}
+}
+
+func TestRetry_StopsWhenContextDone(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	refused := errors.New("connection refused")
+	attempts := 0
+	err := retry(ctx, time.Millisecond, 5*time.Millisecond, func(int) error {
+		attempts++
+		return refused
+	})
+	if !errors.Is(err, refused) {
+		t.Fatalf("err = %v, want the last connection error", err)
+	}
+	if attempts < 2 {
+		t.Errorf("attempts = %d, want several before giving up", attempts)
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://primary")
+	t.Setenv("DATABASE_REPLICA_URL", "postgres://replica")
+	t.Setenv("DB_MAX_OPEN_CONNS", "25")
+	t.Setenv("DB_CONN_MAX_LIFETIME", "1h")
+	t.Setenv("DB_LOG_LEVEL", "info")
+
+	cfg := ConfigFromEnv()
+	if cfg.DSN != "postgres://primary" || cfg.ReplicaDSN != "postgres://replica" {
+		t.Errorf("DSNs = %q, %q", cfg.DSN, cfg.ReplicaDSN)
+	}
+	if cfg.MaxOpenConns != 25 || cfg.ConnMaxLifetime != time.Hour || cfg.LogLevel != logger.Info {
+		t.Errorf("cfg = %+v", cfg)
+	}
+	if cfg.MaxIdleConns != DefaultConfig().MaxIdleConns {
+		t.Errorf("MaxIdleConns = %d, want default", cfg.MaxIdleConns)
+	}
+}
@@ -0,0 +1,75 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// slogLogger adapts GORM's logger to log/slog so query logs come out as
+// structured records alongside the rest of the service's output.
+type slogLogger struct {
+	log           *slog.Logger
+	level         logger.LogLevel
+	slowThreshold time.Duration
+}
+
+func newLogger(log *slog.Logger, level logger.LogLevel, slowThreshold time.Duration) logger.Interface {
+	return &slogLogger{log: log, level: level, slowThreshold: slowThreshold}
+}
+
+func (l *slogLogger) LogMode(level logger.LogLevel) logger.Interface {
+	clone := *l
+	clone.level = level
+	return &clone
+}
+
+func (l *slogLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Info {
+		l.log.InfoContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *slogLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Warn {
+		l.log.WarnContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *slogLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Error {
+		l.log.ErrorContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+// Trace logs failed queries at error level, slow queries at warn level and,
+// when the level is Info, every query.
+func (l *slogLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.level <= logger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	attrs := func() []any {
+		sql, rows := fc()
+		return []any{
+			slog.String("sql", sql),
+			slog.Int64("rows", rows),
+			slog.Duration("elapsed", elapsed),
+		}
+	}
+
+	switch {
+	case err != nil && l.level >= logger.Error && !errors.Is(err, gorm.ErrRecordNotFound):
+		l.log.ErrorContext(ctx, "query failed", append(attrs(), slog.String("error", err.Error()))...)
+	case l.slowThreshold > 0 && elapsed > l.slowThreshold && l.level >= logger.Warn:
+		l.log.WarnContext(ctx, "slow query", attrs()...)
+	case l.level >= logger.Info:
+		l.log.InfoContext(ctx, "query", attrs()...)
+	}
+}
@@ -3,6 +3,8 @@ package federation
 import (
     "context"
     "fmt"
+
+    "github.com/tagaertner/e-commerce-graphql/pkg/apperr"
 )
 
 // Entity represents a federated entity
@@ -44,12 +46,12 @@ func (fc *FederationConfig) ResolveEntities(ctx context.Context, representations
     for i, representation := range representations {
         typename, ok := representation["__typename"].(string)
         if !ok {
-            return nil, fmt.Errorf("missing __typename in representation")
+            return nil, apperr.Validation("missing __typename in representation")
         }
         
         resolver, exists := fc.EntityResolvers[typename]
         if !exists {
-            return nil, fmt.Errorf("no entity resolver for type %s", typename)
+            return nil, apperr.Validation("no entity resolver for type %s", typename)
         }
         
         entity, err := resolver.FindEntityByRepresentation(ctx, representation)
@@ -0,0 +1,169 @@
+// Package inventory keeps an append-only ledger of every change to a product
+// variant's stock. Stock lives in the product_variants table that the
+// products and orders services share; Record changes it and appends the
+// ledger entry in the caller's transaction, so the two never drift apart.
+package inventory
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tagaertner/e-commerce-graphql/pkg/apperr"
+	"gorm.io/gorm"
+)
+
+// Kind is why stock changed.
+type Kind string
+
+const (
+	// KindRestock adds delivered units.
+	KindRestock Kind = "RESTOCK"
+	// KindReservation holds units for an order that has not been paid.
+	KindReservation Kind = "RESERVATION"
+	// KindRelease returns held or sold units, e.g. for a cancelled order.
+	KindRelease Kind = "RELEASE"
+	// KindSale settles a reservation once its order is paid. The units
+	// already left inventory with the reservation.
+	KindSale Kind = "SALE"
+	// KindAdjustment corrects stock by hand, e.g. after a count.
+	KindAdjustment Kind = "ADJUSTMENT"
+)
+
+var (
+	// ErrInsufficientStock is returned when a change would take a variant's
+	// inventory below zero.
+	ErrInsufficientStock error = apperr.New(apperr.CodeConflict, "insufficient stock")
+	// ErrVariantNotFound is returned for an unknown SKU.
+	ErrVariantNotFound error = apperr.New(apperr.CodeNotFound, "variant not found")
+)
+
+// Entry is one ledger row. Change is the signed effect on the variant's
+// sellable inventory and Balance the inventory right after it.
+type Entry struct {
+	ID        string `gorm:"primaryKey"`
+	SKU       string `gorm:"column:sku"`
+	ProductID string
+	Kind      Kind
+	Quantity  int
+	Change    int
+	Balance   int
+	Reason    string
+	Actor     string
+	OrderID   *string
+	CreatedAt time.Time
+}
+
+func (Entry) TableName() string { return "inventory_ledger" }
+
+// Movement asks Record for one change to a variant's stock.
+type Movement struct {
+	SKU  string
+	Kind Kind
+	// Quantity is how many units moved. Restocks and releases add them to
+	// inventory, reservations take them out and sales leave it as it is.
+	// Only adjustments may be negative, to remove units.
+	Quantity int
+	Reason   string
+	Actor    string
+	OrderID  *string
+}
+
+// change returns the movement's signed effect on inventory.
+func (m Movement) change() (int, error) {
+	if m.Quantity == 0 || m.Quantity < 0 && m.Kind != KindAdjustment {
+		return 0, apperr.Validation("invalid %s quantity %d", m.Kind, m.Quantity)
+	}
+	switch m.Kind {
+	case KindRestock, KindRelease, KindAdjustment:
+		return m.Quantity, nil
+	case KindReservation:
+		return -m.Quantity, nil
+	case KindSale:
+		return 0, nil
+	}
+	return 0, apperr.Validation("unknown stock movement %q", m.Kind)
+}
+
+// Record applies a movement to a variant's inventory with a single atomic
+// update, so concurrent movements never overwrite each other, and appends
+// it to the ledger. It fails with ErrInsufficientStock rather than take
+// inventory below zero. Callers then bring the product's totals in step
+// with SyncProducts.
+func Record(tx *gorm.DB, m Movement) (*Entry, error) {
+	change, err := m.change()
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		ProductID string
+		Inventory int
+	}
+	if err := tx.Raw(`UPDATE product_variants SET inventory = inventory + ?
+		WHERE sku = ? AND inventory + ? >= 0
+		RETURNING product_id, inventory`, change, m.SKU, change).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		var exists int64
+		if err := tx.Table("product_variants").Where("sku = ?", m.SKU).Count(&exists).Error; err != nil {
+			return nil, err
+		}
+		if exists == 0 {
+			return nil, fmt.Errorf("%w: %s", ErrVariantNotFound, m.SKU)
+		}
+		return nil, fmt.Errorf("%w: variant %s cannot give up %d", ErrInsufficientStock, m.SKU, -change)
+	}
+
+	quantity := m.Quantity
+	if quantity < 0 {
+		quantity = -quantity
+	}
+	entry := &Entry{
+		ID:        "led_" + uuid.NewString(),
+		SKU:       m.SKU,
+		ProductID: rows[0].ProductID,
+		Kind:      m.Kind,
+		Quantity:  quantity,
+		Change:    change,
+		Balance:   rows[0].Inventory,
+		Reason:    m.Reason,
+		Actor:     m.Actor,
+		OrderID:   m.OrderID,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := tx.Create(entry).Error; err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// SyncProducts recomputes the inventory and availability the products table
+// keeps as totals over each product's variants. Both services call it after
+// changing variants, in the same transaction. Products are updated in ID
+// order so concurrent syncs cannot deadlock.
+func SyncProducts(tx *gorm.DB, productIDs ...string) error {
+	ids := uniqueSorted(productIDs)
+	if len(ids) == 0 {
+		return nil
+	}
+	return tx.Exec(`UPDATE products SET
+		inventory = (SELECT COALESCE(SUM(inventory), 0) FROM product_variants WHERE product_id = products.id),
+		available = EXISTS (SELECT 1 FROM product_variants WHERE product_id = products.id AND available)
+		WHERE id IN ?`, ids).Error
+}
+
+func uniqueSorted(ids []string) []string {
+	sorted := append([]string(nil), ids...)
+	sort.Strings(sorted)
+	unique := sorted[:0]
+	for i, id := range sorted {
+		if i == 0 || id != sorted[i-1] {
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,39 @@
+package inventory
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMovementChange(t *testing.T) {
+	tests := []struct {
+		m       Movement
+		want    int
+		wantErr bool
+	}{
+		{Movement{Kind: KindRestock, Quantity: 5}, 5, false},
+		{Movement{Kind: KindReservation, Quantity: 2}, -2, false},
+		{Movement{Kind: KindRelease, Quantity: 2}, 2, false},
+		{Movement{Kind: KindSale, Quantity: 2}, 0, false},
+		{Movement{Kind: KindAdjustment, Quantity: -3}, -3, false},
+		{Movement{Kind: KindRestock, Quantity: -1}, 0, true},
+		{Movement{Kind: KindReservation, Quantity: 0}, 0, true},
+		{Movement{Kind: "LOST", Quantity: 1}, 0, true},
+	}
+	for _, tt := range tests {
+		got, err := tt.m.change()
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("%+v.change() = %d, %v; want %d, err %v", tt.m, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestUniqueSorted(t *testing.T) {
+	got := uniqueSorted([]string{"p2", "p1", "p2", "p3", "p1"})
+	if want := []string{"p1", "p2", "p3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("uniqueSorted() = %v, want %v", got, want)
+	}
+	if got := uniqueSorted(nil); len(got) != 0 {
+		t.Errorf("uniqueSorted(nil) = %v, want empty", got)
+	}
+}
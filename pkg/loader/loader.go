@@ -0,0 +1,51 @@
+// Package loader builds request-scoped DataLoaders. Each loader collapses
+// the lookups made while resolving one request into a single batched query
+// per type, and caches the results until the request ends.
+package loader
+
+import (
+	"context"
+	"time"
+
+	"github.com/graph-gophers/dataloader/v7"
+)
+
+// wait is how long a loader collects keys before fetching them. Federated
+// entity lookups arrive together, so a short window is enough.
+const wait = 2 * time.Millisecond
+
+// Fetch loads the values for keys, typically with one WHERE id IN query.
+// Keys without a value are left out of the map.
+type Fetch[K comparable, V any] func(ctx context.Context, keys []K) (map[K]V, error)
+
+// Loader batches and caches lookups of V by K.
+type Loader[K comparable, V any] struct {
+	loader *dataloader.Loader[K, V]
+}
+
+// New returns a loader backed by fetch. Keys that fetch does not return
+// resolve to notFound(key), or to the zero value when notFound is nil.
+func New[K comparable, V any](fetch Fetch[K, V], notFound func(K) error) *Loader[K, V] {
+	batch := func(ctx context.Context, keys []K) []*dataloader.Result[V] {
+		results := make([]*dataloader.Result[V], len(keys))
+
+		values, err := fetch(ctx, keys)
+		for i, key := range keys {
+			switch value, ok := values[key]; {
+			case err != nil:
+				results[i] = &dataloader.Result[V]{Error: err}
+			case !ok && notFound != nil:
+				results[i] = &dataloader.Result[V]{Error: notFound(key)}
+			default:
+				results[i] = &dataloader.Result[V]{Data: value}
+			}
+		}
+		return results
+	}
+	return &Loader[K, V]{loader: dataloader.NewBatchedLoader(batch, dataloader.WithWait[K, V](wait))}
+}
+
+// Load returns the value for key, waiting for the batch it joins.
+func (l *Loader[K, V]) Load(ctx context.Context, key K) (V, error) {
+	return l.loader.Load(ctx, key)()
+}
@@ -0,0 +1,119 @@
+package loader
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestLoader_BatchesAndCaches(t *testing.T) {
+	var mu sync.Mutex
+	var batches [][]string
+	errMissing := errors.New("missing")
+
+	l := New(func(ctx context.Context, keys []string) (map[string]int, error) {
+		mu.Lock()
+		batches = append(batches, keys)
+		mu.Unlock()
+		values := map[string]int{}
+		for _, k := range keys {
+			if k != "missing" {
+				values[k] = len(k)
+			}
+		}
+		return values, nil
+	}, func(string) error { return errMissing })
+
+	keys := []string{"a", "bb", "ccc", "a", "missing"}
+	got := make([]int, len(keys))
+	errs := make([]error, len(keys))
+	var wg sync.WaitGroup
+	for i, k := range keys {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got[i], errs[i] = l.Load(context.Background(), k)
+		}()
+	}
+	wg.Wait()
+
+	if len(batches) != 1 {
+		t.Fatalf("fetched in %d batches, want 1", len(batches))
+	}
+	if len(batches[0]) != 4 {
+		t.Errorf("batch %v, want each key once", batches[0])
+	}
+	for i, want := range []int{1, 2, 3, 1} {
+		if errs[i] != nil || got[i] != want {
+			t.Errorf("Load(%q) = %d, %v; want %d", keys[i], got[i], errs[i], want)
+		}
+	}
+	if !errors.Is(errs[4], errMissing) {
+		t.Errorf("Load(missing) err = %v, want %v", errs[4], errMissing)
+	}
+
+	// A repeat lookup is served from the cache
+	if v, err := l.Load(context.Background(), "bb"); err != nil || v != 2 {
+		t.Errorf("cached Load = %d, %v", v, err)
+	}
+	if len(batches) != 1 {
+		t.Errorf("cached key was fetched again")
+	}
+}
+
+func TestLoader_FetchErrorFailsWholeBatch(t *testing.T) {
+	boom := errors.New("boom")
+	l := New(func(ctx context.Context, keys []string) (map[string]int, error) {
+		return nil, boom
+	}, nil)
+
+	if _, err := l.Load(context.Background(), "a"); !errors.Is(err, boom) {
+		t.Errorf("err = %v, want %v", err, boom)
+	}
+}
+
+func TestLoader_NilNotFoundReturnsZeroValue(t *testing.T) {
+	l := New(func(ctx context.Context, keys []string) (map[string][]int, error) {
+		return map[string][]int{}, nil
+	}, nil)
+
+	v, err := l.Load(context.Background(), "a")
+	if err != nil || v != nil {
+		t.Errorf("Load = %v, %v; want nil, nil", v, err)
+	}
+}
+
+func TestMiddleware_FreshLoadersPerRequest(t *testing.T) {
+	type loaders struct{ n int }
+	created := 0
+	var seen []*loaders
+	handler := Middleware(func() *loaders {
+		created++
+		return &loaders{n: created}
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, For[*loaders](r.Context()))
+	}))
+
+	for i := 0; i < 2; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/query", nil))
+	}
+
+	if len(seen) != 2 || seen[0].n != 1 || seen[1].n != 2 {
+		t.Errorf("requests saw loaders %+v, %+v; want one fresh set each", seen[0], seen[1])
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	l := New(func(ctx context.Context, keys []string) (map[string]int, error) {
+		return map[string]int{}, nil
+	}, NotFound)
+
+	if _, err := l.Load(context.Background(), "a"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Load error = %v, want gorm.ErrRecordNotFound", err)
+	}
+}
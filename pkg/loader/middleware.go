@@ -0,0 +1,33 @@
+package loader
+
+import (
+	"context"
+	"net/http"
+
+	"gorm.io/gorm"
+)
+
+// NotFound is the notFound of loaders whose values are database records: a
+// missing key fails with the error a single lookup would have returned.
+func NotFound[K any](K) error {
+	return gorm.ErrRecordNotFound
+}
+
+// ctxKey stores a service's loaders of type T in the request context.
+type ctxKey[T any] struct{}
+
+// Middleware gives each request a fresh set of loaders from newLoaders, so
+// cached results never outlive the request.
+func Middleware[T any](newLoaders func() T) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), ctxKey[T]{}, newLoaders())
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// For returns the loaders Middleware stored for the current request.
+func For[T any](ctx context.Context) T {
+	return ctx.Value(ctxKey[T]{}).(T)
+}
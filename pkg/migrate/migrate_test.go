@@ -0,0 +1,70 @@
+package migrate
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0002_add_index.up.sql":     {Data: []byte("CREATE INDEX i ON t (c);")},
+		"0002_add_index.down.sql":   {Data: []byte("DROP INDEX i;")},
+		"0001_create_table.up.sql":  {Data: []byte("CREATE TABLE t (c text);")},
+		"0010_backfill_data.up.sql": {Data: []byte("UPDATE t SET c = 'x';")},
+	}
+
+	migrations, err := Load(fsys)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	var names []string
+	for _, m := range migrations {
+		names = append(names, m.String())
+	}
+	if got, want := strings.Join(names, ","), "0001_create_table,0002_add_index,0010_backfill_data"; got != want {
+		t.Errorf("order = %s, want %s", got, want)
+	}
+	if migrations[1].Down != "DROP INDEX i;" {
+		t.Errorf("down = %q", migrations[1].Down)
+	}
+	if migrations[2].Down != "" {
+		t.Errorf("irreversible migration has down %q", migrations[2].Down)
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+		want string
+	}{
+		{
+			name: "bad file name",
+			fsys: fstest.MapFS{"create_table.sql": {}},
+			want: "name must look like",
+		},
+		{
+			name: "down without up",
+			fsys: fstest.MapFS{"0001_create_table.down.sql": {}},
+			want: "missing up file",
+		},
+		{
+			name: "two names for one version",
+			fsys: fstest.MapFS{
+				"0001_create_table.up.sql": {},
+				"0001_other_name.down.sql": {},
+			},
+			want: "conflicting names",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(tt.fsys)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
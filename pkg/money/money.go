@@ -0,0 +1,132 @@
+// Package money represents amounts as integer minor units, e.g. cents, with
+// an ISO 4217 currency code, so totals never pick up floating point errors.
+package money
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tagaertner/e-commerce-graphql/pkg/apperr"
+)
+
+// DefaultCurrency is used where no currency is given.
+const DefaultCurrency = "USD"
+
+var (
+	// ErrCurrencyMismatch is returned when amounts in different currencies
+	// are combined.
+	ErrCurrencyMismatch error = apperr.New(apperr.CodeValidation, "currency mismatch")
+	// ErrInvalidCurrency is returned for codes that are not three upper-case
+	// letters.
+	ErrInvalidCurrency error = apperr.New(apperr.CodeValidation, "invalid currency code")
+)
+
+// Money is bound to the Money type and MoneyInput of every subgraph. Stored
+// with GORM's embedded tag it maps to <prefix>amount and <prefix>currency.
+type Money struct {
+	// Amount in the currency's minor units, e.g. cents for USD.
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// New returns amount minor units of currency.
+func New(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// Zero returns no money in currency.
+func Zero(currency string) Money {
+	return Money{Currency: currency}
+}
+
+// ValidateCurrency checks that code looks like an ISO 4217 code.
+func ValidateCurrency(code string) error {
+	if len(code) != 3 {
+		return fmt.Errorf("%w: %q", ErrInvalidCurrency, code)
+	}
+	for _, c := range code {
+		if c < 'A' || c > 'Z' {
+			return fmt.Errorf("%w: %q", ErrInvalidCurrency, code)
+		}
+	}
+	return nil
+}
+
+// Validate checks the currency code and that the amount is not negative.
+func (m Money) Validate() error {
+	if err := ValidateCurrency(m.Currency); err != nil {
+		return err
+	}
+	if m.Amount < 0 {
+		return apperr.Validation("amount cannot be negative")
+	}
+	return nil
+}
+
+// Add returns m + o. Both must be in the same currency.
+func (m Money) Add(o Money) (Money, error) {
+	if m.Currency != o.Currency {
+		return Money{}, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, o.Currency)
+	}
+	return Money{Amount: m.Amount + o.Amount, Currency: m.Currency}, nil
+}
+
+// Mul returns m multiplied by n, e.g. a unit price by a quantity.
+func (m Money) Mul(n int64) Money {
+	return Money{Amount: m.Amount * n, Currency: m.Currency}
+}
+
+// Sum adds amounts that must all share one currency. With no amounts it
+// returns Zero(DefaultCurrency).
+func Sum(amounts ...Money) (Money, error) {
+	if len(amounts) == 0 {
+		return Zero(DefaultCurrency), nil
+	}
+	total := Zero(amounts[0].Currency)
+	for _, a := range amounts {
+		var err error
+		if total, err = total.Add(a); err != nil {
+			return Money{}, err
+		}
+	}
+	return total, nil
+}
+
+// Formatted renders the amount in major units followed by the currency,
+// e.g. "1999.99 USD".
+func (m Money) Formatted() string {
+	digits := MinorDigits(m.Currency)
+	amount := m.Amount
+	sign := ""
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+	s := strconv.FormatInt(amount, 10)
+	if digits > 0 {
+		if len(s) <= digits {
+			s = strings.Repeat("0", digits-len(s)+1) + s
+		}
+		s = s[:len(s)-digits] + "." + s[len(s)-digits:]
+	}
+	return sign + s + " " + m.Currency
+}
+
+// zeroDecimal and threeDecimal list the currencies whose minor unit is not
+// a hundredth.
+var (
+	zeroDecimal  = map[string]bool{"BIF": true, "CLP": true, "DJF": true, "GNF": true, "ISK": true, "JPY": true, "KMF": true, "KRW": true, "PYG": true, "RWF": true, "UGX": true, "VND": true, "VUV": true, "XAF": true, "XOF": true, "XPF": true}
+	threeDecimal = map[string]bool{"BHD": true, "IQD": true, "JOD": true, "KWD": true, "LYD": true, "OMR": true, "TND": true}
+)
+
+// MinorDigits returns how many decimal places the currency's minor unit
+// has: 2 for most currencies, 0 for e.g. JPY and 3 for e.g. KWD.
+func MinorDigits(currency string) int {
+	switch {
+	case zeroDecimal[currency]:
+		return 0
+	case threeDecimal[currency]:
+		return 3
+	}
+	return 2
+}
@@ -0,0 +1,57 @@
+package money
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSum(t *testing.T) {
+	total, err := Sum(New(199, "USD"), New(2999, "USD").Mul(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != New(6197, "USD") {
+		t.Errorf("Sum = %+v", total)
+	}
+
+	if _, err := Sum(New(100, "USD"), New(100, "EUR")); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("mixed currencies err = %v, want ErrCurrencyMismatch", err)
+	}
+
+	if total, _ := Sum(); total != Zero(DefaultCurrency) {
+		t.Errorf("empty Sum = %+v", total)
+	}
+}
+
+func TestFormatted(t *testing.T) {
+	tests := []struct {
+		m    Money
+		want string
+	}{
+		{New(199999, "USD"), "1999.99 USD"},
+		{New(5, "USD"), "0.05 USD"},
+		{New(0, "EUR"), "0.00 EUR"},
+		{New(-150, "USD"), "-1.50 USD"},
+		{New(1500, "JPY"), "1500 JPY"},
+		{New(1234, "KWD"), "1.234 KWD"},
+	}
+	for _, tt := range tests {
+		if got := tt.m.Formatted(); got != tt.want {
+			t.Errorf("%+v.Formatted() = %q, want %q", tt.m, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, m := range []Money{New(1, "usd"), New(1, "US"), New(1, "")} {
+		if err := m.Validate(); !errors.Is(err, ErrInvalidCurrency) {
+			t.Errorf("%+v.Validate() = %v, want ErrInvalidCurrency", m, err)
+		}
+	}
+	if err := New(-1, "USD").Validate(); err == nil {
+		t.Errorf("negative amount accepted")
+	}
+	if err := New(0, "USD").Validate(); err != nil {
+		t.Errorf("zero amount rejected: %v", err)
+	}
+}
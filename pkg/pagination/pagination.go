@@ -0,0 +1,275 @@
+// Package pagination implements Relay-style connections over keyset
+// ordered queries: opaque cursors, first/after/last/before arguments and
+// PageInfo.
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/tagaertner/e-commerce-graphql/pkg/apperr"
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultPageSize = 10
+	MaxPageSize     = 100
+)
+
+// CursorVersion is written into every cursor. Cursors from other versions
+// are rejected, so the format can change without misreading old cursors.
+const CursorVersion = 1
+
+// ErrInvalidCursor is returned for cursors this package did not produce, or
+// produced for a different sort.
+var ErrInvalidCursor error = apperr.New(apperr.CodeValidation, "invalid cursor")
+
+// Sort is the order a keyset page is read in: Column, then id to break ties,
+// both in the same direction.
+type Sort struct {
+	// Name identifies the sort in cursors, so a cursor from one sort cannot
+	// be replayed against another.
+	Name string
+	// Column is the SQL column to sort by. Empty sorts by id alone.
+	Column string
+	Desc   bool
+	// Key is a zero value of Column's Go type. Cursor keys decode into it.
+	Key any
+}
+
+// ByID sorts by id, ascending.
+var ByID = Sort{Name: "ID"}
+
+// Cursor is a row's position in a Sort: its Column value and its id.
+type Cursor struct {
+	Key any
+	ID  string
+}
+
+type wireCursor struct {
+	Version int             `json:"v"`
+	Sort    string          `json:"s"`
+	Key     json.RawMessage `json:"k,omitempty"`
+	ID      string          `json:"i"`
+}
+
+// Encode returns the opaque form of c handed to clients.
+func (s Sort) Encode(c Cursor) string {
+	w := wireCursor{Version: CursorVersion, Sort: s.Name, ID: c.ID}
+	if s.Column != "" {
+		w.Key, _ = json.Marshal(c.Key)
+	}
+	b, _ := json.Marshal(w)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// Decode parses a cursor produced by Encode for the same sort.
+func (s Sort) Decode(str string) (Cursor, error) {
+	var w wireCursor
+	b, err := base64.RawURLEncoding.DecodeString(str)
+	if err != nil || json.Unmarshal(b, &w) != nil || w.ID == "" {
+		return Cursor{}, ErrInvalidCursor
+	}
+	if w.Version != CursorVersion || w.Sort != s.Name {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	c := Cursor{ID: w.ID}
+	if s.Column == "" {
+		return c, nil
+	}
+	if len(w.Key) == 0 || s.Key == nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	key := reflect.New(reflect.TypeOf(s.Key))
+	if err := json.Unmarshal(w.Key, key.Interface()); err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	c.Key = key.Elem().Interface()
+	return c, nil
+}
+
+// Args are the Relay connection arguments.
+type Args struct {
+	First  *int
+	After  *string
+	Last   *int
+	Before *string
+}
+
+// ArgsKey is a comparable form of Args, for use as a DataLoader key.
+type ArgsKey struct {
+	First, Last       int
+	HasFirst, HasLast bool
+	After, Before     string
+}
+
+// Key returns the comparable form of a.
+func (a Args) Key() ArgsKey {
+	var k ArgsKey
+	if a.First != nil {
+		k.First, k.HasFirst = *a.First, true
+	}
+	if a.Last != nil {
+		k.Last, k.HasLast = *a.Last, true
+	}
+	if a.After != nil {
+		k.After = *a.After
+	}
+	if a.Before != nil {
+		k.Before = *a.Before
+	}
+	return k
+}
+
+// Args turns the key back into arguments.
+func (k ArgsKey) Args() Args {
+	var a Args
+	if k.HasFirst {
+		a.First = &k.First
+	}
+	if k.HasLast {
+		a.Last = &k.Last
+	}
+	if k.After != "" {
+		a.After = &k.After
+	}
+	if k.Before != "" {
+		a.Before = &k.Before
+	}
+	return a
+}
+
+// Page is a validated request for one page of results.
+type Page struct {
+	Limit  int
+	Sort   Sort
+	After  *Cursor
+	Before *Cursor
+	// Backward is set when the caller asked for the last rows.
+	Backward bool
+}
+
+// Page validates the arguments for a list read in sort order. first and
+// last cannot be combined; without either, the first DefaultPageSize rows
+// are returned.
+func (a Args) Page(sort Sort) (Page, error) {
+	p := Page{Limit: DefaultPageSize, Sort: sort}
+
+	switch {
+	case a.First != nil && a.Last != nil:
+		return Page{}, apperr.Validation("first and last cannot be combined")
+	case a.First != nil:
+		p.Limit = *a.First
+	case a.Last != nil:
+		p.Limit = *a.Last
+		p.Backward = true
+	}
+	if p.Limit <= 0 || p.Limit > MaxPageSize {
+		return Page{}, apperr.Validation("page size must be between 1 and %d", MaxPageSize)
+	}
+
+	if a.After != nil && *a.After != "" {
+		c, err := sort.Decode(*a.After)
+		if err != nil {
+			return Page{}, fmt.Errorf("after: %w", err)
+		}
+		p.After = &c
+	}
+	if a.Before != nil && *a.Before != "" {
+		c, err := sort.Decode(*a.Before)
+		if err != nil {
+			return Page{}, fmt.Errorf("before: %w", err)
+		}
+		p.Before = &c
+	}
+	return p, nil
+}
+
+// Apply restricts q to the page, read in the page's sort. One extra row is
+// fetched so Trim can tell whether more pages exist. Backward pages are read
+// in reverse and put back in order by Trim.
+func (p Page) Apply(q *gorm.DB) *gorm.DB {
+	return p.Filter(q).Order(p.OrderBy()).Limit(p.Limit + 1)
+}
+
+// Filter keeps only the rows between the page's cursors, for queries that
+// need to order and limit rows themselves.
+func (p Page) Filter(q *gorm.DB) *gorm.DB {
+	after, before := ">", "<"
+	if p.Sort.Desc {
+		after, before = before, after
+	}
+	if p.After != nil {
+		q = p.keyset(q, after, p.After)
+	}
+	if p.Before != nil {
+		q = p.keyset(q, before, p.Before)
+	}
+	return q
+}
+
+// OrderBy returns the ORDER BY expression Apply uses.
+func (p Page) OrderBy() string {
+	dir := " ASC"
+	if p.Sort.Desc != p.Backward {
+		dir = " DESC"
+	}
+	if p.Sort.Column == "" {
+		return "id" + dir
+	}
+	return p.Sort.Column + dir + ", id" + dir
+}
+
+func (p Page) keyset(q *gorm.DB, op string, c *Cursor) *gorm.DB {
+	if p.Sort.Column == "" {
+		return q.Where("id "+op+" ?", c.ID)
+	}
+	return q.Where("("+p.Sort.Column+", id) "+op+" (?, ?)", c.Key, c.ID)
+}
+
+// PageInfo is bound to the PageInfo type of every subgraph.
+type PageInfo struct {
+	HasNextPage     bool    `json:"hasNextPage"`
+	HasPreviousPage bool    `json:"hasPreviousPage"`
+	StartCursor     *string `json:"startCursor"`
+	EndCursor       *string `json:"endCursor"`
+}
+
+// Trim drops the extra row fetched by Apply, restores sort order and
+// returns each row's cursor along with the PageInfo.
+//
+// As the Relay spec allows, the page on the far side of the cursor the
+// caller paged from is reported as present without querying for it.
+func Trim[T any](rows []T, p Page, cursorOf func(T) Cursor) ([]T, []string, PageInfo) {
+	more := len(rows) > p.Limit
+	if more {
+		rows = rows[:p.Limit]
+	}
+	if p.Backward {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+
+	var info PageInfo
+	if p.Backward {
+		info.HasPreviousPage = more
+		info.HasNextPage = p.Before != nil
+	} else {
+		info.HasNextPage = more
+		info.HasPreviousPage = p.After != nil
+	}
+
+	cursors := make([]string, len(rows))
+	for i, row := range rows {
+		cursors[i] = p.Sort.Encode(cursorOf(row))
+	}
+	if len(cursors) > 0 {
+		info.StartCursor = &cursors[0]
+		info.EndCursor = &cursors[len(cursors)-1]
+	}
+	return rows, cursors, info
+}
@@ -0,0 +1,145 @@
+package pagination
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func intPtr(n int) *int       { return &n }
+func strPtr(s string) *string { return &s }
+
+var byCreatedAt = Sort{Name: "CREATED_AT", Column: "created_at", Key: time.Time{}}
+
+func TestSort_CursorRoundTrip(t *testing.T) {
+	c := Cursor{Key: time.Date(2025, 1, 2, 3, 4, 5, 6, time.UTC), ID: "order_1"}
+	got, err := byCreatedAt.Decode(byCreatedAt.Encode(c))
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if !got.Key.(time.Time).Equal(c.Key.(time.Time)) || got.ID != c.ID {
+		t.Errorf("round trip = %+v, want %+v", got, c)
+	}
+
+	byPrice := Sort{Name: "PRICE_DESC", Column: "price", Desc: true, Key: float64(0)}
+	got, err = byPrice.Decode(byPrice.Encode(Cursor{Key: 19.99, ID: "p1"}))
+	if err != nil || got != (Cursor{Key: 19.99, ID: "p1"}) {
+		t.Errorf("price round trip = %+v, %v", got, err)
+	}
+
+	got, err = ByID.Decode(ByID.Encode(Cursor{ID: "5"}))
+	if err != nil || got != (Cursor{ID: "5"}) {
+		t.Errorf("id round trip = %+v, %v", got, err)
+	}
+
+	bad := []string{
+		"", "not base64!", "bm90IGpzb24",
+		byCreatedAt.Encode(Cursor{}),
+		ByID.Encode(Cursor{ID: "5"}),
+		byPrice.Encode(Cursor{Key: 19.99, ID: "p1"}),
+		byCreatedAt.Encode(Cursor{Key: "yesterday", ID: "o1"}),
+		// version 0
+		"eyJzIjoiQ1JFQVRFRF9BVCIsImsiOiIyMDI1LTAxLTAyVDAzOjA0OjA1WiIsImkiOiJvMSJ9",
+	}
+	for _, str := range bad {
+		if _, err := byCreatedAt.Decode(str); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("Decode(%q) err = %v, want ErrInvalidCursor", str, err)
+		}
+	}
+}
+
+func TestArgs_Page(t *testing.T) {
+	after := ByID.Encode(Cursor{ID: "5"})
+
+	tests := []struct {
+		name    string
+		args    Args
+		want    Page
+		wantErr string
+	}{
+		{name: "defaults", args: Args{}, want: Page{Limit: DefaultPageSize}},
+		{name: "first", args: Args{First: intPtr(3), After: &after}, want: Page{Limit: 3, After: &Cursor{ID: "5"}}},
+		{name: "last", args: Args{Last: intPtr(4)}, want: Page{Limit: 4, Backward: true}},
+		{name: "both", args: Args{First: intPtr(1), Last: intPtr(1)}, wantErr: "cannot be combined"},
+		{name: "too many", args: Args{First: intPtr(MaxPageSize + 1)}, wantErr: "between 1 and"},
+		{name: "zero", args: Args{Last: intPtr(0)}, wantErr: "between 1 and"},
+		{name: "bad cursor", args: Args{Before: strPtr("garbage")}, wantErr: "invalid cursor"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.args.Page(ByID)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Limit != tt.want.Limit || got.Backward != tt.want.Backward ||
+				(got.After == nil) != (tt.want.After == nil) || (got.After != nil && *got.After != *tt.want.After) {
+				t.Errorf("Page = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrim(t *testing.T) {
+	cursorOf := func(id string) Cursor { return Cursor{ID: id} }
+
+	rows, cursors, info := Trim([]string{"1", "2", "3"}, Page{Limit: 2, Sort: ByID}, cursorOf)
+	if strings.Join(rows, ",") != "1,2" || len(cursors) != 2 {
+		t.Errorf("forward rows = %v", rows)
+	}
+	if !info.HasNextPage || info.HasPreviousPage || *info.EndCursor != cursors[1] {
+		t.Errorf("forward info = %+v", info)
+	}
+
+	// Backward pages are fetched newest first and returned oldest first
+	before := Cursor{ID: "9"}
+	rows, _, info = Trim([]string{"8", "7", "6"}, Page{Limit: 2, Sort: ByID, Backward: true, Before: &before}, cursorOf)
+	if strings.Join(rows, ",") != "7,8" {
+		t.Errorf("backward rows = %v", rows)
+	}
+	if !info.HasPreviousPage || !info.HasNextPage {
+		t.Errorf("backward info = %+v", info)
+	}
+
+	_, _, info = Trim([]string{}, Page{Limit: 2, Sort: ByID}, cursorOf)
+	if info.StartCursor != nil || info.EndCursor != nil || info.HasNextPage {
+		t.Errorf("empty info = %+v", info)
+	}
+}
+
+func TestArgsKey_RoundTrip(t *testing.T) {
+	args := Args{Last: intPtr(5), Before: strPtr(ByID.Encode(Cursor{ID: "9"}))}
+	if args.Key() != args.Key().Args().Key() {
+		t.Errorf("key changed on round trip")
+	}
+	if (Args{First: intPtr(0)}).Key() == (Args{}).Key() {
+		t.Errorf("explicit zero first and no first share a key")
+	}
+}
+
+func TestPage_OrderBy(t *testing.T) {
+	byName := Sort{Name: "NAME_DESC", Column: "name", Desc: true, Key: ""}
+
+	tests := []struct {
+		page Page
+		want string
+	}{
+		{Page{Sort: ByID}, "id ASC"},
+		{Page{Sort: ByID, Backward: true}, "id DESC"},
+		{Page{Sort: byCreatedAt}, "created_at ASC, id ASC"},
+		{Page{Sort: byName}, "name DESC, id DESC"},
+		// Backward pages over a descending sort read ascending
+		{Page{Sort: byName, Backward: true}, "name ASC, id ASC"},
+	}
+	for _, tt := range tests {
+		if got := tt.page.OrderBy(); got != tt.want {
+			t.Errorf("OrderBy(%+v) = %q, want %q", tt.page, got, tt.want)
+		}
+	}
+}
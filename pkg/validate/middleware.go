@@ -0,0 +1,51 @@
+package validate
+
+import (
+	"context"
+	"sync"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/tagaertner/e-commerce-graphql/pkg/apperr"
+)
+
+type collectorKey struct{}
+
+// collector holds the violations found while reading each field's arguments.
+// Sibling fields resolve concurrently, so violations are kept per field.
+type collector struct {
+	mu         sync.Mutex
+	violations map[*graphql.FieldContext][]apperr.FieldViolation
+}
+
+func (c *collector) add(fc *graphql.FieldContext, violations []apperr.FieldViolation) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.violations[fc] = append(c.violations[fc], violations...)
+}
+
+func (c *collector) take(fc *graphql.FieldContext) []apperr.FieldViolation {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	violations := c.violations[fc]
+	delete(c.violations, fc)
+	return violations
+}
+
+// Collect is a gqlgen root field middleware that makes Constraint record
+// violations instead of failing on the first one.
+func Collect(ctx context.Context, next graphql.RootResolver) graphql.Marshaler {
+	c := &collector{violations: map[*graphql.FieldContext][]apperr.FieldViolation{}}
+	return next(context.WithValue(ctx, collectorKey{}, c))
+}
+
+// Enforce is a gqlgen field middleware. It runs once a field's arguments have
+// been read, and fails the field with a VALIDATION error listing every
+// violation Constraint recorded, so the resolver never sees invalid input.
+func Enforce(ctx context.Context, next graphql.Resolver) (interface{}, error) {
+	if c, ok := ctx.Value(collectorKey{}).(*collector); ok {
+		if violations := c.take(graphql.GetFieldContext(ctx)); len(violations) > 0 {
+			return nil, apperr.Invalid(violations...)
+		}
+	}
+	return next(ctx)
+}
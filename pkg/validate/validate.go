@@ -0,0 +1,186 @@
+// Package validate implements the @constraint schema directive shared by all
+// subgraphs, and collects field violations so a request learns about every
+// invalid field at once rather than one per round trip.
+package validate
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/tagaertner/e-commerce-graphql/pkg/apperr"
+)
+
+// Format is a well-known string format. It is bound to the ConstraintFormat
+// enum in every subgraph.
+type Format string
+
+const (
+	FormatEmail Format = "EMAIL"
+)
+
+// Constraint implements the @constraint directive on input fields. Null
+// values are left alone; required fields are the schema's business. min and
+// max bound integers, and minLength, maxLength, pattern and format apply to
+// strings. minLength ignores surrounding whitespace, so minLength: 1 rejects
+// blank strings.
+//
+// Under Collect, violations are recorded and reported together by Enforce
+// before the field resolves. Otherwise the first violation fails the field.
+func Constraint(ctx context.Context, obj interface{}, next graphql.Resolver, min *int, max *int, minLength *int, maxLength *int, pattern *string, format *Format) (interface{}, error) {
+	value, err := next(ctx)
+	if err != nil {
+		return value, err
+	}
+
+	messages, err := check(value, rules{min, max, minLength, maxLength, pattern, format})
+	if err != nil || len(messages) == 0 {
+		return value, err
+	}
+	field := fieldPath(ctx)
+	violations := make([]apperr.FieldViolation, len(messages))
+	for i, m := range messages {
+		violations[i] = apperr.FieldViolation{Field: field, Message: m}
+	}
+
+	if c, ok := ctx.Value(collectorKey{}).(*collector); ok {
+		c.add(graphql.GetFieldContext(ctx), violations)
+		return value, nil
+	}
+	return value, apperr.Invalid(violations...)
+}
+
+type rules struct {
+	min, max             *int
+	minLength, maxLength *int
+	pattern              *string
+	format               *Format
+}
+
+func check(value interface{}, r rules) ([]string, error) {
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, nil
+		}
+		v = v.Elem()
+	}
+
+	var messages []string
+	switch v.Kind() {
+	case reflect.Invalid:
+		return nil, nil
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		n := v.Int()
+		if r.min != nil && n < int64(*r.min) {
+			messages = append(messages, fmt.Sprintf("must be at least %d", *r.min))
+		}
+		if r.max != nil && n > int64(*r.max) {
+			messages = append(messages, fmt.Sprintf("must be at most %d", *r.max))
+		}
+	case reflect.String:
+		s := v.String()
+		if n := utf8.RuneCountInString(strings.TrimSpace(s)); r.minLength != nil && n < *r.minLength {
+			if *r.minLength == 1 {
+				messages = append(messages, "must not be blank")
+			} else {
+				messages = append(messages, fmt.Sprintf("must be at least %d characters", *r.minLength))
+			}
+		}
+		if r.maxLength != nil && utf8.RuneCountInString(s) > *r.maxLength {
+			messages = append(messages, fmt.Sprintf("must be at most %d characters", *r.maxLength))
+		}
+		if r.pattern != nil {
+			re, err := compile(*r.pattern)
+			if err != nil {
+				return nil, apperr.Internal(fmt.Errorf("@constraint pattern %q: %w", *r.pattern, err))
+			}
+			if !re.MatchString(s) {
+				messages = append(messages, fmt.Sprintf("must match %s", *r.pattern))
+			}
+		}
+		if r.format != nil {
+			switch *r.format {
+			case FormatEmail:
+				if !IsEmail(s) {
+					messages = append(messages, "must be an email address")
+				}
+			default:
+				return nil, apperr.Internal(fmt.Errorf("@constraint format %q is not supported", *r.format))
+			}
+		}
+	default:
+		return nil, apperr.Internal(fmt.Errorf("@constraint does not apply to %T", value))
+	}
+	return messages, nil
+}
+
+// patterns caches compiled @constraint patterns; a schema has only a few.
+var patterns sync.Map
+
+func compile(pattern string) (*regexp.Regexp, error) {
+	if re, ok := patterns.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	patterns.Store(pattern, re)
+	return re, nil
+}
+
+// IsEmail reports whether s is a bare email address, e.g. "ada@example.com"
+// but not "Ada <ada@example.com>".
+func IsEmail(s string) bool {
+	addr, err := mail.ParseAddress(s)
+	return err == nil && addr.Address == s && strings.Contains(s[strings.LastIndex(s, "@"):], ".")
+}
+
+// fieldPath names the input field being read within its argument, the way
+// services name fields in their own violations, e.g. "lineItems[0].quantity"
+// for a field of the input argument.
+func fieldPath(ctx context.Context) string {
+	var parts []string
+	for pc := graphql.GetPathContext(ctx); pc != nil; pc = pc.Parent {
+		switch {
+		case pc.Index != nil:
+			parts = append(parts, fmt.Sprintf("[%d]", *pc.Index))
+		case pc.Field != nil:
+			parts = append(parts, "."+*pc.Field)
+		}
+	}
+	if len(parts) > 1 {
+		// Drop the argument's own name
+		parts = parts[:len(parts)-1]
+	}
+	var b strings.Builder
+	for i := len(parts) - 1; i >= 0; i-- {
+		b.WriteString(parts[i])
+	}
+	return strings.TrimPrefix(b.String(), ".")
+}
+
+// Violations collects field violations found by a service, so it can report
+// them all in one VALIDATION error.
+type Violations []apperr.FieldViolation
+
+// Add records a violation of field.
+func (v *Violations) Add(field, format string, args ...interface{}) {
+	*v = append(*v, apperr.FieldViolation{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// Err returns nil when nothing was recorded, and otherwise a VALIDATION error
+// listing every violation.
+func (v Violations) Err() error {
+	if len(v) == 0 {
+		return nil
+	}
+	return apperr.Invalid(v...)
+}
@@ -0,0 +1,126 @@
+package validate
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/tagaertner/e-commerce-graphql/pkg/apperr"
+)
+
+func intp(n int) *int          { return &n }
+func strp(s string) *string    { return &s }
+func formatp(f Format) *Format { return &f }
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		rules rules
+		want  int
+	}{
+		{"int in range", 5, rules{min: intp(1), max: intp(10)}, 0},
+		{"int below min", 0, rules{min: intp(1)}, 1},
+		{"int above max", 11, rules{max: intp(10)}, 1},
+		{"null", (*int)(nil), rules{min: intp(1)}, 0},
+		{"pointer", intp(-1), rules{min: intp(0)}, 1},
+		{"blank", "   ", rules{minLength: intp(1)}, 1},
+		{"too long", "abcdef", rules{maxLength: intp(5)}, 1},
+		{"pattern", "usd", rules{pattern: strp("^[A-Z]{3}$")}, 1},
+		{"email", "ada@example.com", rules{format: formatp(FormatEmail)}, 0},
+		{"not email", "ada", rules{format: formatp(FormatEmail), maxLength: intp(2)}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := check(tt.value, tt.rules)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != tt.want {
+				t.Errorf("check(%v) = %q, want %d messages", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckRejectsMisuse(t *testing.T) {
+	if _, err := check(true, rules{min: intp(1)}); apperr.CodeOf(err) != apperr.CodeInternal {
+		t.Errorf("check(bool) error = %v, want INTERNAL", err)
+	}
+	if _, err := check("x", rules{pattern: strp("(")}); apperr.CodeOf(err) != apperr.CodeInternal {
+		t.Errorf("check(bad pattern) error = %v, want INTERNAL", err)
+	}
+}
+
+func TestIsEmail(t *testing.T) {
+	for s, want := range map[string]bool{
+		"ada@example.com":       true,
+		"ada.lovelace@mail.org": true,
+		"":                      false,
+		"ada":                   false,
+		"ada@localhost":         false,
+		"Ada <ada@example.com>": false,
+	} {
+		if got := IsEmail(s); got != want {
+			t.Errorf("IsEmail(%q) = %v, want %v", s, got, want)
+		}
+	}
+}
+
+// readInput reads value as input field name of the field's input argument,
+// the way generated code does.
+func readInput(ctx context.Context, name string, value interface{}, minimum int) error {
+	ctx = graphql.WithPathContext(ctx, graphql.NewPathWithField("input"))
+	ctx = graphql.WithPathContext(ctx, graphql.NewPathWithField(name))
+	_, err := Constraint(ctx, nil, func(context.Context) (interface{}, error) { return value, nil }, intp(minimum), nil, nil, nil, nil, nil)
+	return err
+}
+
+func TestCollectReportsEveryViolation(t *testing.T) {
+	var resolved bool
+	var err error
+	Collect(context.Background(), func(ctx context.Context) graphql.Marshaler {
+		ctx = graphql.WithFieldContext(ctx, &graphql.FieldContext{})
+		for _, name := range []string{"quantity", "inventory"} {
+			if err := readInput(ctx, name, 0, 1); err != nil {
+				t.Fatalf("Constraint() = %v, want violations collected", err)
+			}
+		}
+		_, err = Enforce(ctx, func(context.Context) (interface{}, error) {
+			resolved = true
+			return nil, nil
+		})
+		return graphql.Null
+	})
+
+	if resolved {
+		t.Error("resolver ran with invalid input")
+	}
+	var appErr *apperr.Error
+	if !errors.As(err, &appErr) || len(appErr.Fields) != 2 {
+		t.Fatalf("Enforce() = %v, want 2 violations", err)
+	}
+	if appErr.Fields[0].Field != "quantity" || appErr.Fields[1].Field != "inventory" {
+		t.Errorf("fields = %+v", appErr.Fields)
+	}
+}
+
+func TestConstraintWithoutCollect(t *testing.T) {
+	err := readInput(context.Background(), "quantity", 0, 1)
+	if apperr.CodeOf(err) != apperr.CodeValidation {
+		t.Errorf("Constraint() = %v, want VALIDATION", err)
+	}
+}
+
+func TestViolations(t *testing.T) {
+	var v Violations
+	if v.Err() != nil {
+		t.Error("Err() without violations is not nil")
+	}
+	v.Add("name", "is required")
+	v.Add("price", "must be greater than zero")
+	if err := v.Err(); apperr.CodeOf(err) != apperr.CodeValidation || len(err.(*apperr.Error).Fields) != 2 {
+		t.Errorf("Err() = %v", err)
+	}
+}
@@ -0,0 +1,134 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/docker/go-connections/nat"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tagaertner/e-commerce-graphql/pkg/migrate"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// productsMigrations are the products service's migrations. All services
+// share one database and migrate in whatever order they boot.
+const productsMigrations = "../../products/database/migrations"
+
+// setupTestDB starts a temporary Postgres container using testcontainers-go.
+// Requires Docker to be running.
+func setupTestDB(t *testing.T) *gorm.DB {
+	ctx := context.Background()
+	req := testcontainers.ContainerRequest{
+		Image: "postgres:15",
+		Env: map[string]string{
+			"POSTGRES_USER":     "testuser",
+			"POSTGRES_PASSWORD": "testpass",
+			"POSTGRES_DB":       "testdb",
+		},
+		ExposedPorts: []string{"5432/tcp"},
+		WaitingFor: wait.ForSQL("5432/tcp", "postgres", func(host string, port nat.Port) string {
+			return fmt.Sprintf("host=%s port=%s user=testuser password=testpass dbname=testdb sslmode=disable", host, port.Port())
+		}).WithStartupTimeout(60 * time.Second),
+	}
+
+	pgContainer, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: req,
+		Started:          true,
+	})
+	require.NoError(t, err)
+	testcontainers.CleanupContainer(t, pgContainer)
+
+	host, _ := pgContainer.Host(ctx)
+	port, _ := pgContainer.MappedPort(ctx, "5432/tcp")
+
+	dsn := fmt.Sprintf("host=%s port=%s user=testuser password=testpass dbname=testdb sslmode=disable", host, port.Port())
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+	return db
+}
+
+// seedLegacySchema recreates the tables AutoMigrate left behind before
+// versioned migrations: one order for two units each of two products.
+func seedLegacySchema(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	for _, stmt := range []string{
+		`CREATE TABLE products (id text PRIMARY KEY, name text, price numeric, description text, inventory bigint, available boolean)`,
+		`CREATE TABLE orders (id text PRIMARY KEY, user_id text, quantity bigint, total_price numeric, status text, created_at timestamptz)`,
+		`CREATE TABLE order_products (order_id text, product_id text, PRIMARY KEY (order_id, product_id))`,
+		`INSERT INTO products VALUES ('p1', 'Widget', 12.50, '', 10, true), ('p2', 'Gadget', 3.25, '', 10, true)`,
+		`INSERT INTO orders VALUES ('o1', 'u1', 2, 31.50, 'completed', now())`,
+		`INSERT INTO order_products VALUES ('o1', 'p1'), ('o1', 'p2')`,
+	} {
+		require.NoError(t, db.Exec(stmt).Error)
+	}
+}
+
+func migrateProducts(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	m, err := migrate.New(db, "products", os.DirFS(productsMigrations))
+	require.NoError(t, err)
+	_, err = m.Up(context.Background())
+	require.NoError(t, err)
+}
+
+func migrateOrders(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	m, err := Migrator(db)
+	require.NoError(t, err)
+	_, err = m.Up(context.Background())
+	require.NoError(t, err)
+}
+
+// requireLegacyLines checks the legacy order's products became line items
+// priced in cents.
+func requireLegacyLines(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	var lines []struct {
+		SKU             string
+		Quantity        int
+		UnitPriceAmount int64
+	}
+	require.NoError(t, db.Raw(`SELECT sku, quantity, unit_price_amount FROM order_line_items WHERE order_id = 'o1' ORDER BY sku`).
+		Scan(&lines).Error)
+	require.Len(t, lines, 2)
+	assert.Equal(t, "p1", lines[0].SKU)
+	assert.Equal(t, 2, lines[0].Quantity)
+	assert.Equal(t, int64(1250), lines[0].UnitPriceAmount)
+	assert.Equal(t, "p2", lines[1].SKU)
+	assert.Equal(t, int64(325), lines[1].UnitPriceAmount)
+	assert.False(t, db.Migrator().HasTable("order_products"))
+}
+
+// TestMigrations_ProductsFirst upgrades a legacy database whose products
+// service boots first, so products.price is already gone when orders moves
+// order_products into line items.
+func TestMigrations_ProductsFirst(t *testing.T) {
+	db := setupTestDB(t)
+	seedLegacySchema(t, db)
+
+	migrateProducts(t, db)
+	require.False(t, db.Migrator().HasColumn("products", "price"))
+	migrateOrders(t, db)
+
+	requireLegacyLines(t, db)
+}
+
+// TestMigrations_OrdersFirst upgrades a legacy database whose orders service
+// boots first.
+func TestMigrations_OrdersFirst(t *testing.T) {
+	db := setupTestDB(t)
+	seedLegacySchema(t, db)
+
+	migrateOrders(t, db)
+	migrateProducts(t, db)
+
+	requireLegacyLines(t, db)
+}
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS orders (
+    id          text PRIMARY KEY,
+    user_id     text,
+    total_price numeric,
+    status      text,
+    created_at  timestamptz
+);
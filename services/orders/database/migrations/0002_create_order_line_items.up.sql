@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS order_line_items (
+    id         text PRIMARY KEY,
+    order_id   text    NOT NULL,
+    product_id text    NOT NULL,
+    quantity   bigint  NOT NULL,
+    unit_price numeric NOT NULL,
+    CONSTRAINT fk_orders_line_items FOREIGN KEY (order_id) REFERENCES orders (id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_order_line_items_order_product ON order_line_items (order_id, product_id);
@@ -0,0 +1 @@
+-- Irreversible: the legacy join table and order quantity are not restored.
@@ -0,0 +1,45 @@
+-- Moves rows from the old order_products join table into order_line_items.
+-- Legacy orders applied one quantity to every product, so each line takes
+-- the order's quantity; the price comes from the stored snapshot when there
+-- is one, otherwise from the product's current price.
+DO $$
+DECLARE
+    line_quantity text := '1';
+    product_price text := 'p.price';
+    line_price    text;
+BEGIN
+    IF to_regclass('order_products') IS NULL THEN
+        RETURN;
+    END IF;
+
+    -- The products service may already store prices in minor units
+    IF EXISTS (SELECT 1 FROM information_schema.columns
+               WHERE table_name = 'products' AND column_name = 'price_amount') THEN
+        product_price := 'p.price_amount / 100.0';
+    END IF;
+    line_price := format('COALESCE(%s, 0)', product_price);
+
+    IF EXISTS (SELECT 1 FROM information_schema.columns
+               WHERE table_name = 'orders' AND column_name = 'quantity') THEN
+        line_quantity := 'GREATEST(COALESCE(o.quantity, 1), 1)';
+    END IF;
+    IF EXISTS (SELECT 1 FROM information_schema.columns
+               WHERE table_name = 'order_products' AND column_name = 'unit_price') THEN
+        line_price := format('COALESCE(NULLIF(op.unit_price, 0), %s, 0)', product_price);
+    END IF;
+
+    EXECUTE format($sql$
+        INSERT INTO order_line_items (id, order_id, product_id, quantity, unit_price)
+        SELECT op.order_id || '_' || op.product_id, op.order_id, op.product_id, %s, %s
+        FROM order_products op
+        JOIN orders o ON o.id = op.order_id
+        LEFT JOIN products p ON p.id = op.product_id
+        ON CONFLICT DO NOTHING
+    $sql$, line_quantity, line_price);
+
+    DROP TABLE order_products;
+END
+$$;
+
+-- The order-wide quantity is now derived from the line items.
+ALTER TABLE orders DROP COLUMN IF EXISTS quantity;
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS order_status_changes (
+    id          bigserial PRIMARY KEY,
+    order_id    text        NOT NULL,
+    from_status text,
+    to_status   text        NOT NULL,
+    actor       text        NOT NULL,
+    changed_at  timestamptz NOT NULL,
+    CONSTRAINT fk_orders_status_history FOREIGN KEY (order_id) REFERENCES orders (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_status_changes_order_id ON order_status_changes (order_id);
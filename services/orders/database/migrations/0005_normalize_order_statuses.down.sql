@@ -0,0 +1 @@
+-- Irreversible: the original free-form statuses are not kept.
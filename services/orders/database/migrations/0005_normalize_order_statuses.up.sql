@@ -0,0 +1,10 @@
+-- Maps free-form legacy statuses onto the OrderStatus enum. "completed"
+-- orders were delivered; anything unrecognised is left PENDING so it can
+-- still be moved through the state machine.
+UPDATE orders SET status = CASE
+    WHEN UPPER(status) IN ('PENDING', 'PAID', 'SHIPPED', 'DELIVERED', 'CANCELLED', 'REFUNDED') THEN UPPER(status)
+    WHEN LOWER(status) = 'completed' THEN 'DELIVERED'
+    WHEN LOWER(status) = 'canceled' THEN 'CANCELLED'
+    ELSE 'PENDING'
+END
+WHERE status IS NULL OR status NOT IN ('PENDING', 'PAID', 'SHIPPED', 'DELIVERED', 'CANCELLED', 'REFUNDED');
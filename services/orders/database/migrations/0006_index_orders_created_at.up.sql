@@ -0,0 +1,3 @@
+-- Keyset pagination walks orders in (created_at, id) order, overall and per user.
+CREATE INDEX IF NOT EXISTS idx_orders_created_at_id ON orders (created_at, id);
+CREATE INDEX IF NOT EXISTS idx_orders_user_id_created_at_id ON orders (user_id, created_at, id);
@@ -0,0 +1,5 @@
+-- Fails if an order holds two variants of the same product.
+DROP INDEX IF EXISTS idx_order_line_items_order_sku;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_order_line_items_order_product ON order_line_items (order_id, product_id);
+
+ALTER TABLE order_line_items DROP COLUMN IF EXISTS sku;
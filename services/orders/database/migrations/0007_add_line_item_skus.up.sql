@@ -0,0 +1,8 @@
+-- Line items now reference product variants by SKU. Every product got a
+-- variant whose SKU is its product ID, so existing lines map onto those.
+ALTER TABLE order_line_items ADD COLUMN IF NOT EXISTS sku text;
+UPDATE order_line_items SET sku = product_id WHERE sku IS NULL;
+ALTER TABLE order_line_items ALTER COLUMN sku SET NOT NULL;
+
+DROP INDEX IF EXISTS idx_order_line_items_order_product;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_order_line_items_order_sku ON order_line_items (order_id, sku);
@@ -0,0 +1,8 @@
+ALTER TABLE order_line_items ADD COLUMN IF NOT EXISTS unit_price numeric;
+UPDATE order_line_items SET unit_price = unit_price_amount / 100.0;
+ALTER TABLE order_line_items ALTER COLUMN unit_price SET NOT NULL;
+ALTER TABLE order_line_items DROP COLUMN IF EXISTS unit_price_amount, DROP COLUMN IF EXISTS unit_price_currency;
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS total_price numeric;
+UPDATE orders SET total_price = total_price_amount / 100.0;
+ALTER TABLE orders DROP COLUMN IF EXISTS total_price_amount, DROP COLUMN IF EXISTS total_price_currency;
@@ -0,0 +1,15 @@
+-- Totals and unit prices become integer minor units (cents for USD) plus an
+-- ISO 4217 currency. Existing amounts were all dollars.
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS total_price_amount bigint,
+    ADD COLUMN IF NOT EXISTS total_price_currency text NOT NULL DEFAULT 'USD' CHECK (total_price_currency ~ '^[A-Z]{3}$');
+UPDATE orders SET total_price_amount = round(COALESCE(total_price, 0) * 100) WHERE total_price_amount IS NULL;
+ALTER TABLE orders ALTER COLUMN total_price_amount SET NOT NULL;
+ALTER TABLE orders DROP COLUMN IF EXISTS total_price;
+
+ALTER TABLE order_line_items
+    ADD COLUMN IF NOT EXISTS unit_price_amount bigint,
+    ADD COLUMN IF NOT EXISTS unit_price_currency text NOT NULL DEFAULT 'USD' CHECK (unit_price_currency ~ '^[A-Z]{3}$');
+UPDATE order_line_items SET unit_price_amount = round(unit_price * 100) WHERE unit_price_amount IS NULL;
+ALTER TABLE order_line_items ALTER COLUMN unit_price_amount SET NOT NULL;
+ALTER TABLE order_line_items DROP COLUMN IF EXISTS unit_price;
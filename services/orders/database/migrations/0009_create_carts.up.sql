@@ -0,0 +1,26 @@
+-- Carts are kept until expires_at, which every change pushes back. A user
+-- has at most one cart; anonymous carts have no user_id.
+CREATE TABLE IF NOT EXISTS carts (
+    id         text PRIMARY KEY,
+    user_id    text,
+    expires_at timestamptz NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_carts_user_id ON carts (user_id) WHERE user_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_carts_expires_at ON carts (expires_at);
+
+-- added_unit_price is the price when the line was last changed, so a cart
+-- can point out prices that moved since.
+CREATE TABLE IF NOT EXISTS cart_lines (
+    cart_id                   text   NOT NULL,
+    sku                       text   NOT NULL,
+    product_id                text   NOT NULL,
+    quantity                  bigint NOT NULL CHECK (quantity > 0),
+    added_unit_price_amount   bigint NOT NULL,
+    added_unit_price_currency text   NOT NULL,
+    added_at                  timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (cart_id, sku),
+    CONSTRAINT fk_carts_lines FOREIGN KEY (cart_id) REFERENCES carts (id) ON DELETE CASCADE
+);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_orders_reservation_expires_at;
+ALTER TABLE orders DROP COLUMN IF EXISTS reservation_expires_at;
@@ -0,0 +1,8 @@
+-- PENDING orders hold their stock until reservation_expires_at, after which
+-- they are cancelled and the stock returned. Orders already pending get the
+-- full reservation window from now.
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS reservation_expires_at timestamptz;
+UPDATE orders SET reservation_expires_at = now() + interval '30 minutes'
+WHERE status = 'PENDING' AND reservation_expires_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_orders_reservation_expires_at ON orders (reservation_expires_at) WHERE status = 'PENDING';
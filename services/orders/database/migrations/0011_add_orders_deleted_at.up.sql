@@ -0,0 +1,5 @@
+-- Deleted orders are kept, with deleted_at set, until they are purged after
+-- the retention window. Purging cascades to their line items and history.
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+
+CREATE INDEX IF NOT EXISTS idx_orders_deleted_at ON orders (deleted_at);
@@ -0,0 +1,59 @@
+// Package loaders holds the request-scoped DataLoaders used to resolve
+// federated Order references and User.orders in batches.
+package loaders
+
+import (
+	"context"
+
+	"github.com/tagaertner/e-commerce-graphql/pkg/loader"
+	"github.com/tagaertner/e-commerce-graphql/pkg/pagination"
+	"github.com/tagaertner/e-commerce-graphql/services/orders/models"
+	"github.com/tagaertner/e-commerce-graphql/services/orders/services"
+)
+
+// Loaders are created per request, so cached results never outlive it.
+type Loaders struct {
+	OrderByID *loader.Loader[string, *models.Order]
+	// OrdersByUser loads one page of a user's orders per key.
+	OrdersByUser *loader.Loader[UserOrdersKey, *services.OrderPage]
+}
+
+// UserOrdersKey asks for a page of one user's orders.
+type UserOrdersKey struct {
+	UserID string
+	Args   pagination.ArgsKey
+}
+
+func New(orders *services.OrderService) *Loaders {
+	return &Loaders{
+		OrderByID:      loader.New(orders.GetOrdersByIDs, loader.NotFound),
+		OrdersByUser:   loader.New(userOrderPages(orders), nil),
+	}
+}
+
+// userOrderPages fetches the requested pages with one query per distinct
+// set of page arguments; normally every user in a batch asks for the same.
+func userOrderPages(orders *services.OrderService) loader.Fetch[UserOrdersKey, *services.OrderPage] {
+	return func(ctx context.Context, keys []UserOrdersKey) (map[UserOrdersKey]*services.OrderPage, error) {
+		userIDs := map[pagination.ArgsKey][]string{}
+		for _, k := range keys {
+			userIDs[k.Args] = append(userIDs[k.Args], k.UserID)
+		}
+
+		pages := make(map[UserOrdersKey]*services.OrderPage, len(keys))
+		for args, ids := range userIDs {
+			page, err := args.Args().Page(services.OrderSort)
+			if err != nil {
+				return nil, err
+			}
+			byUser, err := orders.GetOrderPagesByUserIDs(ctx, ids, page)
+			if err != nil {
+				return nil, err
+			}
+			for userID, p := range byUser {
+				pages[UserOrdersKey{UserID: userID, Args: args}] = p
+			}
+		}
+		return pages, nil
+	}
+}
@@ -6,10 +6,18 @@ import (
 	"os"
 	"flag"
 	"fmt"
+	"context"
+	"time"
 
 	"github.com/99designs/gqlgen/graphql/handler"
 	"github.com/99designs/gqlgen/graphql/playground"
+	dbconn "github.com/tagaertner/e-commerce-graphql/pkg/db"
+	"github.com/tagaertner/e-commerce-graphql/pkg/apperr"
+	"github.com/tagaertner/e-commerce-graphql/pkg/auth"
+	"github.com/tagaertner/e-commerce-graphql/pkg/loader"
+	"github.com/tagaertner/e-commerce-graphql/pkg/validate"
 	"github.com/tagaertner/e-commerce-graphql/services/orders/generated"
+	"github.com/tagaertner/e-commerce-graphql/services/orders/loaders"
 	"github.com/tagaertner/e-commerce-graphql/services/orders/resolvers"
 	"github.com/99designs/gqlgen/graphql/handler/transport"
 	"github.com/tagaertner/e-commerce-graphql/services/orders/database" 
@@ -39,7 +47,7 @@ func main() {
 	flag.Parse()
 
 	// Connect to the database
-	db := database.Connect()
+	db := dbconn.MustOpen(dbconn.ConfigFromEnv())
 
 	if *testDB {
 		sqlDB, err := db.DB()
@@ -54,6 +62,14 @@ func main() {
 		return // exit after test
 	}
 
+    // "migrate up|down|status" manages the schema and exits
+    if flag.Arg(0) == "migrate" {
+        if err := database.Migrate(db, flag.Args()[1:]); err != nil {
+            log.Fatalf("❌ migrate: %v", err)
+        }
+        return
+    }
+
     database.RunMigrations(db)
 
 	port := os.Getenv("PORT")
@@ -61,16 +77,34 @@ func main() {
 		port = "4003"
 	}
 
+    // Access tokens are verified offline against the users service's public keys
+    jwksURL := os.Getenv("AUTH_JWKS_URL")
+    if jwksURL == "" {
+        jwksURL = "http://users:4002/.well-known/jwks.json"
+    }
+    issuer := os.Getenv("JWT_ISSUER")
+    if issuer == "" {
+        issuer = "e-commerce-users"
+    }
+    verifier := auth.NewJWKSVerifier(jwksURL, issuer)
+
 // Creates Order services with data
     orderService := services.NewOrderService(db)
+    cartService := services.NewCartService(db, orderService)
 
     resolver := &resolvers.Resolver{
         OrderService: orderService,
+        CartService:  cartService,
     }
 
+    go purgeExpiredCarts(cartService, time.Hour)
+    go expireReservations(orderService, time.Minute)
+    go purgeDeletedOrders(orderService, durationEnv("DELETED_RETENTION", 30*24*time.Hour), time.Hour)
+
 	srv := handler.New(generated.NewExecutableSchema(
 		generated.Config{
-			Resolvers: resolver,
+			Resolvers:  resolver,
+			Directives: generated.DirectiveRoot{Auth: auth.Directive, Constraint: validate.Constraint},
 		},
         ),    )
 
@@ -79,6 +113,14 @@ func main() {
     // Enable federation introspection
     srv.Use(extension.Introspection{})
 
+    // Errors carry extensions.code, and panics become INTERNAL errors
+    srv.SetErrorPresenter(apperr.Presenter)
+    srv.SetRecoverFunc(apperr.Recover)
+
+    // @constraint violations are gathered per field and reported together
+    srv.AroundRootFields(validate.Collect)
+    srv.AroundFields(validate.Enforce)
+
 	// Supported transport methods for GraphQL requests:
 	// - POST and GET for queries/mutations
 	// - WebSocket transport enables live data features like subscriptions
@@ -87,7 +129,8 @@ func main() {
 	srv.AddTransport(transport.Websocket{})
 
 	http.Handle("/", playground.Handler("GraphQL playground", "/query"))
-	http.Handle("/query", srv)
+	newLoaders := func() *loaders.Loaders { return loaders.New(orderService) }
+	http.Handle("/query", apperr.Middleware(auth.Middleware(verifier)(loader.Middleware(newLoaders)(srv))))
 
 	// Health check
 	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
@@ -98,3 +141,60 @@ func main() {
 	log.Printf("🛍️ [orders] service ready at http://orders:%s/query", port)
 	log.Fatal(http.ListenAndServe("0.0.0.0:"+port, nil))
 }
+
+// purgeExpiredCarts deletes expired carts every interval. Expired carts are
+// already hidden from reads; this only reclaims their rows.
+func purgeExpiredCarts(carts *services.CartService, interval time.Duration) {
+    for range time.Tick(interval) {
+        n, err := carts.PurgeExpiredCarts(context.Background())
+        if err != nil {
+            log.Printf("⚠️  Failed to purge expired carts: %v", err)
+            continue
+        }
+        if n > 0 {
+            log.Printf("🧹 Purged %d expired carts", n)
+        }
+    }
+}
+
+// expireReservations cancels PENDING orders whose reservation has expired
+// every interval, so abandoned checkouts return their stock.
+func expireReservations(orders *services.OrderService, interval time.Duration) {
+    for range time.Tick(interval) {
+        n, err := orders.ExpireReservations(context.Background())
+        if err != nil {
+            log.Printf("⚠️  Failed to expire reservations: %v", err)
+        }
+        if n > 0 {
+            log.Printf("⏰ Cancelled %d orders with expired reservations", n)
+        }
+    }
+}
+
+// purgeDeletedOrders permanently removes orders deleted longer than
+// retention ago every interval. Until then they can be restored.
+func purgeDeletedOrders(orders *services.OrderService, retention, interval time.Duration) {
+    for range time.Tick(interval) {
+        n, err := orders.PurgeDeleted(context.Background(), time.Now().Add(-retention))
+        if err != nil {
+            log.Printf("⚠️  Failed to purge deleted orders: %v", err)
+            continue
+        }
+        if n > 0 {
+            log.Printf("🗑️  Purged %d deleted orders", n)
+        }
+    }
+}
+
+// durationEnv reads a duration such as "720h" from the environment.
+func durationEnv(name string, fallback time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("❌ Invalid %s %q: %v", name, value, err)
+	}
+	return d
+}
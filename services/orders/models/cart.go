@@ -0,0 +1,111 @@
+package models
+
+import "github.com/tagaertner/e-commerce-graphql/pkg/money"
+
+// Cart holds what a shopper means to buy before checkout. A cart belongs to
+// a user, or is anonymous when UserID is nil, in which case holding its ID
+// is what grants access to it.
+type Cart struct {
+	ID        string     `json:"id" gorm:"primaryKey"`
+	UserID    *string    `json:"userId"`
+	Lines     []CartLine `json:"lines" gorm:"foreignKey:CartID;constraint:OnDelete:CASCADE"`
+	ExpiresAt Time       `json:"expiresAt" gorm:"not null"`
+	CreatedAt Time       `json:"createdAt" gorm:"not null"`
+	UpdatedAt Time       `json:"updatedAt" gorm:"not null"`
+}
+
+// CartLine is one variant in a cart. AddedUnitPrice is the price when the
+// line was last added to or changed; UnitPrice, InStock and Issue are
+// filled in from current prices and stock whenever a cart is loaded.
+type CartLine struct {
+	CartID         string      `json:"-" gorm:"primaryKey"`
+	SKU            string      `json:"sku" gorm:"column:sku;primaryKey"`
+	ProductID      string      `json:"productId" gorm:"not null"`
+	Quantity       int         `json:"quantity" gorm:"not null"`
+	AddedUnitPrice money.Money `json:"addedUnitPrice" gorm:"embedded;embeddedPrefix:added_unit_price_"`
+	AddedAt        Time        `json:"addedAt" gorm:"not null"`
+
+	UnitPrice money.Money    `json:"unitPrice" gorm:"-"`
+	InStock   int            `json:"inStock" gorm:"-"`
+	Issue     *CartLineIssue `json:"issue" gorm:"-"`
+}
+
+// LineTotal returns the current unit price multiplied by the quantity.
+func (l *CartLine) LineTotal() money.Money {
+	return l.UnitPrice.Mul(int64(l.Quantity))
+}
+
+// CartLineIssue is what stops a cart line from being ordered as it is.
+type CartLineIssue string
+
+const (
+	CartLineIssueNotFound          CartLineIssue = "NOT_FOUND"
+	CartLineIssueUnavailable       CartLineIssue = "UNAVAILABLE"
+	CartLineIssueInsufficientStock CartLineIssue = "INSUFFICIENT_STOCK"
+	CartLineIssuePriceChanged      CartLineIssue = "PRICE_CHANGED"
+)
+
+// TotalQuantity returns the number of units across all lines.
+func (c *Cart) TotalQuantity() int {
+	total := 0
+	for _, l := range c.Lines {
+		total += l.Quantity
+	}
+	return total
+}
+
+// Subtotal sums the line totals at current prices. It is nil for an empty
+// cart.
+func (c *Cart) Subtotal() (*money.Money, error) {
+	if len(c.Lines) == 0 {
+		return nil, nil
+	}
+	totals := make([]money.Money, len(c.Lines))
+	for i := range c.Lines {
+		totals[i] = c.Lines[i].LineTotal()
+	}
+	subtotal, err := money.Sum(totals...)
+	if err != nil {
+		return nil, err
+	}
+	return &subtotal, nil
+}
+
+// CheckoutReady reports whether the cart has lines and all of them can be
+// ordered. A changed price does not stop checkout.
+func (c *Cart) CheckoutReady() bool {
+	if len(c.Lines) == 0 {
+		return false
+	}
+	for _, l := range c.Lines {
+		if l.Issue != nil && *l.Issue != CartLineIssuePriceChanged {
+			return false
+		}
+	}
+	return true
+}
+
+// AddCartLineInput names a variant by SKU, or by ProductID for products
+// with a single variant.
+type AddCartLineInput struct {
+	CartID    string  `json:"cartId"`
+	SKU       *string `json:"sku"`
+	ProductID *string `json:"productId"`
+	Quantity  int     `json:"quantity"`
+}
+
+type UpdateCartLineInput struct {
+	CartID   string `json:"cartId"`
+	SKU      string `json:"sku"`
+	Quantity int    `json:"quantity"`
+}
+
+type RemoveCartLineInput struct {
+	CartID string `json:"cartId"`
+	SKU    string `json:"sku"`
+}
+
+type CheckoutCartInput struct {
+	CartID        string       `json:"cartId"`
+	ExpectedTotal *money.Money `json:"expectedTotal"`
+}
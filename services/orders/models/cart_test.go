@@ -0,0 +1,54 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/tagaertner/e-commerce-graphql/pkg/money"
+)
+
+// TestCartCheckoutReady verifies that only lines that cannot be ordered
+// hold up checkout; a changed price does not.
+func TestCartCheckoutReady(t *testing.T) {
+	issue := func(i CartLineIssue) *CartLineIssue { return &i }
+
+	tests := []struct {
+		name  string
+		lines []CartLine
+		want  bool
+	}{
+		{"empty", nil, false},
+		{"no issues", []CartLine{{SKU: "a"}, {SKU: "b"}}, true},
+		{"price changed", []CartLine{{SKU: "a", Issue: issue(CartLineIssuePriceChanged)}}, true},
+		{"out of stock", []CartLine{{SKU: "a"}, {SKU: "b", Issue: issue(CartLineIssueInsufficientStock)}}, false},
+		{"gone", []CartLine{{SKU: "a", Issue: issue(CartLineIssueNotFound)}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cart := &Cart{Lines: tt.lines}
+			if got := cart.CheckoutReady(); got != tt.want {
+				t.Fatalf("CheckoutReady() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestCartSubtotal verifies the subtotal uses current unit prices.
+func TestCartSubtotal(t *testing.T) {
+	cart := &Cart{}
+	if subtotal, err := cart.Subtotal(); err != nil || subtotal != nil {
+		t.Fatalf("empty cart Subtotal() = %v, %v; want nil", subtotal, err)
+	}
+
+	cart.Lines = []CartLine{
+		{SKU: "a", Quantity: 2, AddedUnitPrice: money.New(100, "USD"), UnitPrice: money.New(150, "USD")},
+		{SKU: "b", Quantity: 1, AddedUnitPrice: money.New(999, "USD"), UnitPrice: money.New(999, "USD")},
+	}
+	subtotal, err := cart.Subtotal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *subtotal != money.New(1299, "USD") {
+		t.Fatalf("Subtotal() = %+v, want 1299 USD", *subtotal)
+	}
+}
@@ -2,8 +2,24 @@
 
 package models
 
+import (
+	"github.com/tagaertner/e-commerce-graphql/pkg/pagination"
+)
+
 type Mutation struct {
 }
 
+// A page of orders, oldest first.
+type OrderConnection struct {
+	Edges      []*OrderEdge         `json:"edges"`
+	PageInfo   *pagination.PageInfo `json:"pageInfo"`
+	TotalCount int                  `json:"totalCount"`
+}
+
+type OrderEdge struct {
+	Cursor string `json:"cursor"`
+	Node   *Order `json:"node"`
+}
+
 type Query struct {
 }
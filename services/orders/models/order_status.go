@@ -0,0 +1,30 @@
+package models
+
+// OrderStatus is where an order is in its lifecycle.
+type OrderStatus string
+
+const (
+	OrderStatusPending   OrderStatus = "PENDING"
+	OrderStatusPaid      OrderStatus = "PAID"
+	OrderStatusShipped   OrderStatus = "SHIPPED"
+	OrderStatusDelivered OrderStatus = "DELIVERED"
+	OrderStatusCancelled OrderStatus = "CANCELLED"
+	OrderStatusRefunded  OrderStatus = "REFUNDED"
+)
+
+// HoldsStock reports whether an order in this status still has stock
+// reserved that has not left the warehouse.
+func (s OrderStatus) HoldsStock() bool {
+	return s == OrderStatusPending || s == OrderStatusPaid
+}
+
+// OrderStatusChange records one status transition of an order. From is nil
+// for the entry written when the order is created.
+type OrderStatusChange struct {
+	ID        uint         `json:"-" gorm:"primaryKey"`
+	OrderID   string       `json:"orderId" gorm:"not null;index"`
+	From      *OrderStatus `json:"from" gorm:"column:from_status"`
+	To        OrderStatus  `json:"to" gorm:"column:to_status;not null"`
+	Actor     string       `json:"actor" gorm:"not null"`
+	ChangedAt Time         `json:"changedAt" gorm:"not null"`
+}
@@ -1,32 +1,62 @@
 package models
+
+import (
+	"github.com/tagaertner/e-commerce-graphql/pkg/money"
+	"gorm.io/gorm"
+)
+
 // todo change created_at to something simlar to "CreatedAt: s.CreatedAt.Format(time.RFC3339)," see job story story_mapper for example
 type Order struct {
 	 ID        string  `json:"id" gorm:"primarykey"`  
 	UserID     string  `json:"userId"`
-	Products []Product `gorm:"many2many:order_products;"`
-	Quantity   int     `json:"quantity"`
-	TotalPrice float64 `json:"totalPrice"`
-	Status     string  `json:"status"`
+	LineItems []OrderLineItem `json:"lineItems" gorm:"foreignKey:OrderID;constraint:OnDelete:CASCADE"`
+	TotalPrice money.Money `json:"totalPrice" gorm:"embedded;embeddedPrefix:total_price_"`
+	Status     OrderStatus `json:"status"`
+	StatusHistory []OrderStatusChange `json:"statusHistory" gorm:"foreignKey:OrderID;constraint:OnDelete:CASCADE"`
 	CreatedAt  Time    `json:"createdAt"`
-	
-	
+	// ReservationExpiresAt is when a PENDING order's stock is released
+	// unless it has been paid. It is nil in every other status.
+	ReservationExpiresAt *Time `json:"reservationExpiresAt"`
+	DeletedAt  gorm.DeletedAt `json:"-" gorm:"index"`
+
 }
 
 
+// OrderLineItem is one product variant on an order. UnitPrice is a snapshot
+// of the variant's price when the order was placed.
+type OrderLineItem struct {
+	ID        string  `json:"id" gorm:"primaryKey"`
+	OrderID   string  `json:"orderId" gorm:"not null;uniqueIndex:idx_order_line_items_order_sku"`
+	SKU       string  `json:"sku" gorm:"column:sku;not null;uniqueIndex:idx_order_line_items_order_sku"`
+	ProductID string  `json:"productId" gorm:"not null"`
+	Quantity  int     `json:"quantity" gorm:"not null"`
+	UnitPrice money.Money `json:"unitPrice" gorm:"embedded;embeddedPrefix:unit_price_"`
+}
+
+// LineTotal returns the unit price multiplied by the quantity.
+func (l *OrderLineItem) LineTotal() money.Money {
+	return l.UnitPrice.Mul(int64(l.Quantity))
+}
+
+// OrderLineItemInput names a variant by SKU, or by ProductID for products
+// with a single variant.
+type OrderLineItemInput struct {
+	SKU       *string `json:"sku"`
+	ProductID *string `json:"productId"`
+	Quantity  int     `json:"quantity"`
+}
+
 type CreateOrderInput struct {
 	UserID     string  `json:"userId"`
-	ProductIDs  []string  `json:"productIds"`
-	Quantity   int     `json:"quantity"`
-	TotalPrice float64 `json:"totalPrice"`
-	Status     string  `json:"status"`
+	LineItems  []*OrderLineItemInput `json:"lineItems"`
+	TotalPrice *money.Money `json:"totalPrice"`
+	Status     OrderStatus `json:"status"`
 	CreatedAt  Time    `json:"createdAt" gorm:"autoCreateTime"`
 }
 
 type UpdateOrderInput struct {
 	OrderID     string   `json:"orderId"`
-	Quantity    *int     `json:"quantity"`
-	TotalPrice  *float64 `json:"totalPrice"`
-	Status      *string  `json:"status"`
+	Status      *OrderStatus `json:"status"`
 }
 
 type DeleteOrderInput struct {
@@ -36,14 +66,34 @@ type DeleteOrderInput struct {
 
 type SetOrderStatusInput struct {
 	OrderID     string   `json:"orderId"`
-	Status      *string  `json:"status"`
+	Status      OrderStatus `json:"status"`
 }
 
 type ChangeOrderQuantityInput struct {
 	OrderID     string   `json:"orderId"`
+	SKU         *string  `json:"sku"`
+	ProductID   *string  `json:"productId"`
 	Quantity   int     `json:"quantity"`
 }
 
+// TotalQuantity returns the number of units across all line items.
+func (o *Order) TotalQuantity() int {
+	total := 0
+	for _, l := range o.LineItems {
+		total += l.Quantity
+	}
+	return total
+}
+
+// LineQuantities returns the quantity ordered per SKU.
+func (o *Order) LineQuantities() map[string]int {
+	quantities := make(map[string]int, len(o.LineItems))
+	for _, l := range o.LineItems {
+		quantities[l.SKU] += l.Quantity
+	}
+	return quantities
+}
+
 func (Order) IsEntity() {}
 
 
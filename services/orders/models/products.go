@@ -1,12 +1,17 @@
 package models
 
+// Product is a reference to a product owned by the products subgraph. Only
+// the ID is known here; the gateway fetches every other field from products.
 type Product struct {
-    ID          string  `json:"id" gorm:"primaryKey"`
-    Name        string  `json:"name"`
-    Price       float64 `json:"price"`
-    Description string  `json:"description"`
-    Inventory   int     `json:"inventory"`
-    Available   bool    `json:"available"`
+    ID string `json:"id"`
 }
 
-func (Product) IsEntity() {}
\ No newline at end of file
+func (Product) IsEntity() {}
+
+// ProductVariant is a reference to a variant owned by the products
+// subgraph, keyed by SKU.
+type ProductVariant struct {
+    SKU string `json:"sku"`
+}
+
+func (ProductVariant) IsEntity() {}
@@ -0,0 +1,21 @@
+package resolvers
+
+import (
+	"context"
+
+	"github.com/tagaertner/e-commerce-graphql/pkg/auth"
+	"github.com/tagaertner/e-commerce-graphql/services/orders/models"
+)
+
+func ToGraphQLCart(c *models.Cart) *models.Cart {
+	return c
+}
+
+// authorizeCart lets anyone holding an anonymous cart's ID use it, and
+// limits user carts to their owner and admins.
+func authorizeCart(ctx context.Context, cart *models.Cart) error {
+	if cart.UserID == nil {
+		return nil
+	}
+	return auth.RequireSelfOrAdmin(ctx, *cart.UserID)
+}
@@ -9,22 +9,35 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/tagaertner/e-commerce-graphql/pkg/auth"
+	"github.com/tagaertner/e-commerce-graphql/pkg/loader"
 	"github.com/tagaertner/e-commerce-graphql/services/orders/generated"
+	"github.com/tagaertner/e-commerce-graphql/services/orders/loaders"
 	"github.com/tagaertner/e-commerce-graphql/services/orders/models"
 )
 
 // FindOrderByID is the resolver for the findOrderByID field.
 func (r *entityResolver) FindOrderByID(ctx context.Context, id string) (*models.Order, error) {
-	order, err := r.OrderService.GetOrderByID(id)
+	order, err := loader.For[*loaders.Loaders](ctx).OrderByID.Load(ctx, id)
 	if err != nil {
 		return nil, err
 	}
+	if err := auth.RequireSelfOrAdmin(ctx, order.UserID); err != nil {
+		return nil, err
+	}
 	return ToGraphQLOrder(order), nil
 }
 
 // FindProductByID is the resolver for the findProductByID field.
 func (r *entityResolver) FindProductByID(ctx context.Context, id string) (*models.Product, error) {
-	panic(fmt.Errorf("not implemented: FindProductByID - findProductByID"))
+	// Only the key lives here; the products subgraph resolves the rest
+	return ToGraphQLProduct(&models.Product{ID: id}), nil
+}
+
+// FindProductVariantBySku is the resolver for the findProductVariantBySku field.
+func (r *entityResolver) FindProductVariantBySku(ctx context.Context, sku string) (*models.ProductVariant, error) {
+	// Only the key lives here; the products subgraph resolves the rest
+	return ToGraphQLProductVariant(&models.ProductVariant{SKU: sku}), nil
 }
 
 // FindUserByID is the resolver for the findUserByID field.
@@ -2,6 +2,7 @@ package resolvers
 
 import (
     "github.com/tagaertner/e-commerce-graphql/services/orders/models"
+    "github.com/tagaertner/e-commerce-graphql/services/orders/services"
 )
 
 func ToGraphQLOrder(o *models.Order) *models.Order {
@@ -12,10 +13,23 @@ func ToGraphQLOrders(orders []*models.Order) []*models.Order {
     return orders
 }
 
+func ToGraphQLOrderConnection(page *services.OrderPage) *models.OrderConnection {
+    edges := make([]*models.OrderEdge, len(page.Orders))
+    for i, o := range page.Orders {
+        edges[i] = &models.OrderEdge{Cursor: page.Cursors[i], Node: ToGraphQLOrder(o)}
+    }
+    pageInfo := page.PageInfo
+    return &models.OrderConnection{Edges: edges, PageInfo: &pageInfo, TotalCount: page.TotalCount}
+}
+
 func ToGraphQLUser(u *models.User) *models.User {
     return u
 }
 
 func ToGraphQLProduct(p *models.Product) *models.Product {
     return p
+}
+
+func ToGraphQLProductVariant(v *models.ProductVariant) *models.ProductVariant {
+    return v
 }
\ No newline at end of file
@@ -1,16 +1,30 @@
 package resolvers
 
 import (
+	"context"
+
 	"github.com/tagaertner/e-commerce-graphql/services/orders/services"
 	"gorm.io/gorm"
 )
 
 type Resolver struct {
 	OrderService *services.OrderService
+	CartService  *services.CartService
 }
 
 func NewResolver(db *gorm.DB) *Resolver {
+	orders := services.NewOrderService(db)
 	return &Resolver{
-		OrderService: services.NewOrderService(db),
+		OrderService: orders,
+		CartService:  services.NewCartService(db, orders),
+	}
+}
+
+// authorizeCartID loads a cart and checks the caller may use it.
+func (r *Resolver) authorizeCartID(ctx context.Context, id string) error {
+	cart, err := r.CartService.GetCart(ctx, id)
+	if err != nil {
+		return err
 	}
+	return authorizeCart(ctx, cart)
 }
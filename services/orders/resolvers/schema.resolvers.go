@@ -10,19 +10,41 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/tagaertner/e-commerce-graphql/pkg/auth"
+	"github.com/tagaertner/e-commerce-graphql/pkg/loader"
+	"github.com/tagaertner/e-commerce-graphql/pkg/pagination"
 	"github.com/tagaertner/e-commerce-graphql/services/orders/generated"
+	"github.com/tagaertner/e-commerce-graphql/services/orders/loaders"
 	"github.com/tagaertner/e-commerce-graphql/services/orders/models"
+	"github.com/tagaertner/e-commerce-graphql/services/orders/services"
 )
 
+// Quantity is the resolver for the quantity field.
+func (r *cartResolver) Quantity(ctx context.Context, obj *models.Cart) (int, error) {
+	return obj.TotalQuantity(), nil
+}
+
+// Variant is the resolver for the variant field.
+func (r *cartLineResolver) Variant(ctx context.Context, obj *models.CartLine) (*models.ProductVariant, error) {
+	return ToGraphQLProductVariant(&models.ProductVariant{SKU: obj.SKU}), nil
+}
+
+// Product is the resolver for the product field.
+func (r *cartLineResolver) Product(ctx context.Context, obj *models.CartLine) (*models.Product, error) {
+	return ToGraphQLProduct(&models.Product{ID: obj.ProductID}), nil
+}
+
 // CreateOrder is the resolver for the createOrder field.
 func (r *mutationResolver) CreateOrder(ctx context.Context, input models.CreateOrderInput) (*models.Order, error) {
+	if err := auth.RequireSelfOrAdmin(ctx, input.UserID); err != nil {
+		return nil, err
+	}
 	createdAt := time.Time(input.CreatedAt)
 
 	order, err := r.OrderService.CreateOrder(
 		ctx,
 		input.UserID,
-		input.ProductIDs,
-		input.Quantity,
+		input.LineItems,
 		input.TotalPrice,
 		input.Status,
 		createdAt,
@@ -49,6 +71,15 @@ func (r *mutationResolver) DeleteOrder(ctx context.Context, input models.DeleteO
 	return r.OrderService.DeleteOrder(ctx, input)
 }
 
+// RestoreOrder is the resolver for the restoreOrder field.
+func (r *mutationResolver) RestoreOrder(ctx context.Context, id string) (*models.Order, error) {
+	order, err := r.OrderService.RestoreOrder(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return ToGraphQLOrder(order), nil
+}
+
 // SetOrderStatus is the resolver for the setOrderStatus field.
 func (r *mutationResolver) SetOrderStatus(ctx context.Context, input models.SetOrderStatusInput) (*models.Order, error) {
 	order, err := r.OrderService.SetOrderStatus(ctx, input)
@@ -60,7 +91,96 @@ func (r *mutationResolver) SetOrderStatus(ctx context.Context, input models.SetO
 
 // ChangeOrderQuantity is the resolver for the changeOrderQuantity field.
 func (r *mutationResolver) ChangeOrderQuantity(ctx context.Context, input models.ChangeOrderQuantityInput) (*models.Order, error) {
-	panic(fmt.Errorf("not implemented: ChangeOrderQuantity"))
+	existing, err := r.OrderService.GetOrderByID(ctx, input.OrderID, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := auth.RequireSelfOrAdmin(ctx, existing.UserID); err != nil {
+		return nil, err
+	}
+
+	order, err := r.OrderService.ChangeOrderQuantity(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	return ToGraphQLOrder(order), nil
+}
+
+// CreateCart is the resolver for the createCart field.
+func (r *mutationResolver) CreateCart(ctx context.Context) (*models.Cart, error) {
+	var userID string
+	if caller, ok := auth.CallerFrom(ctx); ok {
+		userID = caller.UserID
+	}
+
+	cart, err := r.CartService.CreateCart(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return ToGraphQLCart(cart), nil
+}
+
+// AddCartLine is the resolver for the addCartLine field.
+func (r *mutationResolver) AddCartLine(ctx context.Context, input models.AddCartLineInput) (*models.Cart, error) {
+	if err := r.authorizeCartID(ctx, input.CartID); err != nil {
+		return nil, err
+	}
+
+	cart, err := r.CartService.AddLine(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	return ToGraphQLCart(cart), nil
+}
+
+// UpdateCartLine is the resolver for the updateCartLine field.
+func (r *mutationResolver) UpdateCartLine(ctx context.Context, input models.UpdateCartLineInput) (*models.Cart, error) {
+	if err := r.authorizeCartID(ctx, input.CartID); err != nil {
+		return nil, err
+	}
+
+	cart, err := r.CartService.UpdateLine(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	return ToGraphQLCart(cart), nil
+}
+
+// RemoveCartLine is the resolver for the removeCartLine field.
+func (r *mutationResolver) RemoveCartLine(ctx context.Context, input models.RemoveCartLineInput) (*models.Cart, error) {
+	if err := r.authorizeCartID(ctx, input.CartID); err != nil {
+		return nil, err
+	}
+
+	cart, err := r.CartService.RemoveLine(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	return ToGraphQLCart(cart), nil
+}
+
+// CheckoutCart is the resolver for the checkoutCart field.
+func (r *mutationResolver) CheckoutCart(ctx context.Context, input models.CheckoutCartInput) (*models.Order, error) {
+	cart, err := r.CartService.GetCart(ctx, input.CartID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeCart(ctx, cart); err != nil {
+		return nil, err
+	}
+
+	// Anonymous carts are ordered for whoever checks them out
+	caller, _ := auth.CallerFrom(ctx)
+	userID := caller.UserID
+	if cart.UserID != nil {
+		userID = *cart.UserID
+	}
+
+	order, err := r.CartService.Checkout(ctx, cart.ID, userID, input.ExpectedTotal)
+	if err != nil {
+		return nil, err
+	}
+	return ToGraphQLOrder(order), nil
 }
 
 // User is the resolver for the user field on Order.
@@ -71,66 +191,163 @@ func (r *orderResolver) User(ctx context.Context, obj *models.Order) (*models.Us
 	}, nil
 }
 
+// Products is the resolver for the products field.
+func (r *orderResolver) Products(ctx context.Context, obj *models.Order) ([]*models.Product, error) {
+	// Federated references: the Products service resolves the remaining fields
+	products := make([]*models.Product, 0, len(obj.LineItems))
+	for _, l := range obj.LineItems {
+		products = append(products, &models.Product{ID: l.ProductID})
+	}
+	return products, nil
+}
+
+// Quantity is the resolver for the quantity field.
+func (r *orderResolver) Quantity(ctx context.Context, obj *models.Order) (int, error) {
+	return obj.TotalQuantity(), nil
+}
+
+// DeletedAt is the resolver for the deletedAt field.
+func (r *orderResolver) DeletedAt(ctx context.Context, obj *models.Order) (*models.Time, error) {
+	if !obj.DeletedAt.Valid {
+		return nil, nil
+	}
+	t := models.Time(obj.DeletedAt.Time)
+	return &t, nil
+}
+
+// Variant is the resolver for the variant field.
+func (r *orderLineItemResolver) Variant(ctx context.Context, obj *models.OrderLineItem) (*models.ProductVariant, error) {
+	return ToGraphQLProductVariant(&models.ProductVariant{SKU: obj.SKU}), nil
+}
+
+// Product is the resolver for the product field.
+func (r *orderLineItemResolver) Product(ctx context.Context, obj *models.OrderLineItem) (*models.Product, error) {
+	return &models.Product{ID: obj.ProductID}, nil
+}
+
 // Orders is the resolver for the orders field.
-func (r *queryResolver) Orders(ctx context.Context) ([]*models.Order, error) {
-	orders, err := r.OrderService.GetAllOrders()
+func (r *queryResolver) Orders(ctx context.Context, first *int, after *string, last *int, before *string, includeDeleted *bool) (*models.OrderConnection, error) {
+	page, err := pagination.Args{First: first, After: after, Last: last, Before: before}.Page(services.OrderSort)
+	if err != nil {
+		return nil, err
+	}
+
+	orders, err := r.OrderService.GetOrdersPage(ctx, page, includeDeleted != nil && *includeDeleted)
 	if err != nil {
 		return nil, err
 	}
 
-	return ToGraphQLOrders(orders), nil
+	return ToGraphQLOrderConnection(orders), nil
 }
 
 // Order is the resolver for the order field.
-func (r *queryResolver) Order(ctx context.Context, id string) (*models.Order, error) {
-	order, err := r.OrderService.GetOrderByID(id)
+func (r *queryResolver) Order(ctx context.Context, id string, includeDeleted *bool) (*models.Order, error) {
+	withDeleted, err := auth.AdminFlag(ctx, includeDeleted)
 	if err != nil {
 		return nil, err
 	}
+	order, err := r.OrderService.GetOrderByID(ctx, id, withDeleted)
+	if err != nil {
+		return nil, err
+	}
+	if err := auth.RequireSelfOrAdmin(ctx, order.UserID); err != nil {
+		return nil, err
+	}
 
 	return ToGraphQLOrder(order), nil
 }
 
 // OrdersByUser is the resolver for the ordersByUser field.
-func (r *queryResolver) OrdersByUser(ctx context.Context, userID string) ([]*models.Order, error) {
-	orders, err := r.OrderService.GetOrdersByUserID(userID)
+func (r *queryResolver) OrdersByUser(ctx context.Context, userID string, first *int, after *string, last *int, before *string, includeDeleted *bool) (*models.OrderConnection, error) {
+	if err := auth.RequireSelfOrAdmin(ctx, userID); err != nil {
+		return nil, err
+	}
+	withDeleted, err := auth.AdminFlag(ctx, includeDeleted)
 	if err != nil {
 		return nil, err
 	}
+	page, err := pagination.Args{First: first, After: after, Last: last, Before: before}.Page(services.OrderSort)
+	if err != nil {
+		return nil, err
+	}
+
+	orders, err := r.OrderService.GetOrdersByUserIDPage(ctx, userID, page, withDeleted)
+	if err != nil {
+		return nil, err
+	}
+
+	return ToGraphQLOrderConnection(orders), nil
+}
+
+// Cart is the resolver for the cart field.
+func (r *queryResolver) Cart(ctx context.Context, id string) (*models.Cart, error) {
+	cart, err := r.CartService.GetCart(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeCart(ctx, cart); err != nil {
+		return nil, err
+	}
+	return ToGraphQLCart(cart), nil
+}
 
-	return ToGraphQLOrders(orders), nil
+// MyCart is the resolver for the myCart field.
+func (r *queryResolver) MyCart(ctx context.Context) (*models.Cart, error) {
+	caller, _ := auth.CallerFrom(ctx)
+	cart, err := r.CartService.GetCartByUserID(ctx, caller.UserID)
+	if err != nil || cart == nil {
+		return nil, err
+	}
+	return ToGraphQLCart(cart), nil
 }
 
 // Orders resolves the orders field on User.
-func (r *userResolver) Orders(ctx context.Context, obj *models.User) ([]*models.Order, error) {
+func (r *userResolver) Orders(ctx context.Context, obj *models.User, first *int, after *string, last *int, before *string) (*models.OrderConnection, error) {
 	fmt.Printf("🚨 USERRESOLVER CALLED FOR USER ID: %s 🚨\n", obj.ID)
 
-	modelOrders, err := r.OrderService.GetOrdersByUserID(obj.ID)
-	if err != nil {
-		fmt.Printf("❌ Error getting orders for user %s: %v\n", obj.ID, err)
-		return []*models.Order{}, nil
+	if err := auth.RequireSelfOrAdmin(ctx, obj.ID); err != nil {
+		return nil, err
 	}
 
-	if modelOrders == nil {
-		return []*models.Order{}, nil
+	args := pagination.Args{First: first, After: after, Last: last, Before: before}
+	if _, err := args.Page(services.OrderSort); err != nil {
+		return nil, err
 	}
 
-	return ToGraphQLOrders(modelOrders), nil
+	orders, err := loader.For[*loaders.Loaders](ctx).OrdersByUser.Load(ctx, loaders.UserOrdersKey{UserID: obj.ID, Args: args.Key()})
+	if err != nil {
+		fmt.Printf("❌ Error getting orders for user %s: %v\n", obj.ID, err)
+		return nil, err
+	}
+
+	return ToGraphQLOrderConnection(orders), nil
 }
 
+// Cart returns generated.CartResolver implementation.
+func (r *Resolver) Cart() generated.CartResolver { return &cartResolver{r} }
+
+// CartLine returns generated.CartLineResolver implementation.
+func (r *Resolver) CartLine() generated.CartLineResolver { return &cartLineResolver{r} }
+
 // Mutation returns generated.MutationResolver implementation.
 func (r *Resolver) Mutation() generated.MutationResolver { return &mutationResolver{r} }
 
 // Order returns generated.OrderResolver implementation.
 func (r *Resolver) Order() generated.OrderResolver { return &orderResolver{r} }
 
+// OrderLineItem returns generated.OrderLineItemResolver implementation.
+func (r *Resolver) OrderLineItem() generated.OrderLineItemResolver { return &orderLineItemResolver{r} }
+
 // Query returns generated.QueryResolver implementation.
 func (r *Resolver) Query() generated.QueryResolver { return &queryResolver{r} }
 
 // User returns generated.UserResolver implementation.
 func (r *Resolver) User() generated.UserResolver { return &userResolver{r} }
 
+type cartResolver struct{ *Resolver }
+type cartLineResolver struct{ *Resolver }
 type mutationResolver struct{ *Resolver }
 type orderResolver struct{ *Resolver }
+type orderLineItemResolver struct{ *Resolver }
 type queryResolver struct{ *Resolver }
 type userResolver struct{ *Resolver }
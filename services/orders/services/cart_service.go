@@ -0,0 +1,346 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tagaertner/e-commerce-graphql/pkg/apperr"
+	"github.com/tagaertner/e-commerce-graphql/pkg/money"
+	"github.com/tagaertner/e-commerce-graphql/pkg/validate"
+	"github.com/tagaertner/e-commerce-graphql/services/orders/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CartTTL is how long a cart is kept after it was last changed.
+const CartTTL = 7 * 24 * time.Hour
+
+var (
+	// ErrCartNotFound is returned for unknown and expired carts.
+	ErrCartNotFound error = apperr.New(apperr.CodeNotFound, "cart not found")
+	// ErrCartEmpty is returned when checking out a cart without lines.
+	ErrCartEmpty error = apperr.New(apperr.CodeValidation, "cart is empty")
+)
+
+type CartService struct {
+	db     *gorm.DB
+	orders *OrderService
+}
+
+func NewCartService(db *gorm.DB, orders *OrderService) *CartService {
+	return &CartService{db: db, orders: orders}
+}
+
+// GetCart loads a cart that has not expired and checks each line against
+// current prices and stock.
+func (s *CartService) GetCart(ctx context.Context, id string) (*models.Cart, error) {
+	db := s.db.WithContext(ctx)
+	var cart models.Cart
+	if err := preloadCart(db).First(&cart, "id = ? AND expires_at > ?", id, time.Now()).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCartNotFound
+		}
+		return nil, err
+	}
+	if err := revalidateCart(db, &cart); err != nil {
+		return nil, err
+	}
+	return &cart, nil
+}
+
+// GetCartByUserID returns a user's cart, or nil when they have none.
+func (s *CartService) GetCartByUserID(ctx context.Context, userID string) (*models.Cart, error) {
+	var ids []string
+	if err := s.db.WithContext(ctx).Model(&models.Cart{}).
+		Where("user_id = ? AND expires_at > ?", userID, time.Now()).
+		Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	return s.GetCart(ctx, ids[0])
+}
+
+// CreateCart returns the user's cart, creating it if they have none. An
+// empty userID creates a new anonymous cart.
+func (s *CartService) CreateCart(ctx context.Context, userID string) (*models.Cart, error) {
+	now := time.Now().UTC()
+	cart := &models.Cart{
+		ID:        "cart_" + uuid.NewString(),
+		ExpiresAt: models.Time(now.Add(CartTTL)),
+		CreatedAt: models.Time(now),
+		UpdatedAt: models.Time(now),
+	}
+	if userID == "" {
+		if err := s.db.WithContext(ctx).Create(cart).Error; err != nil {
+			return nil, err
+		}
+		return cart, nil
+	}
+
+	cart.UserID = &userID
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// An expired cart makes way for a new one
+		if err := tx.Where("user_id = ? AND expires_at <= ?", userID, now).Delete(&models.Cart{}).Error; err != nil {
+			return err
+		}
+		// Concurrent calls both end up with the one cart the user may have
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(cart).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetCartByUserID(ctx, userID)
+}
+
+// AddLine adds units of a variant to a cart, merging them into the
+// variant's line if it has one. The variant must be on sale with enough
+// stock, and priced in the same currency as the rest of the cart.
+func (s *CartService) AddLine(ctx context.Context, input models.AddCartLineInput) (*models.Cart, error) {
+	item := &models.OrderLineItemInput{
+		SKU:       input.SKU,
+		ProductID: input.ProductID,
+		Quantity:  input.Quantity,
+	}
+	var violations validate.Violations
+	checkLineItem(&violations, "", item)
+	if err := violations.Err(); err != nil {
+		return nil, err
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		cart, err := lockCart(tx, input.CartID)
+		if err != nil {
+			return err
+		}
+
+		skus, _, err := mergeLineItems(tx, []*models.OrderLineItemInput{item})
+		if err != nil {
+			return err
+		}
+		sku := skus[0]
+
+		quantity := input.Quantity
+		if line := findCartLine(cart, sku); line != nil {
+			quantity += line.Quantity
+		}
+		return setLine(tx, cart, sku, quantity)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetCart(ctx, input.CartID)
+}
+
+// UpdateLine sets the quantity of a line already in the cart, removing it
+// when the quantity is zero.
+func (s *CartService) UpdateLine(ctx context.Context, input models.UpdateCartLineInput) (*models.Cart, error) {
+	if input.Quantity < 0 {
+		return nil, apperr.Field("quantity", "cannot be negative")
+	}
+	if input.Quantity == 0 {
+		return s.RemoveLine(ctx, models.RemoveCartLineInput{CartID: input.CartID, SKU: input.SKU})
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		cart, err := lockCart(tx, input.CartID)
+		if err != nil {
+			return err
+		}
+		if findCartLine(cart, input.SKU) == nil {
+			return apperr.NotFound("variant %s is not in the cart", input.SKU)
+		}
+		return setLine(tx, cart, input.SKU, input.Quantity)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetCart(ctx, input.CartID)
+}
+
+// RemoveLine takes a variant out of the cart.
+func (s *CartService) RemoveLine(ctx context.Context, input models.RemoveCartLineInput) (*models.Cart, error) {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		cart, err := lockCart(tx, input.CartID)
+		if err != nil {
+			return err
+		}
+		result := tx.Where("cart_id = ? AND sku = ?", cart.ID, input.SKU).Delete(&models.CartLine{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return apperr.NotFound("variant %s is not in the cart", input.SKU)
+		}
+		return touchCart(tx, cart)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetCart(ctx, input.CartID)
+}
+
+// Checkout places an order for userID with the cart's lines at current
+// prices and deletes the cart. Either both happen or neither does, so a
+// failed checkout, e.g. for lack of stock, leaves the cart as it was.
+func (s *CartService) Checkout(ctx context.Context, cartID, userID string, expectedTotal *money.Money) (*models.Order, error) {
+	var order *models.Order
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		cart, err := lockCart(tx, cartID)
+		if err != nil {
+			return err
+		}
+		if len(cart.Lines) == 0 {
+			return ErrCartEmpty
+		}
+
+		lineItems := make([]*models.OrderLineItemInput, len(cart.Lines))
+		for i, l := range cart.Lines {
+			sku := l.SKU
+			lineItems[i] = &models.OrderLineItemInput{SKU: &sku, Quantity: l.Quantity}
+		}
+		order, err = s.orders.placeOrder(ctx, tx, userID, lineItems, expectedTotal, models.OrderStatusPending, time.Now().UTC())
+		if err != nil {
+			return err
+		}
+		return tx.Delete(cart).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// PurgeExpiredCarts deletes every expired cart and returns how many there
+// were.
+func (s *CartService) PurgeExpiredCarts(ctx context.Context) (int64, error) {
+	result := s.db.WithContext(ctx).Where("expires_at <= ?", time.Now()).Delete(&models.Cart{})
+	return result.RowsAffected, result.Error
+}
+
+// lockCart locks a cart that has not expired and loads its lines.
+func lockCart(tx *gorm.DB, id string) (*models.Cart, error) {
+	var cart models.Cart
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&cart, "id = ? AND expires_at > ?", id, time.Now()).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCartNotFound
+		}
+		return nil, err
+	}
+	if err := tx.Where("cart_id = ?", cart.ID).Order("added_at, sku").Find(&cart.Lines).Error; err != nil {
+		return nil, err
+	}
+	return &cart, nil
+}
+
+// setLine writes a line for quantity units of sku at the current price,
+// after checking stock and that the cart stays in one currency.
+func setLine(tx *gorm.DB, cart *models.Cart, sku string, quantity int) error {
+	states, err := variantStates(tx, []string{sku})
+	if err != nil {
+		return err
+	}
+	state, ok := states[sku]
+	if !ok {
+		return apperr.NotFound("variant %s not found", sku)
+	}
+	if !state.Available {
+		return fmt.Errorf("%w: variant %s", ErrProductUnavailable, sku)
+	}
+	if state.Inventory < quantity {
+		return fmt.Errorf("%w: variant %s has %d left, requested %d", ErrInsufficientStock, sku, state.Inventory, quantity)
+	}
+	for _, l := range cart.Lines {
+		if l.SKU != sku && l.AddedUnitPrice.Currency != state.Price.Currency {
+			return fmt.Errorf("%w: cart is in %s, variant %s is priced in %s", ErrMixedCurrencies, l.AddedUnitPrice.Currency, sku, state.Price.Currency)
+		}
+	}
+
+	line := models.CartLine{
+		CartID:         cart.ID,
+		SKU:            sku,
+		ProductID:      state.ProductID,
+		Quantity:       quantity,
+		AddedUnitPrice: state.Price,
+		AddedAt:        models.Now(),
+	}
+	if err := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "cart_id"}, {Name: "sku"}},
+		DoUpdates: clause.AssignmentColumns([]string{"quantity", "added_unit_price_amount", "added_unit_price_currency"}),
+	}).Create(&line).Error; err != nil {
+		return err
+	}
+	return touchCart(tx, cart)
+}
+
+// touchCart records a change to a cart and pushes back its expiry.
+func touchCart(tx *gorm.DB, cart *models.Cart) error {
+	now := time.Now().UTC()
+	return tx.Model(cart).Updates(map[string]interface{}{
+		"updated_at": now,
+		"expires_at": now.Add(CartTTL),
+	}).Error
+}
+
+// revalidateCart fills in each line's current price and stock and flags
+// what would stop it from being ordered as it is.
+func revalidateCart(db *gorm.DB, cart *models.Cart) error {
+	if len(cart.Lines) == 0 {
+		return nil
+	}
+	skus := make([]string, len(cart.Lines))
+	for i, l := range cart.Lines {
+		skus[i] = l.SKU
+	}
+	states, err := variantStates(db, skus)
+	if err != nil {
+		return err
+	}
+
+	for i := range cart.Lines {
+		line := &cart.Lines[i]
+		state, ok := states[line.SKU]
+		if !ok {
+			line.UnitPrice = line.AddedUnitPrice
+			line.Issue = cartLineIssue(models.CartLineIssueNotFound)
+			continue
+		}
+
+		line.UnitPrice = state.Price
+		line.InStock = state.Inventory
+		switch {
+		case !state.Available:
+			line.Issue = cartLineIssue(models.CartLineIssueUnavailable)
+		case state.Inventory < line.Quantity:
+			line.Issue = cartLineIssue(models.CartLineIssueInsufficientStock)
+		case state.Price != line.AddedUnitPrice:
+			line.Issue = cartLineIssue(models.CartLineIssuePriceChanged)
+		}
+	}
+	return nil
+}
+
+func cartLineIssue(issue models.CartLineIssue) *models.CartLineIssue {
+	return &issue
+}
+
+func findCartLine(cart *models.Cart, sku string) *models.CartLine {
+	for i := range cart.Lines {
+		if cart.Lines[i].SKU == sku {
+			return &cart.Lines[i]
+		}
+	}
+	return nil
+}
+
+// preloadCart loads a cart's lines, oldest first.
+func preloadCart(db *gorm.DB) *gorm.DB {
+	return db.Preload("Lines", func(db *gorm.DB) *gorm.DB {
+		return db.Order("added_at, sku")
+	})
+}
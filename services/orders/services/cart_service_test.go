@@ -0,0 +1,178 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tagaertner/e-commerce-graphql/pkg/apperr"
+	"github.com/tagaertner/e-commerce-graphql/pkg/inventory"
+	"github.com/tagaertner/e-commerce-graphql/pkg/money"
+	"github.com/tagaertner/e-commerce-graphql/services/orders/models"
+	"gorm.io/gorm"
+)
+
+// setupCartEnv is setupTestEnv with a CartService on the same database.
+func setupCartEnv(t *testing.T) (*gorm.DB, *CartService, context.Context) {
+	db, orderService, ctx := setupTestEnv(t)
+	return db, NewCartService(db, orderService), ctx
+}
+
+// fillCart creates a cart for user holding the given lines.
+func fillCart(t *testing.T, cartService *CartService, userID string, lines ...*models.OrderLineItemInput) *models.Cart {
+	t.Helper()
+	ctx := context.Background()
+	cart, err := cartService.CreateCart(ctx, userID)
+	require.NoError(t, err)
+	for _, l := range lines {
+		cart, err = cartService.AddLine(ctx, models.AddCartLineInput{CartID: cart.ID, SKU: l.SKU, Quantity: l.Quantity})
+		require.NoError(t, err)
+	}
+	return cart
+}
+
+// TestCheckout_Success The order is placed at current prices, its stock is
+// reserved and the cart is gone, all at once.
+func TestCheckout_Success(t *testing.T) {
+	db, cartService, ctx := setupCartEnv(t)
+
+	// --- Arrange ---
+	seedVariant(t, db, "p1", 1000, 10)
+	seedVariant(t, db, "p2", 250, 10)
+	cart := fillCart(t, cartService, "1", line("p1", 2), line("p2", 4))
+
+	// --- Act ---
+	order, err := cartService.Checkout(ctx, cart.ID, "1", nil)
+
+	// --- Assert ---
+	require.NoError(t, err)
+	assert.Equal(t, models.OrderStatusPending, order.Status)
+	assert.Equal(t, money.New(3000, "USD"), order.TotalPrice)
+	assert.Len(t, order.LineItems, 2)
+	assert.Equal(t, 8, stockOf(t, db, "p1").Inventory)
+	assert.Equal(t, 6, stockOf(t, db, "p2").Inventory)
+
+	_, err = cartService.GetCart(ctx, cart.ID)
+	assert.ErrorIs(t, err, ErrCartNotFound)
+}
+
+// TestCheckout_OutOfStockRollsBack A line that has run out since it was
+// added fails the checkout, leaving the cart, the other lines' stock and the
+// orders table as they were.
+func TestCheckout_OutOfStockRollsBack(t *testing.T) {
+	db, cartService, ctx := setupCartEnv(t)
+
+	// --- Arrange --- p2 is sold elsewhere after it is added to the cart
+	seedVariant(t, db, "p1", 1000, 10)
+	seedVariant(t, db, "p2", 250, 3)
+	cart := fillCart(t, cartService, "1", line("p1", 2), line("p2", 3))
+	_, err := inventory.Record(db, inventory.Movement{SKU: "p2", Kind: inventory.KindAdjustment, Quantity: -2, Reason: "damaged", Actor: "test"})
+	require.NoError(t, err)
+
+	// --- Act ---
+	order, err := cartService.Checkout(ctx, cart.ID, "1", nil)
+
+	// --- Assert ---
+	assert.ErrorIs(t, err, ErrInsufficientStock)
+	assert.Nil(t, order)
+	assert.Equal(t, 10, stockOf(t, db, "p1").Inventory, "the p1 reservation should be rolled back")
+	assert.Equal(t, 1, stockOf(t, db, "p2").Inventory)
+
+	var count int64
+	require.NoError(t, db.Model(&models.Order{}).Count(&count).Error)
+	assert.Zero(t, count)
+
+	kept, err := cartService.GetCart(ctx, cart.ID)
+	require.NoError(t, err)
+	assert.Len(t, kept.Lines, 2)
+}
+
+// TestCheckout_TotalMismatch A cart whose prices changed since the shopper
+// saw them is not checked out at the new total unless they agree to it.
+func TestCheckout_TotalMismatch(t *testing.T) {
+	db, cartService, ctx := setupCartEnv(t)
+
+	// --- Arrange ---
+	seedVariant(t, db, "p1", 1000, 10)
+	cart := fillCart(t, cartService, "1", line("p1", 2))
+	require.NoError(t, db.Exec(`UPDATE products SET price_amount = 1200 WHERE id = 'p1'`).Error)
+	seen := money.New(2000, "USD")
+
+	// --- Act ---
+	order, err := cartService.Checkout(ctx, cart.ID, "1", &seen)
+
+	// --- Assert ---
+	assert.ErrorIs(t, err, ErrTotalMismatch)
+	assert.Nil(t, order)
+	assert.Equal(t, 10, stockOf(t, db, "p1").Inventory)
+
+	current := money.New(2400, "USD")
+	order, err = cartService.Checkout(ctx, cart.ID, "1", &current)
+	require.NoError(t, err)
+	assert.Equal(t, current, order.TotalPrice)
+}
+
+// TestGetCart_Revalidates Loading a cart flags lines whose price or stock
+// changed since they were added, and lines that can no longer be bought hold
+// up checkout.
+func TestGetCart_Revalidates(t *testing.T) {
+	db, cartService, ctx := setupCartEnv(t)
+
+	// --- Arrange ---
+	seedVariant(t, db, "p1", 1000, 10)
+	seedVariant(t, db, "p2", 250, 3)
+	cart := fillCart(t, cartService, "1", line("p1", 2), line("p2", 3))
+	require.NoError(t, db.Exec(`UPDATE products SET price_amount = 1200 WHERE id = 'p1'`).Error)
+	_, err := inventory.Record(db, inventory.Movement{SKU: "p2", Kind: inventory.KindAdjustment, Quantity: -2, Reason: "damaged", Actor: "test"})
+	require.NoError(t, err)
+
+	// --- Act ---
+	loaded, err := cartService.GetCart(ctx, cart.ID)
+
+	// --- Assert ---
+	require.NoError(t, err)
+	p1, p2 := findCartLine(loaded, "p1"), findCartLine(loaded, "p2")
+	require.NotNil(t, p1)
+	require.NotNil(t, p2)
+
+	require.NotNil(t, p1.Issue)
+	assert.Equal(t, models.CartLineIssuePriceChanged, *p1.Issue)
+	assert.Equal(t, money.New(1000, "USD"), p1.AddedUnitPrice)
+	assert.Equal(t, money.New(1200, "USD"), p1.UnitPrice)
+
+	require.NotNil(t, p2.Issue)
+	assert.Equal(t, models.CartLineIssueInsufficientStock, *p2.Issue)
+	assert.Equal(t, 1, p2.InStock)
+
+	assert.False(t, loaded.CheckoutReady())
+}
+
+// TestCheckout_ExpiredCart An expired cart can be neither loaded nor checked
+// out, and reserves nothing.
+func TestCheckout_ExpiredCart(t *testing.T) {
+	db, cartService, ctx := setupCartEnv(t)
+
+	// --- Arrange ---
+	seedVariant(t, db, "p1", 1000, 10)
+	cart := fillCart(t, cartService, "1", line("p1", 2))
+	require.NoError(t, db.Exec(`UPDATE carts SET expires_at = now() - interval '1 minute' WHERE id = ?`, cart.ID).Error)
+
+	// --- Act ---
+	order, err := cartService.Checkout(ctx, cart.ID, "1", nil)
+
+	// --- Assert ---
+	assert.ErrorIs(t, err, ErrCartNotFound)
+	assert.Equal(t, apperr.CodeNotFound, apperr.CodeOf(err))
+	assert.Nil(t, order)
+	assert.Equal(t, 10, stockOf(t, db, "p1").Inventory)
+
+	_, err = cartService.GetCart(ctx, cart.ID)
+	assert.ErrorIs(t, err, ErrCartNotFound)
+
+	// The user gets a fresh, empty cart in its place
+	fresh, err := cartService.CreateCart(ctx, "1")
+	require.NoError(t, err)
+	assert.NotEqual(t, cart.ID, fresh.ID)
+	assert.Empty(t, fresh.Lines)
+}
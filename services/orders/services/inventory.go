@@ -0,0 +1,166 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/tagaertner/e-commerce-graphql/pkg/apperr"
+	"github.com/tagaertner/e-commerce-graphql/pkg/inventory"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInsufficientStock is returned when a variant cannot cover the requested quantity.
+var ErrInsufficientStock error = apperr.New(apperr.CodeConflict, "insufficient stock")
+
+// ErrProductUnavailable is returned when a variant has been taken off sale.
+var ErrProductUnavailable error = apperr.New(apperr.CodeConflict, "product unavailable")
+
+// variantStock maps the stock columns of the product_variants table owned
+// by the products service. Orders and products share one database, so stock
+// can be reserved inside the same transaction that writes the order.
+type variantStock struct {
+	ID        string `gorm:"primaryKey"`
+	ProductID string
+	SKU       string `gorm:"column:sku"`
+	Inventory int
+	Available bool
+}
+
+func (variantStock) TableName() string { return "product_variants" }
+
+// reserveStock takes the given quantity of each SKU out of inventory for an
+// order and records the reservations in the inventory ledger, flipping a
+// variant to unavailable once it reaches zero. Rows are locked in SKU order
+// so concurrent orders for the same variants cannot deadlock.
+func reserveStock(tx *gorm.DB, orderID, actor, reason string, quantities map[string]int) error {
+	var products []string
+	for _, sku := range sortedSKUs(quantities) {
+		quantity := quantities[sku]
+		var stock variantStock
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&stock, "sku = ?", sku).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apperr.NotFound("variant %s not found", sku)
+			}
+			return err
+		}
+		// The product may have been deleted since the order was priced;
+		// deleting it waits on this lock
+		var live int64
+		if err := tx.Table("products").Where("id = ? AND deleted_at IS NULL", stock.ProductID).Count(&live).Error; err != nil {
+			return err
+		}
+		if live == 0 {
+			return apperr.NotFound("variant %s not found", sku)
+		}
+
+		if !stock.Available {
+			return fmt.Errorf("%w: variant %s", ErrProductUnavailable, sku)
+		}
+		if stock.Inventory < quantity {
+			return fmt.Errorf("%w: variant %s has %d left, requested %d", ErrInsufficientStock, sku, stock.Inventory, quantity)
+		}
+
+		entry, err := inventory.Record(tx, movement(inventory.KindReservation, sku, quantity, orderID, actor, reason))
+		if err != nil {
+			return err
+		}
+		if entry.Balance == 0 {
+			if err := tx.Model(&variantStock{}).Where("id = ?", stock.ID).Update("available", false).Error; err != nil {
+				return err
+			}
+		}
+		products = append(products, stock.ProductID)
+	}
+	return inventory.SyncProducts(tx, products...)
+}
+
+// releaseStock returns the given quantity of each SKU to inventory and
+// records the releases in the inventory ledger. A variant that was sold out
+// becomes available again; one that was taken off sale by hand stays
+// unavailable. SKUs whose variant has since been deleted are skipped, as
+// there is no stock left to return them to.
+func releaseStock(tx *gorm.DB, orderID, actor, reason string, quantities map[string]int) error {
+	var products []string
+	for _, sku := range sortedSKUs(quantities) {
+		entry, err := inventory.Record(tx, movement(inventory.KindRelease, sku, quantities[sku], orderID, actor, reason))
+		if errors.Is(err, inventory.ErrVariantNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		// The variant was sold out if all of its stock came back just now
+		if entry.Balance == entry.Quantity {
+			if err := tx.Model(&variantStock{}).Where("sku = ?", sku).Update("available", true).Error; err != nil {
+				return err
+			}
+		}
+		products = append(products, entry.ProductID)
+	}
+	return inventory.SyncProducts(tx, products...)
+}
+
+// sellStock records in the inventory ledger that the stock an order
+// reserved has been paid for. Inventory does not change.
+func sellStock(tx *gorm.DB, orderID, actor string, quantities map[string]int) error {
+	for _, sku := range sortedSKUs(quantities) {
+		if _, err := inventory.Record(tx, movement(inventory.KindSale, sku, quantities[sku], orderID, actor, "order paid")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// adjustStock reserves or releases the difference between two quantities
+// of a single SKU on an order.
+func adjustStock(tx *gorm.DB, orderID, actor, sku string, from, to int) error {
+	switch delta := to - from; {
+	case delta > 0:
+		return reserveStock(tx, orderID, actor, "order quantity changed", map[string]int{sku: delta})
+	case delta < 0:
+		return releaseStock(tx, orderID, actor, "order quantity changed", map[string]int{sku: -delta})
+	}
+	return nil
+}
+
+func movement(kind inventory.Kind, sku string, quantity int, orderID, actor, reason string) inventory.Movement {
+	return inventory.Movement{
+		SKU:      sku,
+		Kind:     kind,
+		Quantity: quantity,
+		Reason:   reason,
+		Actor:    actor,
+		OrderID:  &orderID,
+	}
+}
+
+// soleVariantSKU returns the SKU of a product's only variant, for line items
+// given by product ID.
+func soleVariantSKU(tx *gorm.DB, productID string) (string, error) {
+	var skus []string
+	if err := tx.Model(&variantStock{}).
+		Where("product_id = ?", productID).
+		Limit(2).
+		Pluck("sku", &skus).Error; err != nil {
+		return "", err
+	}
+	switch len(skus) {
+	case 0:
+		return "", apperr.NotFound("product %s not found", productID)
+	case 1:
+		return skus[0], nil
+	}
+	return "", apperr.Field("sku", "product %s has several variants; give a sku", productID)
+}
+
+func sortedSKUs(quantities map[string]int) []string {
+	skus := make([]string, 0, len(quantities))
+	for sku := range quantities {
+		skus = append(skus, sku)
+	}
+	sort.Strings(skus)
+	return skus
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tagaertner/e-commerce-graphql/pkg/inventory"
+	"github.com/tagaertner/e-commerce-graphql/services/orders/models"
+	"gorm.io/gorm"
+)
+
+// expire backdates an order's reservation so ExpireReservations picks it up.
+func expire(t *testing.T, db *gorm.DB, orderID string) {
+	t.Helper()
+	require.NoError(t, db.Exec(`UPDATE orders SET reservation_expires_at = now() - interval '1 minute' WHERE id = ?`, orderID).Error)
+}
+
+// orderEntries returns the ledger entries an order wrote, oldest first.
+func orderEntries(t *testing.T, db *gorm.DB, orderID string) []inventory.Entry {
+	t.Helper()
+	var entries []inventory.Entry
+	require.NoError(t, db.Where("order_id = ?", orderID).Order("created_at, id").Find(&entries).Error)
+	return entries
+}
+
+// TestOrderLedger An order's life is traced in the ledger: a reservation
+// when placed, a sale when paid and a release when refunded.
+func TestOrderLedger(t *testing.T) {
+	db, orderService, ctx := setupTestEnv(t)
+
+	// --- Arrange ---
+	seedVariant(t, db, "p1", 1000, 5)
+	order := placeOrder(t, orderService, "1", line("p1", 2))
+
+	// --- Act ---
+	for _, status := range []models.OrderStatus{models.OrderStatusPaid, models.OrderStatusRefunded} {
+		_, err := orderService.SetOrderStatus(ctx, models.SetOrderStatusInput{OrderID: order.ID, Status: status})
+		require.NoError(t, err)
+	}
+
+	// --- Assert ---
+	entries := orderEntries(t, db, order.ID)
+	require.Len(t, entries, 3)
+
+	assert.Equal(t, inventory.KindReservation, entries[0].Kind)
+	assert.Equal(t, -2, entries[0].Change)
+	assert.Equal(t, 3, entries[0].Balance)
+	assert.Equal(t, "order placed", entries[0].Reason)
+
+	assert.Equal(t, inventory.KindSale, entries[1].Kind)
+	assert.Equal(t, 2, entries[1].Quantity)
+	assert.Equal(t, 0, entries[1].Change, "a sale leaves inventory alone")
+	assert.Equal(t, 3, entries[1].Balance)
+
+	assert.Equal(t, inventory.KindRelease, entries[2].Kind)
+	assert.Equal(t, 2, entries[2].Change)
+	assert.Equal(t, 5, entries[2].Balance)
+	assert.Equal(t, "order refunded", entries[2].Reason)
+
+	assert.Equal(t, 5, stockOf(t, db, "p1").Inventory)
+}
+
+// TestExpireReservations Only PENDING orders past their reservation are
+// cancelled, by the system, and their stock comes back.
+func TestExpireReservations(t *testing.T) {
+	db, orderService, ctx := setupTestEnv(t)
+
+	// --- Arrange ---
+	seedVariant(t, db, "p1", 1000, 10)
+	stale := placeOrder(t, orderService, "1", line("p1", 3))
+	fresh := placeOrder(t, orderService, "2", line("p1", 2))
+	paid := placeOrder(t, orderService, "3", line("p1", 1))
+	_, err := orderService.SetOrderStatus(ctx, models.SetOrderStatusInput{OrderID: paid.ID, Status: models.OrderStatusPaid})
+	require.NoError(t, err)
+	expire(t, db, stale.ID)
+
+	// --- Act ---
+	expired, err := orderService.ExpireReservations(ctx)
+
+	// --- Assert ---
+	require.NoError(t, err)
+	assert.Equal(t, 1, expired)
+	assert.Equal(t, 7, stockOf(t, db, "p1").Inventory)
+
+	cancelled, err := orderService.GetOrderByID(ctx, stale.ID, false)
+	require.NoError(t, err)
+	assert.Equal(t, models.OrderStatusCancelled, cancelled.Status)
+	assert.Nil(t, cancelled.ReservationExpiresAt)
+	require.Len(t, cancelled.StatusHistory, 2)
+	assert.Equal(t, systemActor, cancelled.StatusHistory[1].Actor)
+
+	entries := orderEntries(t, db, stale.ID)
+	require.Len(t, entries, 2)
+	assert.Equal(t, inventory.KindRelease, entries[1].Kind)
+	assert.Equal(t, "reservation expired", entries[1].Reason)
+	assert.Equal(t, systemActor, entries[1].Actor)
+
+	for _, id := range []string{fresh.ID, paid.ID} {
+		order, err := orderService.GetOrderByID(ctx, id, false)
+		require.NoError(t, err)
+		assert.NotEqual(t, models.OrderStatusCancelled, order.Status)
+	}
+
+	// A second run has nothing left to do
+	expired, err = orderService.ExpireReservations(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, expired)
+}
+
+// TestExpireReservations_MissingVariant An order for a variant that has
+// since been deleted is still cancelled, returning what stock it can, and
+// does not hold up other orders.
+func TestExpireReservations_MissingVariant(t *testing.T) {
+	db, orderService, ctx := setupTestEnv(t)
+
+	// --- Arrange ---
+	seedVariant(t, db, "p1", 1000, 10)
+	seedVariant(t, db, "p2", 250, 10)
+	mixed := placeOrder(t, orderService, "1", line("p1", 1), line("p2", 2))
+	other := placeOrder(t, orderService, "2", line("p1", 4))
+	require.NoError(t, db.Exec(`DELETE FROM product_variants WHERE sku = 'p2'`).Error)
+	expire(t, db, mixed.ID)
+	expire(t, db, other.ID)
+
+	// --- Act ---
+	expired, err := orderService.ExpireReservations(ctx)
+
+	// --- Assert ---
+	require.NoError(t, err)
+	assert.Equal(t, 2, expired)
+	assert.Equal(t, 10, stockOf(t, db, "p1").Inventory)
+
+	for _, id := range []string{mixed.ID, other.ID} {
+		order, err := orderService.GetOrderByID(ctx, id, false)
+		require.NoError(t, err)
+		assert.Equal(t, models.OrderStatusCancelled, order.Status)
+	}
+
+	var releases []string
+	for _, e := range orderEntries(t, db, mixed.ID) {
+		if e.Kind == inventory.KindRelease {
+			releases = append(releases, e.SKU)
+		}
+	}
+	assert.Equal(t, []string{"p1"}, releases, "the deleted variant has no stock to return to")
+}
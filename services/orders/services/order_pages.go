@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/tagaertner/e-commerce-graphql/pkg/pagination"
+	"github.com/tagaertner/e-commerce-graphql/services/orders/models"
+	"gorm.io/gorm"
+)
+
+// OrderSort is the order every order list is paged in, oldest first.
+var OrderSort = pagination.Sort{Name: "CREATED_AT", Column: "created_at", Key: time.Time{}}
+
+// OrderPage is one page of orders, oldest first, with each order's cursor.
+type OrderPage struct {
+	Orders     []*models.Order
+	Cursors    []string
+	PageInfo   pagination.PageInfo
+	TotalCount int
+}
+
+// GetOrdersPage returns one page of all orders in (created_at, id) order,
+// with deleted orders only if includeDeleted is set.
+func (s *OrderService) GetOrdersPage(ctx context.Context, page pagination.Page, includeDeleted bool) (*OrderPage, error) {
+	return s.orderPage(ctx, s.scope(ctx, includeDeleted).Model(&models.Order{}), page)
+}
+
+// GetOrdersByUserIDPage returns one page of a user's orders, with deleted
+// orders only if includeDeleted is set.
+func (s *OrderService) GetOrdersByUserIDPage(ctx context.Context, userID string, page pagination.Page, includeDeleted bool) (*OrderPage, error) {
+	return s.orderPage(ctx, s.scope(ctx, includeDeleted).Model(&models.Order{}).Where("user_id = ?", userID), page)
+}
+
+func (s *OrderService) orderPage(ctx context.Context, scope *gorm.DB, page pagination.Page) (*OrderPage, error) {
+	var total int64
+	if err := scope.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	var orders []*models.Order
+	if err := preloadOrder(page.Apply(scope.Session(&gorm.Session{}))).
+		Find(&orders).Error; err != nil {
+		return nil, err
+	}
+
+	orders, cursors, info := pagination.Trim(orders, page, orderCursor)
+	return &OrderPage{Orders: orders, Cursors: cursors, PageInfo: info, TotalCount: int(total)}, nil
+}
+
+// GetOrderPagesByUserIDs returns the same page of orders for many users in
+// one query, keyed by user ID. Users without orders get an empty page.
+func (s *OrderService) GetOrderPagesByUserIDs(ctx context.Context, userIDs []string, page pagination.Page) (map[string]*OrderPage, error) {
+	db := s.db.WithContext(ctx)
+
+	// Number each user's rows in page order and keep one more than the
+	// page size, so Trim can tell whether another page follows
+	ranked := page.Filter(db.Model(&models.Order{})).
+		Select("orders.*, ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY " + page.OrderBy() + ") AS page_row").
+		Where("user_id IN ?", userIDs)
+
+	var orders []*models.Order
+	if err := preloadOrder(db.Table("(?) AS orders", ranked)).
+		Where("page_row <= ?", page.Limit+1).
+		Order("user_id, page_row").
+		Find(&orders).Error; err != nil {
+		return nil, err
+	}
+
+	var counts []struct {
+		UserID string
+		Total  int
+	}
+	if err := db.Model(&models.Order{}).
+		Select("user_id, COUNT(*) AS total").
+		Where("user_id IN ?", userIDs).
+		Group("user_id").
+		Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+
+	byUser := make(map[string][]*models.Order, len(userIDs))
+	for _, o := range orders {
+		byUser[o.UserID] = append(byUser[o.UserID], o)
+	}
+	totals := make(map[string]int, len(counts))
+	for _, c := range counts {
+		totals[c.UserID] = c.Total
+	}
+
+	pages := make(map[string]*OrderPage, len(userIDs))
+	for _, userID := range userIDs {
+		userOrders, cursors, info := pagination.Trim(byUser[userID], page, orderCursor)
+		pages[userID] = &OrderPage{Orders: userOrders, Cursors: cursors, PageInfo: info, TotalCount: totals[userID]}
+	}
+	return pages, nil
+}
+
+func orderCursor(o *models.Order) pagination.Cursor {
+	return pagination.Cursor{Key: time.Time(o.CreatedAt), ID: o.ID}
+}
@@ -4,12 +4,23 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
+	"strings"
 	"time"
 
+	"github.com/tagaertner/e-commerce-graphql/pkg/apperr"
+	"github.com/tagaertner/e-commerce-graphql/pkg/auth"
+	"github.com/tagaertner/e-commerce-graphql/pkg/money"
+	"github.com/tagaertner/e-commerce-graphql/pkg/validate"
 	"github.com/tagaertner/e-commerce-graphql/services/orders/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ReservationTTL is how long a PENDING order holds its stock. Orders that
+// are not paid in time are cancelled and their stock returned.
+const ReservationTTL = 30 * time.Minute
+
 type OrderService struct {
 	db *gorm.DB
 }
@@ -18,115 +29,494 @@ func NewOrderService(db *gorm.DB) *OrderService {
 	return &OrderService{db: db}
 }
 
-func (s *OrderService) GetAllOrders() ([]*models.Order, error) {
-	var orders []*models.Order
-	if err := s.db.Find(&orders).Error; err != nil {
-		return nil, err
+// scope returns the service's DB for ctx, including deleted orders when
+// includeDeleted is set.
+func (s *OrderService) scope(ctx context.Context, includeDeleted bool) *gorm.DB {
+	db := s.db.WithContext(ctx)
+	if includeDeleted {
+		db = db.Unscoped()
 	}
-	return orders, nil
+	return db
 }
 
-func (s *OrderService) GetOrderByID(id string) (*models.Order, error) {
+// GetOrderByID returns an order. Deleted orders are only found with
+// includeDeleted.
+func (s *OrderService) GetOrderByID(ctx context.Context, id string, includeDeleted bool) (*models.Order, error) {
 	var order models.Order
-	if err := s.db.First(&order, "id = ?", id).Error; err != nil {
+	if err := preloadOrder(s.scope(ctx, includeDeleted)).First(&order, "id = ?", id).Error; err != nil {
 		return nil, err
 	}
 	return &order, nil
 }
 
-func (s *OrderService) GetOrdersByUserID(userID string) ([]*models.Order, error) {
+// GetOrdersByIDs loads many orders in one query, keyed by ID. Unknown IDs
+// are left out. Deleted orders are included, so references to them keep
+// resolving until they are purged.
+func (s *OrderService) GetOrdersByIDs(ctx context.Context, ids []string) (map[string]*models.Order, error) {
 	var orders []*models.Order
-	if err := s.db.
-        Where("user_id = ?", userID).
-        Preload("Products").
-		Order("created_at ASC").
-        Find(&orders).Error; err != nil {
-        return nil, err
+	if err := preloadOrder(s.scope(ctx, true)).Where("id IN ?", ids).Find(&orders).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*models.Order, len(orders))
+	for _, o := range orders {
+		byID[o.ID] = o
 	}
-	return orders, nil
+	return byID, nil
 }
 
-func (s *OrderService)CreateOrder(ctx context.Context, userId string, productIds [] string, quantity int, totalPrice float64, status string, createdAt time.Time ) (*models.Order, error){
+// CreateOrder prices each line item from current variant prices, reserves
+// stock and stores a unit price snapshot per line. Lines for the same SKU
+// are merged. All lines must share a currency. expectedTotal is optional; when
+// given, the order is rejected if it disagrees with the computed total.
+// New orders always start as PENDING.
+func (s *OrderService)CreateOrder(ctx context.Context, userId string, lineItems []*models.OrderLineItemInput, expectedTotal *money.Money, status models.OrderStatus, createdAt time.Time ) (*models.Order, error){
+	var order *models.Order
+	// Price, reserve stock and write the order in one transaction so a failed
+	// reservation never leaves an order behind, and vice versa.
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		order, err = s.placeOrder(ctx, tx, userId, lineItems, expectedTotal, status, createdAt)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// placeOrder does the work of CreateOrder inside the caller's transaction,
+// so checkout can place an order and empty a cart all or nothing.
+func (s *OrderService) placeOrder(ctx context.Context, tx *gorm.DB, userId string, lineItems []*models.OrderLineItemInput, expectedTotal *money.Money, status models.OrderStatus, createdAt time.Time) (*models.Order, error) {
+	if status == "" {
+		status = models.OrderStatusPending
+	}
+	var violations validate.Violations
+	if status != models.OrderStatusPending {
+		violations.Add("status", "new orders must be %s, got %s", models.OrderStatusPending, status)
+	}
+	if userId == "" {
+		violations.Add("userId", "is required")
+	}
+	if len(lineItems) == 0 {
+		violations.Add("lineItems", "is required")
+	}
+	for i, l := range lineItems {
+		checkLineItem(&violations, fmt.Sprintf("lineItems[%d].", i), l)
+	}
+	if err := violations.Err(); err != nil {
+		return nil, err
+	}
+
+	expiresAt := models.Time(time.Now().UTC().Add(ReservationTTL))
 	order := &models.Order {
 		ID: fmt.Sprintf("order_%d", time.Now().UnixNano()),
 		UserID: userId,
-		Quantity: quantity,
-		TotalPrice: totalPrice,
 		Status: status,
 		CreatedAt: models.Time(createdAt),
+		ReservationExpiresAt: &expiresAt,
 	}
 
-	if userId == "" || len(productIds) == 0 || quantity <= 0 || totalPrice <= 0 {
-		return nil, errors.New("invalid order input: missing or invalid fields")
+	skus, quantities, err := mergeLineItems(tx, lineItems)
+	if err != nil {
+		return nil, err
 	}
 
-	if quantity <= 0 {
-		return nil, errors.New("invalid order input: quantity must be greater than zero")
+	prices, err := variantPrices(tx, skus)
+	if err != nil {
+		return nil, err
 	}
-	
-	for _, pid := range productIds {
-		order.Products = append(order.Products, models.Product{ID: pid})
+
+	for i, sku := range skus {
+		order.LineItems = append(order.LineItems, models.OrderLineItem{
+			ID:        fmt.Sprintf("%s_%d", order.ID, i+1),
+			OrderID:   order.ID,
+			SKU:       sku,
+			ProductID: prices[sku].ProductID,
+			Quantity:  quantities[sku],
+			UnitPrice: prices[sku].Price,
+		})
 	}
-	if err := s.db.WithContext(ctx).Create(order).Error; err != nil {
+	if order.TotalPrice, err = orderTotal(order.LineItems); err != nil {
+		return nil, err
+	}
+
+	if err := checkExpectedTotal(expectedTotal, order.TotalPrice); err != nil {
+		return nil, err
+	}
+
+	if err := reserveStock(tx, order.ID, auth.ActorFromContext(ctx), "order placed", quantities); err != nil {
+		return nil, err
+	}
+
+	order.StatusHistory = []models.OrderStatusChange{{
+		OrderID:   order.ID,
+		To:        status,
+		Actor:     auth.ActorFromContext(ctx),
+		ChangedAt: models.Now(),
+	}}
+	if err := tx.Create(order).Error; err != nil {
 		return nil, err
 	}
 	return order, nil
 }
 
 func (s *OrderService) UpdateOrder(ctx context.Context, input *models.UpdateOrderInput) (*models.Order, error) {
-	// Fetch existing order by ID
 	var order models.Order
-	if err := s.db.WithContext(ctx).First(&order, "id = ?", input.OrderID).Error; err != nil {
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Fetch existing order by ID, locking it so concurrent updates
+		// cannot reserve or release the same stock twice
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Preload("LineItems").
+			First(&order, "id = ?", input.OrderID).Error; err != nil {
+			return err
+		}
+
+		// Apply updates only if the fields are not nil
+		if input.Status != nil {
+			if err := changeStatus(tx, &order, *input.Status, auth.ActorFromContext(ctx)); err != nil {
+				return err
+			}
+		}
+
+		// Save the updated order
+		return tx.Omit(clause.Associations).Save(&order).Error
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	// Apply updates only if the fields are not nil
-	if input.Quantity != nil {
-		order.Quantity = *input.Quantity
+	return s.GetOrderByID(ctx, order.ID, false)
+}
+
+// DeleteOrder soft-deletes an order, releasing any stock it holds. Its line
+// items and history are kept, and it can be restored until PurgeDeleted
+// removes it.
+func (s *OrderService)DeleteOrder(ctx context.Context, input models.DeleteOrderInput) (bool, error) {
+	// Guard clause: require at least OrderID
+	if input.OrderID == "" {
+		return false, apperr.Field("orderId", "is required")
 	}
-	if input.TotalPrice != nil {
-		order.TotalPrice = *input.TotalPrice
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var order models.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Preload("LineItems").
+			First(&order, "id = ?", input.OrderID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apperr.NotFound("order %s not found", input.OrderID)
+			}
+			return err
+		}
+
+		// Stock of cancelled orders has been released, and stock of shipped
+		// orders has left the warehouse
+		if order.Status.HoldsStock() {
+			if err := releaseStock(tx, order.ID, auth.ActorFromContext(ctx), "order deleted", order.LineQuantities()); err != nil {
+				return err
+			}
+		}
+
+		result := tx.Delete(&models.Order{}, "id = ?", input.OrderID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return apperr.NotFound("order %s not found", input.OrderID)
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
 	}
-	if input.Status != nil {
-		order.Status = *input.Status
+	return true, nil
+}
+
+// RestoreOrder undoes DeleteOrder. An order that held stock reserves it
+// again, failing if it is no longer in stock, and a PENDING order gets a
+// fresh reservation window.
+func (s *OrderService) RestoreOrder(ctx context.Context, id string) (*models.Order, error) {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var order models.Order
+		if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).
+			Preload("LineItems").
+			First(&order, "id = ? AND deleted_at IS NOT NULL", id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apperr.NotFound("deleted order %s not found", id)
+			}
+			return err
+		}
+
+		updates := map[string]interface{}{"deleted_at": nil}
+		if order.Status.HoldsStock() {
+			if err := reserveStock(tx, order.ID, auth.ActorFromContext(ctx), "order restored", order.LineQuantities()); err != nil {
+				return err
+			}
+		}
+		if order.Status == models.OrderStatusPending {
+			updates["reservation_expires_at"] = models.Time(time.Now().UTC().Add(ReservationTTL))
+		}
+		return tx.Unscoped().Model(&order).Updates(updates).Error
+	})
+	if err != nil {
+		return nil, err
 	}
+	return s.GetOrderByID(ctx, id, false)
+}
+
+// PurgeDeleted permanently removes orders deleted before cutoff, along with
+// their line items and status history, and returns how many it removed.
+func (s *OrderService) PurgeDeleted(ctx context.Context, cutoff time.Time) (int64, error) {
+	result := s.db.WithContext(ctx).Unscoped().Where("deleted_at < ?", cutoff).Delete(&models.Order{})
+	return result.RowsAffected, result.Error
+}
 
-	// Save the updated order
-	if err := s.db.WithContext(ctx).Save(&order).Error; err != nil {
+func (s *OrderService)SetOrderStatus(ctx context.Context, input models.SetOrderStatusInput) (*models.Order, error) {
+	var order models.Order
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Preload("LineItems").
+			First(&order, "id = ?", input.OrderID).Error; err != nil {
+			return err
+		}
+
+		if err := changeStatus(tx, &order, input.Status, auth.ActorFromContext(ctx)); err != nil {
+			return err
+		}
+		return tx.Model(&order).Updates(map[string]interface{}{
+			"status":                 order.Status,
+			"reservation_expires_at": order.ReservationExpiresAt,
+		}).Error
+	})
+	if err != nil {
 		return nil, err
 	}
+	return s.GetOrderByID(ctx, order.ID, false)
+}
 
-	return &order, nil
+// ChangeOrderQuantity sets the quantity of one line item, reserving or
+// releasing the difference in stock and repricing the whole order from
+// current variant prices. Only PENDING orders can be changed; anything else
+// fails with ErrOrderNotModifiable. The line is picked by SKU or product ID,
+// which may both be omitted for orders with a single line item.
+func (s *OrderService) ChangeOrderQuantity(ctx context.Context, input models.ChangeOrderQuantityInput) (*models.Order, error) {
+	if input.Quantity <= 0 {
+		return nil, apperr.Field("quantity", "must be greater than zero")
+	}
+
+	var order models.Order
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Preload("LineItems").
+			First(&order, "id = ?", input.OrderID).Error; err != nil {
+			return err
+		}
+		if err := checkModifiable(order.Status); err != nil {
+			return err
+		}
+
+		line, err := findLineItem(&order, input.SKU, input.ProductID)
+		if err != nil {
+			return err
+		}
+		if err := adjustStock(tx, order.ID, auth.ActorFromContext(ctx), line.SKU, line.Quantity, input.Quantity); err != nil {
+			return err
+		}
+		line.Quantity = input.Quantity
+
+		skus := make([]string, 0, len(order.LineItems))
+		for _, l := range order.LineItems {
+			skus = append(skus, l.SKU)
+		}
+		prices, err := variantPrices(tx, skus)
+		if err != nil {
+			return err
+		}
+		for i := range order.LineItems {
+			order.LineItems[i].UnitPrice = prices[order.LineItems[i].SKU].Price
+		}
+		if order.TotalPrice, err = orderTotal(order.LineItems); err != nil {
+			return err
+		}
+
+		if err := tx.Save(&order.LineItems).Error; err != nil {
+			return err
+		}
+		return tx.Model(&order).Updates(map[string]interface{}{
+			"total_price_amount":   order.TotalPrice.Amount,
+			"total_price_currency": order.TotalPrice.Currency,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetOrderByID(ctx, order.ID, false)
 }
 
-func (s *OrderService)DeleteOrder(ctx context.Context, input models.DeleteOrderInput) (bool, error) {
-	var result *gorm.DB
+// findLineItem returns the order's line for sku, or else for productID, or
+// its only line when both are nil. A product ID must match a single line.
+func findLineItem(order *models.Order, sku, productID *string) (*models.OrderLineItem, error) {
+	switch {
+	case sku != nil:
+		for i := range order.LineItems {
+			if order.LineItems[i].SKU == *sku {
+				return &order.LineItems[i], nil
+			}
+		}
+		return nil, apperr.NotFound("variant %s is not on order %s", *sku, order.ID)
 
-	// Guard clause: require at least OrderID
-	if input.OrderID == "" {
-		return false, errors.New("OrderID must be provided for deletion")
+	case productID != nil:
+		var line *models.OrderLineItem
+		for i := range order.LineItems {
+			if order.LineItems[i].ProductID != *productID {
+				continue
+			}
+			if line != nil {
+				return nil, apperr.Field("sku", "order %s has several variants of product %s; give a sku", order.ID, *productID)
+			}
+			line = &order.LineItems[i]
+		}
+		if line == nil {
+			return nil, apperr.NotFound("product %s is not on order %s", *productID, order.ID)
+		}
+		return line, nil
 	}
 
-	// Delete by OrderID
-	result = s.db.WithContext(ctx).Delete(&models.Order{}, "id = ?", input.OrderID)
+	if len(order.LineItems) != 1 {
+		return nil, apperr.Validation("invalid order input: sku or productId is required for orders with more than one line item")
+	}
+	return &order.LineItems[0], nil
+}
 
-	// Handle errors and no-op cases
-	if result.Error != nil {
-		return false, result.Error
+// ExpireReservations cancels every PENDING order whose reservation has
+// expired, returning its stock, and reports how many it cancelled. Orders
+// another transaction is working on are left for the next run, and an order
+// that fails to cancel is logged and skipped so it cannot hold up the rest.
+func (s *OrderService) ExpireReservations(ctx context.Context) (int, error) {
+	var ids []string
+	if err := s.db.WithContext(ctx).Model(&models.Order{}).
+		Where("status = ? AND reservation_expires_at <= ?", models.OrderStatusPending, time.Now()).
+		Order("reservation_expires_at").
+		Pluck("id", &ids).Error; err != nil {
+		return 0, err
 	}
-	if result.RowsAffected == 0 {
-		return false, errors.New("order not found")
+
+	expired := 0
+	for _, id := range ids {
+		cancelled := false
+		err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			var order models.Order
+			// The order may have been paid since it was listed
+			result := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+				Preload("LineItems").
+				Where("status = ? AND reservation_expires_at <= ?", models.OrderStatusPending, time.Now()).
+				Limit(1).
+				Find(&order, "id = ?", id)
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+
+			if err := changeStatus(tx, &order, models.OrderStatusCancelled, systemActor); err != nil {
+				return err
+			}
+			cancelled = true
+			return tx.Model(&order).Updates(map[string]interface{}{
+				"status":                 order.Status,
+				"reservation_expires_at": nil,
+			}).Error
+		})
+		if err != nil {
+			log.Printf("⚠️  Failed to expire reservation of order %s: %v", id, err)
+			continue
+		}
+		if cancelled {
+			expired++
+		}
 	}
-	return true, nil
+	return expired, nil
 }
 
-func (s *OrderService)SetOrderStatus(ctx context.Context, input models.SetOrderStatusInput) (*models.Order, error) {
-	order := &models.Order{ID: input.OrderID}
+// changeStatus moves a locked order to a new status if the state machine
+// allows it and records the transition. Stock is released when an order that
+// still holds it is cancelled or refunded, and marked sold when a PENDING
+// order is paid. Only PENDING orders keep a reservation expiry. The caller
+// persists order.Status and order.ReservationExpiresAt.
+func changeStatus(tx *gorm.DB, order *models.Order, to models.OrderStatus, actor string) error {
+	from := order.Status
+	if err := checkTransition(from, to); err != nil {
+		return err
+	}
 
-	if err := s.db.WithContext(ctx).
-	Model(order).
-	Update("status", input.Status).Error; err != nil {
-		return nil, err
+	switch {
+	case from.HoldsStock() && (to == models.OrderStatusCancelled || to == models.OrderStatusRefunded):
+		reason := "order " + strings.ToLower(string(to))
+		if actor == systemActor {
+			reason = "reservation expired"
+		}
+		if err := releaseStock(tx, order.ID, actor, reason, order.LineQuantities()); err != nil {
+			return err
+		}
+	case from == models.OrderStatusPending && to == models.OrderStatusPaid:
+		if err := sellStock(tx, order.ID, actor, order.LineQuantities()); err != nil {
+			return err
+		}
 	}
-	return order, nil
+
+	order.Status = to
+	order.ReservationExpiresAt = nil
+	return tx.Create(&models.OrderStatusChange{
+		OrderID:   order.ID,
+		From:      &from,
+		To:        to,
+		Actor:     actor,
+		ChangedAt: models.Now(),
+	}).Error
+}
+
+// preloadOrder loads an order's line items and its status history, oldest
+// transition first.
+func preloadOrder(db *gorm.DB) *gorm.DB {
+	return db.
+		Preload("LineItems").
+		Preload("StatusHistory", func(db *gorm.DB) *gorm.DB {
+			return db.Order("changed_at ASC, id ASC")
+		})
+}
+
+// checkLineItem records what is wrong with one line item, naming its fields
+// after prefix, e.g. "lineItems[0].".
+func checkLineItem(violations *validate.Violations, prefix string, l *models.OrderLineItemInput) {
+	if l == nil || (l.SKU == nil || *l.SKU == "") && (l.ProductID == nil || *l.ProductID == "") {
+		violations.Add(prefix+"sku", "a sku or productId is required")
+		return
+	}
+	if l.Quantity <= 0 {
+		violations.Add(prefix+"quantity", "must be greater than zero")
+	}
+}
+
+// mergeLineItems takes line items already checked with checkLineItem,
+// resolves those given by product ID to that product's only variant and folds
+// repeated SKUs into one line, returning SKUs in first-seen order with their
+// quantities.
+func mergeLineItems(tx *gorm.DB, lineItems []*models.OrderLineItemInput) ([]string, map[string]int, error) {
+	skus := make([]string, 0, len(lineItems))
+	quantities := make(map[string]int, len(lineItems))
+	for _, l := range lineItems {
+		var sku string
+		if l.SKU != nil && *l.SKU != "" {
+			sku = *l.SKU
+		} else {
+			var err error
+			if sku, err = soleVariantSKU(tx, *l.ProductID); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		if _, seen := quantities[sku]; !seen {
+			skus = append(skus, sku)
+		}
+		quantities[sku] += l.Quantity
+	}
+	return skus, quantities, nil
 }
@@ -0,0 +1,588 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/go-connections/nat"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tagaertner/e-commerce-graphql/pkg/apperr"
+	"github.com/tagaertner/e-commerce-graphql/pkg/inventory"
+	"github.com/tagaertner/e-commerce-graphql/pkg/migrate"
+	"github.com/tagaertner/e-commerce-graphql/pkg/money"
+	"github.com/tagaertner/e-commerce-graphql/pkg/pagination"
+	"github.com/tagaertner/e-commerce-graphql/services/orders/database"
+	"github.com/tagaertner/e-commerce-graphql/services/orders/models"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// === SET UP ===
+// setupTestDB starts a temporary Postgres container using testcontainers-go.
+// Requires Docker to be running. The container is created automatically for tests
+// and removed after they complete, providing an isolated Postgres instance that
+// matches production behavior. Orders reserve stock in the products
+// service's tables, so both services' migrations are applied.
+func setupTestDB(t *testing.T) *gorm.DB {
+    ctx := context.Background()
+	req := testcontainers.ContainerRequest{
+		Image:        "postgres:15",
+		Env: map[string]string{
+			"POSTGRES_USER":     "testuser",
+			"POSTGRES_PASSWORD": "testpass",
+			"POSTGRES_DB":       "testdb",
+		},
+		ExposedPorts: []string{"5432/tcp"},
+		WaitingFor:   wait.ForSQL("5432/tcp", "postgres", func(host string, port nat.Port) string {
+			return fmt.Sprintf("host=%s port=%s user=testuser password=testpass dbname=testdb sslmode=disable", host, port.Port())
+		}).WithStartupTimeout(60 * time.Second), // ⏳ give it a full minute
+	}
+	
+    pgContainer, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+        ContainerRequest: req,
+        Started:          true,
+    })
+    require.NoError(t, err)
+	testcontainers.CleanupContainer(t, pgContainer)
+
+    host, _ := pgContainer.Host(ctx)
+    port, _ := pgContainer.MappedPort(ctx, "5432/tcp")
+
+    dsn := fmt.Sprintf("host=%s port=%s user=testuser password=testpass dbname=testdb sslmode=disable", host, port.Port())
+    db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+    require.NoError(t, err)
+
+	products, err := migrate.New(db, "products", os.DirFS("../../products/database/migrations"))
+	require.NoError(t, err)
+	_, err = products.Up(ctx)
+	require.NoError(t, err)
+
+	orders, err := database.Migrator(db)
+	require.NoError(t, err)
+	_, err = orders.Up(ctx)
+	require.NoError(t, err)
+    return db
+}
+
+// setupTestEnv initializes a fresh test environment for Order service tests.
+// It sets up the database, creates a new OrderService instance,
+// and returns the DB, service, and context for use within tests.
+func setupTestEnv(t *testing.T) (*gorm.DB, *OrderService, context.Context) {
+	db := setupTestDB(t)
+    orderService := NewOrderService(db)
+    ctx := context.Background()
+	return db, orderService, ctx
+}
+
+// seedVariant creates a product with a single variant whose SKU is the
+// product ID, stocked through the inventory ledger as the products service
+// does.
+func seedVariant(t *testing.T, db *gorm.DB, sku string, cents int64, stock int) {
+	t.Helper()
+	require.NoError(t, db.Exec(`INSERT INTO products (id, name, description, price_amount, price_currency, inventory, available)
+		VALUES (?, ?, '', ?, 'USD', 0, false)`, sku, sku+" product", cents).Error)
+	require.NoError(t, db.Exec(`INSERT INTO product_variants (id, product_id, sku, inventory, available)
+		VALUES (?, ?, ?, 0, ?)`, "var_"+sku, sku, sku, stock > 0).Error)
+	if stock == 0 {
+		return
+	}
+	_, err := inventory.Record(db, inventory.Movement{SKU: sku, Kind: inventory.KindRestock, Quantity: stock, Reason: "initial stock", Actor: "test"})
+	require.NoError(t, err)
+	require.NoError(t, inventory.SyncProducts(db, sku))
+}
+
+// stockOf returns a variant's current stock and availability.
+func stockOf(t *testing.T, db *gorm.DB, sku string) variantStock {
+	t.Helper()
+	var stock variantStock
+	require.NoError(t, db.First(&stock, "sku = ?", sku).Error)
+	return stock
+}
+
+// ledgerFor returns a SKU's ledger entries, oldest first.
+func ledgerFor(t *testing.T, db *gorm.DB, sku string) []inventory.Entry {
+	t.Helper()
+	var entries []inventory.Entry
+	require.NoError(t, db.Where("sku = ?", sku).Order("created_at, id").Find(&entries).Error)
+	return entries
+}
+
+// line returns a line item input for quantity of a SKU.
+func line(sku string, quantity int) *models.OrderLineItemInput {
+	return &models.OrderLineItemInput{SKU: &sku, Quantity: quantity}
+}
+
+// Helper function for string literals
+func strPtr(s string) *string {
+	return &s
+}
+
+// placeOrder creates a PENDING order for user through the service.
+func placeOrder(t *testing.T, orderService *OrderService, userID string, lines ...*models.OrderLineItemInput) *models.Order {
+	t.Helper()
+	order, err := orderService.CreateOrder(context.Background(), userID, lines, nil, "", time.Now())
+	require.NoError(t, err)
+	return order
+}
+
+// userOrders returns the first page of a user's orders, oldest first.
+func userOrders(t *testing.T, orderService *OrderService, userID string) []*models.Order {
+	t.Helper()
+	page, err := pagination.Args{}.Page(OrderSort)
+	require.NoError(t, err)
+	result, err := orderService.GetOrdersByUserIDPage(context.Background(), userID, page, false)
+	require.NoError(t, err)
+	return result.Orders
+}
+
+// === Tests ===
+
+// TestCreateOrder_Success verifies that a valid order is priced from the
+// variant and starts PENDING with a reservation window.
+func TestCreateOrder_Success(t *testing.T) {
+	db, orderService, ctx := setupTestEnv(t)
+	seedVariant(t, db, "p1", 2499, 10)
+
+	// --- Act ---
+	created, err := orderService.CreateOrder(ctx, "1", []*models.OrderLineItemInput{line("p1", 2)}, nil, "", time.Now())
+
+	// --- Assert ---
+	require.NoError(t, err)
+	assert.NotEmpty(t, created.ID)
+	assert.Equal(t, "1", created.UserID)
+	assert.Equal(t, money.New(4998, "USD"), created.TotalPrice)
+	assert.Equal(t, models.OrderStatusPending, created.Status)
+	assert.NotNil(t, created.ReservationExpiresAt)
+	require.Len(t, created.LineItems, 1)
+	assert.Equal(t, money.New(2499, "USD"), created.LineItems[0].UnitPrice)
+}
+
+// TestCreateOrder_Failure ensures that CreateOrder returns an error
+// and does not persist data when required fields are missing or invalid.
+func TestCreateOrder_Failure(t *testing.T){
+	db, orderService, ctx := setupTestEnv(t)
+
+    created, err := orderService.CreateOrder(ctx, "", []*models.OrderLineItemInput{{Quantity: 2}}, nil, "", time.Now())
+
+	assert.Equal(t, apperr.CodeValidation, apperr.CodeOf(err))
+    assert.Nil(t, created, "order should not be created when userID or line items are invalid")
+
+	var count int64
+	require.NoError(t, db.Model(&models.Order{}).Count(&count).Error)
+	assert.Zero(t, count)
+}
+
+// TestCreateOrder_ZeroQuantity rejects lines for zero or fewer units.
+func TestCreateOrder_ZeroQuantity(t *testing.T){
+	db, orderService, ctx := setupTestEnv(t)
+	seedVariant(t, db, "p1", 2499, 10)
+
+	for _, quantity := range []int{0, -5} {
+		created, err := orderService.CreateOrder(ctx, "1", []*models.OrderLineItemInput{line("p1", quantity)}, nil, "", time.Now())
+
+		assert.Equal(t, apperr.CodeValidation, apperr.CodeOf(err), "should reject quantity %d", quantity)
+		assert.Nil(t, created)
+	}
+	assert.Equal(t, 10, stockOf(t, db, "p1").Inventory)
+}
+
+// TestCreateOrder_ReservesStock The order, its stock reservation and the
+// ledger entry are written together, and the last unit takes the variant
+// off sale.
+func TestCreateOrder_ReservesStock(t *testing.T) {
+	db, orderService, _ := setupTestEnv(t)
+
+	// --- Arrange ---
+	seedVariant(t, db, "p1", 2499, 3)
+
+	// --- Act ---
+	first := placeOrder(t, orderService, "1", line("p1", 2))
+	second := placeOrder(t, orderService, "1", line("p1", 1))
+
+	// --- Assert ---
+	stock := stockOf(t, db, "p1")
+	assert.Equal(t, 0, stock.Inventory)
+	assert.False(t, stock.Available, "a variant without stock should be off sale")
+
+	var product struct {
+		Inventory int
+		Available bool
+	}
+	require.NoError(t, db.Table("products").Select("inventory, available").Where("id = ?", "p1").Scan(&product).Error)
+	assert.Equal(t, 0, product.Inventory)
+	assert.False(t, product.Available)
+
+	entries := ledgerFor(t, db, "p1")
+	require.Len(t, entries, 3)
+	assert.Equal(t, inventory.KindReservation, entries[1].Kind)
+	assert.Equal(t, -2, entries[1].Change)
+	require.NotNil(t, entries[1].OrderID)
+	assert.Equal(t, first.ID, *entries[1].OrderID)
+	require.NotNil(t, entries[2].OrderID)
+	assert.Equal(t, second.ID, *entries[2].OrderID)
+}
+
+// TestCreateOrder_InsufficientStockRollsBack One short line rejects the
+// whole order and leaves every variant's stock as it was.
+func TestCreateOrder_InsufficientStockRollsBack(t *testing.T) {
+	db, orderService, ctx := setupTestEnv(t)
+
+	// --- Arrange --- p1 sorts first, so its reservation is made before p2 fails
+	seedVariant(t, db, "p1", 2499, 10)
+	seedVariant(t, db, "p2", 999, 1)
+
+	// --- Act ---
+	created, err := orderService.CreateOrder(ctx, "1", []*models.OrderLineItemInput{line("p1", 2), line("p2", 3)}, nil, "", time.Now())
+
+	// --- Assert ---
+	assert.ErrorIs(t, err, ErrInsufficientStock)
+	assert.Nil(t, created)
+
+	assert.Equal(t, 10, stockOf(t, db, "p1").Inventory)
+	assert.Equal(t, 1, stockOf(t, db, "p2").Inventory)
+	assert.Len(t, ledgerFor(t, db, "p1"), 1, "the p1 reservation should be rolled back")
+
+	var count int64
+	require.NoError(t, db.Model(&models.Order{}).Count(&count).Error)
+	assert.Zero(t, count)
+}
+
+// TestCreateOrder_Concurrent Orders racing for the last units never
+// oversell: exactly as many succeed as there is stock.
+func TestCreateOrder_Concurrent(t *testing.T) {
+	db, orderService, ctx := setupTestEnv(t)
+
+	// --- Arrange ---
+	const stock, buyers = 5, 12
+	seedVariant(t, db, "p1", 2499, stock)
+
+	// --- Act ---
+	errs := make([]error, buyers)
+	var wg sync.WaitGroup
+	for i := 0; i < buyers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = orderService.CreateOrder(ctx, fmt.Sprintf("user%d", i), []*models.OrderLineItemInput{line("p1", 1)}, nil, "", time.Now())
+		}(i)
+	}
+	wg.Wait()
+
+	// --- Assert ---
+	placed := 0
+	for _, err := range errs {
+		if err == nil {
+			placed++
+			continue
+		}
+		assert.Equal(t, apperr.CodeConflict, apperr.CodeOf(err), "late buyers should find the variant sold out: %v", err)
+	}
+	assert.Equal(t, stock, placed)
+	assert.Equal(t, 0, stockOf(t, db, "p1").Inventory)
+	assert.Len(t, ledgerFor(t, db, "p1"), 1+stock)
+}
+
+// TestGetOrdersByUserIDPage_Success confirms that the service correctly
+// retrieves all orders for a given user, with their line items.
+func TestGetOrdersByUserIDPage_Success(t *testing.T){
+	db, orderService, ctx := setupTestEnv(t)
+
+	// --- Arrange ---
+	seedVariant(t, db, "p1", 5999, 40)
+	seedVariant(t, db, "p2", 3899, 51)
+	widget := placeOrder(t, orderService, "1", line("p1", 1))
+	gadget := placeOrder(t, orderService, "1", line("p2", 1))
+	_, err := orderService.SetOrderStatus(ctx, models.SetOrderStatusInput{OrderID: gadget.ID, Status: models.OrderStatusPaid})
+	require.NoError(t, err)
+
+	// --- Act ---
+	orders := userOrders(t, orderService, "1")
+
+	// --- Assert ---
+	require.Len(t, orders, 2)
+	assert.Equal(t, widget.ID, orders[0].ID)
+	require.Len(t, orders[0].LineItems, 1)
+	assert.Equal(t, "p1", orders[0].LineItems[0].SKU)
+	assert.Equal(t, models.OrderStatusPending, orders[0].Status)
+
+	assert.Equal(t, gadget.ID, orders[1].ID)
+	require.Len(t, orders[1].LineItems, 1)
+	assert.Equal(t, "p2", orders[1].LineItems[0].SKU)
+	assert.Equal(t, models.OrderStatusPaid, orders[1].Status)
+}
+
+// TestGetOrdersByUserIDPage_NoOrders checks that a user without orders
+// gets an empty page rather than an error.
+func TestGetOrdersByUserIDPage_NoOrders(t *testing.T) {
+	_, orderService, _ := setupTestEnv(t)
+
+	orders := userOrders(t, orderService, "999")
+
+	assert.Empty(t, orders, "expected no orders for this user")
+}
+
+// TestGetOrdersByUserIDPage_MultipleUsers ensures that each user only sees
+// their own orders and cannot access other users' orders.
+func TestGetOrdersByUserIDPage_MultipleUsers(t *testing.T) {
+	db, orderService, _ := setupTestEnv(t)
+
+	// --- Arrange ---
+	seedVariant(t, db, "p1", 5999, 40)
+	order1 := placeOrder(t, orderService, "user1", line("p1", 1))
+	order2 := placeOrder(t, orderService, "user2", line("p1", 2))
+
+	// --- Act & Assert ---
+	user1Orders := userOrders(t, orderService, "user1")
+	require.Len(t, user1Orders, 1, "user1 should only see 1 order")
+	require.Equal(t, order1.ID, user1Orders[0].ID)
+
+	user2Orders := userOrders(t, orderService, "user2")
+	require.Len(t, user2Orders, 1, "user2 should only see 1 order")
+	require.Equal(t, order2.ID, user2Orders[0].ID)
+}
+
+// TestUpdateOrderStatus_Success verifies that the service moves an order
+// along the state machine and records the transition.
+func TestUpdateOrderStatus_Success(t *testing.T) { 
+	db, orderService, ctx := setupTestEnv(t)
+
+	// --- Arrange ---
+	seedVariant(t, db, "p1", 1000, 5)
+	order := placeOrder(t, orderService, "1", line("p1", 1))
+	newStatus := models.OrderStatusPaid
+
+	// --- Act ---
+	updated, err := orderService.UpdateOrder(ctx, &models.UpdateOrderInput{OrderID: order.ID, Status: &newStatus})
+
+	// --- Assert ---
+	require.NoError(t, err)
+	require.Equal(t, models.OrderStatusPaid, updated.Status)
+	assert.Nil(t, updated.ReservationExpiresAt, "only PENDING orders keep a reservation")
+	require.Len(t, updated.StatusHistory, 2)
+	assert.Equal(t, models.OrderStatusPaid, updated.StatusHistory[1].To)
+}
+
+// TestUpdateOrderStatus_Failure ensures that attempting to update a
+// non-existent order returns an error and does not modify any data.
+func TestUpdateOrderStatus_Failure(t *testing.T) { 
+	_, orderService, ctx := setupTestEnv(t)
+
+	newStatus := models.OrderStatusShipped
+	updated, err := orderService.UpdateOrder(ctx, &models.UpdateOrderInput{OrderID: "bad_id", Status: &newStatus})
+
+	assert.Equal(t, apperr.CodeNotFound, apperr.CodeOf(err))
+	assert.Nil(t, updated, "expected no order returned when update fails")
+}
+
+// TestUpdateOrderStatus_SameStatus An order cannot move to the status it
+// is already in.
+func TestUpdateOrderStatus_SameStatus(t *testing.T){
+	db, orderService, ctx := setupTestEnv(t)
+
+	// --- Arrange ---
+	seedVariant(t, db, "p1", 1000, 5)
+	order := placeOrder(t, orderService, "1", line("p1", 1))
+	sameStatus := models.OrderStatusPending
+
+	// --- Act ---
+	updated, err := orderService.UpdateOrder(ctx, &models.UpdateOrderInput{OrderID: order.ID, Status: &sameStatus})
+
+	// --- Assert ---
+	assert.Equal(t, apperr.CodeConflict, apperr.CodeOf(err))
+	assert.Nil(t, updated)
+	assert.Equal(t, 4, stockOf(t, db, "p1").Inventory, "the reservation should be untouched")
+}
+
+// TestDeleteOrder_Success validates that a deleted order releases its
+// stock and is only found with includeDeleted.
+func TestDeleteOrder_Success(t *testing.T) { 
+	db, orderService, ctx := setupTestEnv(t)
+
+	// --- Arrange ---
+	seedVariant(t, db, "p1", 1000, 2)
+	order := placeOrder(t, orderService, "1", line("p1", 2))
+	require.False(t, stockOf(t, db, "p1").Available)
+
+	// --- Act ---
+	deleted, err := orderService.DeleteOrder(ctx, models.DeleteOrderInput{OrderID: order.ID, UserID: "1"})
+
+	// --- Assert ---
+	require.NoError(t, err)
+	assert.True(t, deleted)
+
+	stock := stockOf(t, db, "p1")
+	assert.Equal(t, 2, stock.Inventory)
+	assert.True(t, stock.Available, "a sold out variant should be back on sale")
+
+	_, err = orderService.GetOrderByID(ctx, order.ID, false)
+	assert.Error(t, err, "deleted orders should be hidden")
+	_, err = orderService.GetOrderByID(ctx, order.ID, true)
+	assert.NoError(t, err)
+}
+
+// TestDeleteOrder_Failure checks that deleting an order with an invalid ID
+// returns an error and does not affect existing records.
+func TestDeleteOrder_Failure(t *testing.T) { 
+	_, orderService, ctx := setupTestEnv(t)
+
+	deleted, err := orderService.DeleteOrder(ctx, models.DeleteOrderInput{OrderID: "non-existent-id", UserID: "1"})
+
+	assert.False(t, deleted)
+	assert.Equal(t, apperr.CodeNotFound, apperr.CodeOf(err))
+}
+
+// TestDeleteOrder_Twice A deleted order cannot be deleted again, and its
+// stock is only released once.
+func TestDeleteOrder_Twice (t *testing.T){
+	db, orderService, ctx := setupTestEnv(t)
+
+	// -- Arrange --
+	seedVariant(t, db, "p1", 1000, 5)
+	order := placeOrder(t, orderService, "1", line("p1", 1))
+
+	// --- Act: First deletion (should succeed) ---
+	success, err := orderService.DeleteOrder(ctx, models.DeleteOrderInput{OrderID: order.ID, UserID: "1"})
+	require.NoError(t, err, "first deletion should succeed")
+	require.True(t, success, "first deletion should return true")
+
+	// --- Act: Second deletion (should fail gracefully) ---
+	success, err = orderService.DeleteOrder(ctx, models.DeleteOrderInput{OrderID: order.ID, UserID: "1"})
+
+	// --- Assert ---
+	assert.Equal(t, apperr.CodeNotFound, apperr.CodeOf(err))
+	assert.False(t, success, "second deletion should return false")
+	assert.Equal(t, 5, stockOf(t, db, "p1").Inventory)
+}
+
+// TestChangeOrderQuantity_Increase Raising a line's quantity reserves the
+// difference and reprices the order.
+func TestChangeOrderQuantity_Increase(t *testing.T) {
+	db, orderService, ctx := setupTestEnv(t)
+
+	// --- Arrange ---
+	seedVariant(t, db, "p1", 1000, 10)
+	seedVariant(t, db, "p2", 250, 10)
+	order := placeOrder(t, orderService, "1", line("p1", 1), line("p2", 2))
+
+	// --- Act ---
+	updated, err := orderService.ChangeOrderQuantity(ctx, models.ChangeOrderQuantityInput{OrderID: order.ID, SKU: strPtr("p1"), Quantity: 4})
+
+	// --- Assert ---
+	require.NoError(t, err)
+	assert.Equal(t, money.New(4500, "USD"), updated.TotalPrice)
+	assert.Equal(t, 6, stockOf(t, db, "p1").Inventory)
+	assert.Equal(t, 8, stockOf(t, db, "p2").Inventory)
+
+	entries := ledgerFor(t, db, "p1")
+	require.Len(t, entries, 3)
+	assert.Equal(t, inventory.KindReservation, entries[2].Kind)
+	assert.Equal(t, -3, entries[2].Change)
+}
+
+// TestChangeOrderQuantity_Decrease Lowering a line's quantity releases the
+// difference.
+func TestChangeOrderQuantity_Decrease(t *testing.T) {
+	db, orderService, ctx := setupTestEnv(t)
+
+	// --- Arrange ---
+	seedVariant(t, db, "p1", 1000, 10)
+	order := placeOrder(t, orderService, "1", line("p1", 5))
+
+	// --- Act --- a single line can be changed without naming it
+	updated, err := orderService.ChangeOrderQuantity(ctx, models.ChangeOrderQuantityInput{OrderID: order.ID, Quantity: 2})
+
+	// --- Assert ---
+	require.NoError(t, err)
+	assert.Equal(t, money.New(2000, "USD"), updated.TotalPrice)
+	assert.Equal(t, 2, updated.LineItems[0].Quantity)
+	assert.Equal(t, 8, stockOf(t, db, "p1").Inventory)
+
+	entries := ledgerFor(t, db, "p1")
+	require.Len(t, entries, 3)
+	assert.Equal(t, inventory.KindRelease, entries[2].Kind)
+	assert.Equal(t, 3, entries[2].Change)
+}
+
+// TestChangeOrderQuantity_Reprices Every line is repriced from current
+// variant prices, not just the one that changed.
+func TestChangeOrderQuantity_Reprices(t *testing.T) {
+	db, orderService, ctx := setupTestEnv(t)
+
+	// --- Arrange ---
+	seedVariant(t, db, "p1", 1000, 10)
+	seedVariant(t, db, "p2", 250, 10)
+	order := placeOrder(t, orderService, "1", line("p1", 1), line("p2", 2))
+	require.NoError(t, db.Exec(`UPDATE products SET price_amount = 300 WHERE id = 'p2'`).Error)
+	require.NoError(t, db.Exec(`UPDATE product_variants SET price_amount = 1200 WHERE sku = 'p1'`).Error)
+
+	// --- Act ---
+	updated, err := orderService.ChangeOrderQuantity(ctx, models.ChangeOrderQuantityInput{OrderID: order.ID, SKU: strPtr("p1"), Quantity: 2})
+
+	// --- Assert ---
+	require.NoError(t, err)
+	assert.Equal(t, money.New(3000, "USD"), updated.TotalPrice)
+	for _, l := range updated.LineItems {
+		switch l.SKU {
+		case "p1":
+			assert.Equal(t, money.New(1200, "USD"), l.UnitPrice)
+		case "p2":
+			assert.Equal(t, money.New(300, "USD"), l.UnitPrice)
+		}
+	}
+}
+
+// TestChangeOrderQuantity_InsufficientStock A raise beyond what is left
+// fails and leaves the order and stock alone.
+func TestChangeOrderQuantity_InsufficientStock(t *testing.T) {
+	db, orderService, ctx := setupTestEnv(t)
+
+	// --- Arrange ---
+	seedVariant(t, db, "p1", 1000, 3)
+	order := placeOrder(t, orderService, "1", line("p1", 1))
+
+	// --- Act ---
+	updated, err := orderService.ChangeOrderQuantity(ctx, models.ChangeOrderQuantityInput{OrderID: order.ID, Quantity: 5})
+
+	// --- Assert ---
+	assert.ErrorIs(t, err, ErrInsufficientStock)
+	assert.Nil(t, updated)
+	assert.Equal(t, 2, stockOf(t, db, "p1").Inventory)
+
+	current, err := orderService.GetOrderByID(ctx, order.ID, false)
+	require.NoError(t, err)
+	assert.Equal(t, 1, current.LineItems[0].Quantity)
+	assert.Equal(t, money.New(1000, "USD"), current.TotalPrice)
+}
+
+// TestChangeOrderQuantity_NotPending Paid and cancelled orders can no longer
+// be changed.
+func TestChangeOrderQuantity_NotPending(t *testing.T) {
+	db, orderService, ctx := setupTestEnv(t)
+
+	// --- Arrange ---
+	seedVariant(t, db, "p1", 1000, 10)
+	paid := placeOrder(t, orderService, "1", line("p1", 2))
+	_, err := orderService.SetOrderStatus(ctx, models.SetOrderStatusInput{OrderID: paid.ID, Status: models.OrderStatusPaid})
+	require.NoError(t, err)
+	cancelled := placeOrder(t, orderService, "1", line("p1", 2))
+	_, err = orderService.SetOrderStatus(ctx, models.SetOrderStatusInput{OrderID: cancelled.ID, Status: models.OrderStatusCancelled})
+	require.NoError(t, err)
+
+	for _, order := range []*models.Order{paid, cancelled} {
+		// --- Act ---
+		updated, err := orderService.ChangeOrderQuantity(ctx, models.ChangeOrderQuantityInput{OrderID: order.ID, Quantity: 1})
+
+		// --- Assert ---
+		assert.ErrorIs(t, err, ErrOrderNotModifiable)
+		assert.Nil(t, updated)
+	}
+	assert.Equal(t, 8, stockOf(t, db, "p1").Inventory, "only the paid order should still hold stock")
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/tagaertner/e-commerce-graphql/pkg/apperr"
+	"github.com/tagaertner/e-commerce-graphql/services/orders/models"
+)
+
+// orderTransitions lists the statuses each status may move to. CANCELLED,
+// REFUNDED and anything missing from the table are terminal.
+var orderTransitions = map[models.OrderStatus][]models.OrderStatus{
+	models.OrderStatusPending:   {models.OrderStatusPaid, models.OrderStatusCancelled},
+	models.OrderStatusPaid:      {models.OrderStatusShipped, models.OrderStatusCancelled, models.OrderStatusRefunded},
+	models.OrderStatusShipped:   {models.OrderStatusDelivered, models.OrderStatusRefunded},
+	models.OrderStatusDelivered: {models.OrderStatusRefunded},
+}
+
+// ErrOrderNotModifiable is returned when an order's contents are changed
+// after it has left PENDING, i.e. once it has been paid for or cancelled.
+var ErrOrderNotModifiable error = apperr.New(apperr.CodeConflict, "order can no longer be modified")
+
+// InvalidTransitionError is returned when an order is asked to move to a
+// status its current status does not allow.
+type InvalidTransitionError struct {
+	From models.OrderStatus
+	To   models.OrderStatus
+}
+
+func (e *InvalidTransitionError) Error() string {
+	return fmt.Sprintf("invalid order status transition: %s -> %s", e.From, e.To)
+}
+
+// InvalidStatusError is returned for a status value the state machine does
+// not know about.
+type InvalidStatusError struct {
+	Status models.OrderStatus
+}
+
+func (e *InvalidStatusError) Error() string {
+	return fmt.Sprintf("invalid order status: %q", e.Status)
+}
+
+// checkTransition validates a move from one status to another.
+func checkTransition(from, to models.OrderStatus) error {
+	if !validStatus(to) {
+		return apperr.Wrap(apperr.CodeValidation, &InvalidStatusError{Status: to})
+	}
+	for _, next := range orderTransitions[from] {
+		if next == to {
+			return nil
+		}
+	}
+	return apperr.Wrap(apperr.CodeConflict, &InvalidTransitionError{From: from, To: to})
+}
+
+// checkModifiable allows changes to an order's contents only while it is
+// PENDING. A paid order's price is settled, so changing it would release
+// stock without a refund.
+func checkModifiable(status models.OrderStatus) error {
+	if status != models.OrderStatusPending {
+		return fmt.Errorf("%w: order is %s", ErrOrderNotModifiable, status)
+	}
+	return nil
+}
+
+func validStatus(status models.OrderStatus) bool {
+	switch status {
+	case models.OrderStatusPending, models.OrderStatusPaid, models.OrderStatusShipped,
+		models.OrderStatusDelivered, models.OrderStatusCancelled, models.OrderStatusRefunded:
+		return true
+	}
+	return false
+}
+
+// systemActor is recorded for changes the service makes on its own, such as
+// cancelling orders whose reservation expired.
+const systemActor = "system"
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/tagaertner/e-commerce-graphql/services/orders/models"
+)
+
+// TestCheckTransition walks the order state machine, checking that legal
+// moves pass and illegal ones come back as typed errors.
+func TestCheckTransition(t *testing.T) {
+	tests := []struct {
+		from, to models.OrderStatus
+		allowed  bool
+	}{
+		{models.OrderStatusPending, models.OrderStatusPaid, true},
+		{models.OrderStatusPending, models.OrderStatusCancelled, true},
+		{models.OrderStatusPaid, models.OrderStatusShipped, true},
+		{models.OrderStatusShipped, models.OrderStatusDelivered, true},
+		{models.OrderStatusDelivered, models.OrderStatusRefunded, true},
+		{models.OrderStatusDelivered, models.OrderStatusPending, false},
+		{models.OrderStatusPending, models.OrderStatusShipped, false},
+		{models.OrderStatusCancelled, models.OrderStatusPaid, false},
+		{models.OrderStatusRefunded, models.OrderStatusDelivered, false},
+		{models.OrderStatusPaid, models.OrderStatusPaid, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.from)+"->"+string(tt.to), func(t *testing.T) {
+			err := checkTransition(tt.from, tt.to)
+			if tt.allowed {
+				if err != nil {
+					t.Fatalf("expected transition to be allowed, got %v", err)
+				}
+				return
+			}
+			var transitionErr *InvalidTransitionError
+			if !errors.As(err, &transitionErr) {
+				t.Fatalf("expected InvalidTransitionError, got %v", err)
+			}
+		})
+	}
+}
+
+// TestCheckTransition_UnknownStatus ensures unknown statuses are rejected
+// before the transition table is consulted.
+func TestCheckTransition_UnknownStatus(t *testing.T) {
+	var statusErr *InvalidStatusError
+	if err := checkTransition(models.OrderStatusPending, "LOST"); !errors.As(err, &statusErr) {
+		t.Fatalf("expected InvalidStatusError, got %v", err)
+	}
+}
+
+// TestCheckModifiable ensures only PENDING orders can be changed; a paid
+// order would otherwise be repriced and release stock without a refund.
+func TestCheckModifiable(t *testing.T) {
+	if err := checkModifiable(models.OrderStatusPending); err != nil {
+		t.Fatalf("expected PENDING to be modifiable, got %v", err)
+	}
+	for _, status := range []models.OrderStatus{
+		models.OrderStatusPaid, models.OrderStatusShipped, models.OrderStatusDelivered,
+		models.OrderStatusCancelled, models.OrderStatusRefunded,
+	} {
+		if err := checkModifiable(status); !errors.Is(err, ErrOrderNotModifiable) {
+			t.Errorf("checkModifiable(%s) = %v, want ErrOrderNotModifiable", status, err)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/tagaertner/e-commerce-graphql/pkg/apperr"
+	"github.com/tagaertner/e-commerce-graphql/pkg/money"
+	"github.com/tagaertner/e-commerce-graphql/services/orders/models"
+	"gorm.io/gorm"
+)
+
+// ErrTotalMismatch is returned when the total a client expects differs from
+// the total computed from current prices.
+var ErrTotalMismatch error = apperr.New(apperr.CodeConflict, "order total does not match current prices")
+
+// ErrMixedCurrencies is returned when an order's items are priced in more
+// than one currency.
+var ErrMixedCurrencies error = apperr.New(apperr.CodeValidation, "order items must all be priced in one currency")
+
+// variantState is what one unit of a variant costs right now, its product
+// and its stock.
+type variantState struct {
+	ProductID string
+	Price     money.Money
+	Inventory int
+	Available bool
+}
+
+// variantStates loads the current price and stock of each SKU. A variant's
+// price is its own if it has one, or else its product's. The products
+// service's tables are the source of truth for what an order costs. Unknown
+// SKUs, and variants of deleted products, are left out.
+func variantStates(tx *gorm.DB, skus []string) (map[string]variantState, error) {
+	var rows []struct {
+		SKU       string `gorm:"column:sku"`
+		ProductID string
+		Amount    int64
+		Currency  string
+		Inventory int
+		Available bool
+	}
+	if err := tx.Table("product_variants AS v").
+		Select("v.sku, v.product_id, COALESCE(v.price_amount, p.price_amount) AS amount, p.price_currency AS currency, v.inventory, v.available").
+		Joins("JOIN products p ON p.id = v.product_id AND p.deleted_at IS NULL").
+		Where("v.sku IN ?", skus).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	states := make(map[string]variantState, len(rows))
+	for _, r := range rows {
+		states[r.SKU] = variantState{
+			ProductID: r.ProductID,
+			Price:     money.New(r.Amount, r.Currency),
+			Inventory: r.Inventory,
+			Available: r.Available,
+		}
+	}
+	return states, nil
+}
+
+// variantPrices is variantStates for SKUs that must all exist.
+func variantPrices(tx *gorm.DB, skus []string) (map[string]variantState, error) {
+	prices, err := variantStates(tx, skus)
+	if err != nil {
+		return nil, err
+	}
+	for _, sku := range skus {
+		if _, ok := prices[sku]; !ok {
+			return nil, apperr.NotFound("variant %s not found", sku)
+		}
+	}
+	return prices, nil
+}
+
+// orderTotal sums the line totals of an order, which must all be in one
+// currency.
+func orderTotal(lines []models.OrderLineItem) (money.Money, error) {
+	totals := make([]money.Money, len(lines))
+	for i := range lines {
+		totals[i] = lines[i].LineTotal()
+	}
+	total, err := money.Sum(totals...)
+	if errors.Is(err, money.ErrCurrencyMismatch) {
+		return money.Money{}, fmt.Errorf("%w: %v", ErrMixedCurrencies, err)
+	}
+	return total, err
+}
+
+// checkExpectedTotal rejects a client-supplied total that differs from the
+// computed one in amount or currency.
+func checkExpectedTotal(expected *money.Money, computed money.Money) error {
+	if expected == nil {
+		return nil
+	}
+	if *expected != computed {
+		return fmt.Errorf("%w: expected %s, computed %s", ErrTotalMismatch, expected.Formatted(), computed.Formatted())
+	}
+	return nil
+}
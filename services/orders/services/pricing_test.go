@@ -0,0 +1,61 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/tagaertner/e-commerce-graphql/pkg/money"
+	"github.com/tagaertner/e-commerce-graphql/services/orders/models"
+)
+
+// TestCheckExpectedTotal verifies that client totals are only accepted
+// when they match the computed total exactly, currency included.
+func TestCheckExpectedTotal(t *testing.T) {
+	usd := func(amount int64) *money.Money { m := money.New(amount, "USD"); return &m }
+
+	tests := []struct {
+		name     string
+		expected *money.Money
+		computed money.Money
+		wantErr  bool
+	}{
+		{"no expectation", nil, money.New(5999, "USD"), false},
+		{"exact match", usd(5999), money.New(5999, "USD"), false},
+		{"client underpays", usd(1), money.New(5999, "USD"), true},
+		{"off by a cent", usd(5998), money.New(5999, "USD"), true},
+		{"other currency", &money.Money{Amount: 5999, Currency: "EUR"}, money.New(5999, "USD"), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkExpectedTotal(tt.expected, tt.computed)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("checkExpectedTotal(%v, %v) error = %v, wantErr %v", tt.expected, tt.computed, err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrTotalMismatch) {
+				t.Fatalf("expected ErrTotalMismatch, got %v", err)
+			}
+		})
+	}
+}
+
+// TestOrderTotal verifies that line totals are summed in minor units and
+// that orders mixing currencies are rejected.
+func TestOrderTotal(t *testing.T) {
+	lines := []models.OrderLineItem{
+		{SKU: "a", Quantity: 3, UnitPrice: money.New(10, "USD")},
+		{SKU: "b", Quantity: 2, UnitPrice: money.New(20, "USD")},
+	}
+	total, err := orderTotal(lines)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != money.New(70, "USD") {
+		t.Fatalf("orderTotal = %+v, want 70 USD", total)
+	}
+
+	lines[1].UnitPrice.Currency = "EUR"
+	if _, err := orderTotal(lines); !errors.Is(err, ErrMixedCurrencies) {
+		t.Fatalf("mixed currencies error = %v, want ErrMixedCurrencies", err)
+	}
+}
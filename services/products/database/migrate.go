@@ -1,57 +1,45 @@
 package database
 
-import(
-	"fmt"
+import (
+	"context"
+	"embed"
+	"io/fs"
 	"log"
 	"os"
-	 "github.com/tagaertner/e-commerce-graphql/services/products/models"
-	"gorm.io/driver/postgres"
+
+	"github.com/tagaertner/e-commerce-graphql/pkg/migrate"
 	"gorm.io/gorm"
-	"time"
 )
 
-func Connect() *gorm.DB{
-	// Try DATABASE_URL first for Render/production
-	dbURL := os.Getenv("DATABASE_URL")
-
-	// Fallback to individual vars for local dev
-	if dbURL == ""{
-		dbURL = fmt.Sprintf(
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
 
-		"host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
-		os.Getenv("DB_HOST"),
-		os.Getenv("POSTGRES_USER"),
-		os.Getenv("POSTGRES_PASSWORD"),
-		os.Getenv("POSTGRES_DB"),
-		os.Getenv("DB_PORT"),
-	)
-		log.Println("🔧 Using individual DB environment variables")
-	} else {
-		log.Println("🔗 Using DATABASE_URL connection string")
+// Migrator returns the migrator for the products schema.
+func Migrator(db *gorm.DB) (*migrate.Migrator, error) {
+	files, err := fs.Sub(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
 	}
+	return migrate.New(db, "products", files)
+}
 
-
-	maxRetries := 20
-	retryDelay := 3 * time.Second
-
-	for i := 0; i < maxRetries; i++ {
-		db, err := gorm.Open(postgres.Open(dbURL), &gorm.Config{})
-		if err == nil {
-			log.Println("✅ Connected to PostgreSQL successfully")
-			return db
-		}
-
-		log.Printf("❌ Database connection attempt %d/%d failed: %v", i+1, maxRetries, err)
-		if i < maxRetries-1 {
-			log.Printf("⏳ Retrying in %v...", retryDelay)
-			time.Sleep(retryDelay)
-		}
+// RunMigrations applies any pending migrations at startup.
+func RunMigrations(db *gorm.DB) {
+	m, err := Migrator(db)
+	if err != nil {
+		log.Fatalf("❌ Failed to load migrations: %v", err)
 	}
-
-	log.Fatalf("❌ Could not connect to database after %d retries", maxRetries)
-	return nil
+	if _, err := m.Up(context.Background()); err != nil {
+		log.Fatalf("❌ Migration failed: %v", err)
+	}
+	log.Println("✅ Migrations complete")
 }
 
-func RunMigrations(db *gorm.DB){
-	db.AutoMigrate(&models.Product{})
-}
\ No newline at end of file
+// Migrate runs the "migrate up|down|status" subcommand.
+func Migrate(db *gorm.DB, args []string) error {
+	m, err := Migrator(db)
+	if err != nil {
+		return err
+	}
+	return m.Run(context.Background(), args, os.Stdout)
+}
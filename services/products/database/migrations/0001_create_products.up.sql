@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS products (
+    id          text PRIMARY KEY,
+    name        text,
+    price       numeric,
+    description text,
+    inventory   bigint,
+    available   boolean
+);
@@ -0,0 +1,8 @@
+-- productsCursor can be sorted by price, name, inventory or creation time.
+-- Existing products all get the time of the migration.
+ALTER TABLE products ADD COLUMN IF NOT EXISTS created_at timestamptz NOT NULL DEFAULT now();
+
+CREATE INDEX IF NOT EXISTS idx_products_price_id ON products (price, id);
+CREATE INDEX IF NOT EXISTS idx_products_name_id ON products (name, id);
+CREATE INDEX IF NOT EXISTS idx_products_inventory_id ON products (inventory, id);
+CREATE INDEX IF NOT EXISTS idx_products_created_at_id ON products (created_at, id);
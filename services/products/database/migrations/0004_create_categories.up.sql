@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS categories (
+    id         text PRIMARY KEY,
+    name       text NOT NULL,
+    slug       text NOT NULL UNIQUE,
+    parent_id  text REFERENCES categories (id),
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_categories_parent_id ON categories (parent_id);
+
+CREATE TABLE IF NOT EXISTS product_categories (
+    product_id  text NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+    category_id text NOT NULL REFERENCES categories (id) ON DELETE CASCADE,
+    PRIMARY KEY (product_id, category_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_categories_category_id ON product_categories (category_id);
@@ -0,0 +1,22 @@
+-- Stock and price overrides live on variants. products.inventory and
+-- products.available are kept as the total and "any variant available" of
+-- a product's variants, so product listings can still sort and filter on them.
+CREATE TABLE IF NOT EXISTS product_variants (
+    id         text PRIMARY KEY,
+    product_id text    NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+    sku        text    NOT NULL UNIQUE,
+    options    jsonb   NOT NULL DEFAULT '[]',
+    price      numeric,
+    inventory  bigint  NOT NULL DEFAULT 0 CHECK (inventory >= 0),
+    available  boolean NOT NULL DEFAULT false,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_variants_product_id ON product_variants (product_id);
+
+-- Every existing product becomes a single variant whose SKU is the product
+-- ID, which is also how existing order lines are mapped to SKUs.
+INSERT INTO product_variants (id, product_id, sku, inventory, available)
+SELECT 'var_' || id, id, id, COALESCE(inventory, 0), COALESCE(available, false)
+FROM products
+ON CONFLICT DO NOTHING;
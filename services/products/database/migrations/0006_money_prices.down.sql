@@ -0,0 +1,9 @@
+ALTER TABLE product_variants ADD COLUMN IF NOT EXISTS price numeric;
+UPDATE product_variants SET price = price_amount / 100.0 WHERE price_amount IS NOT NULL;
+ALTER TABLE product_variants DROP COLUMN IF EXISTS price_amount;
+
+ALTER TABLE products ADD COLUMN IF NOT EXISTS price numeric;
+UPDATE products SET price = price_amount / 100.0;
+DROP INDEX IF EXISTS idx_products_price_amount_id;
+ALTER TABLE products DROP COLUMN IF EXISTS price_amount, DROP COLUMN IF EXISTS price_currency;
+CREATE INDEX IF NOT EXISTS idx_products_price_id ON products (price, id);
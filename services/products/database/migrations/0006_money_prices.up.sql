@@ -0,0 +1,17 @@
+-- Prices become integer minor units (cents for USD) plus an ISO 4217
+-- currency. Existing prices were all dollars.
+ALTER TABLE products
+    ADD COLUMN IF NOT EXISTS price_amount bigint,
+    ADD COLUMN IF NOT EXISTS price_currency text NOT NULL DEFAULT 'USD' CHECK (price_currency ~ '^[A-Z]{3}$');
+
+UPDATE products SET price_amount = round(COALESCE(price, 0) * 100) WHERE price_amount IS NULL;
+
+ALTER TABLE products ALTER COLUMN price_amount SET NOT NULL;
+ALTER TABLE products DROP COLUMN IF EXISTS price;
+
+CREATE INDEX IF NOT EXISTS idx_products_price_amount_id ON products (price_amount, id);
+
+-- Variant overrides are in the product's currency.
+ALTER TABLE product_variants ADD COLUMN IF NOT EXISTS price_amount bigint;
+UPDATE product_variants SET price_amount = round(price * 100) WHERE price IS NOT NULL;
+ALTER TABLE product_variants DROP COLUMN IF EXISTS price;
@@ -0,0 +1,26 @@
+-- Every change to a variant's stock is appended here in the same
+-- transaction that changes product_variants.inventory. change is the signed
+-- effect on inventory and balance the inventory right after it.
+CREATE TABLE IF NOT EXISTS inventory_ledger (
+    id         text PRIMARY KEY,
+    sku        text   NOT NULL,
+    product_id text   NOT NULL,
+    kind       text   NOT NULL CHECK (kind IN ('RESTOCK', 'RESERVATION', 'RELEASE', 'SALE', 'ADJUSTMENT')),
+    quantity   bigint NOT NULL CHECK (quantity > 0),
+    change     bigint NOT NULL,
+    balance    bigint NOT NULL CHECK (balance >= 0),
+    reason     text   NOT NULL DEFAULT '',
+    actor      text   NOT NULL,
+    order_id   text,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_inventory_ledger_sku_created_at ON inventory_ledger (sku, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_inventory_ledger_order_id ON inventory_ledger (order_id) WHERE order_id IS NOT NULL;
+
+-- Existing stock is carried over as an opening adjustment
+INSERT INTO inventory_ledger (id, sku, product_id, kind, quantity, change, balance, reason, actor)
+SELECT 'led_opening_' || id, sku, product_id, 'ADJUSTMENT', inventory, inventory, inventory, 'opening balance', 'system'
+FROM product_variants
+WHERE inventory > 0
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,4 @@
+-- version counts the writes made through product mutations. They update a
+-- product only at the version they read, so concurrent writers cannot
+-- overwrite each other.
+ALTER TABLE products ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
@@ -0,0 +1,5 @@
+-- Deleted products are kept, with deleted_at set, until they are purged
+-- after the retention window, so orders that reference them keep resolving.
+ALTER TABLE products ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+
+CREATE INDEX IF NOT EXISTS idx_products_deleted_at ON products (deleted_at);
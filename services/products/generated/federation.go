@@ -170,6 +170,25 @@ func (ec *executionContext) resolveEntity(
 				return nil, fmt.Errorf(`resolving Entity "Product": %w`, err)
 			}
 
+			return entity, nil
+		}
+	case "ProductVariant":
+		resolverName, err := entityResolverNameForProductVariant(ctx, rep)
+		if err != nil {
+			return nil, fmt.Errorf(`finding resolver for Entity "ProductVariant": %w`, err)
+		}
+		switch resolverName {
+
+		case "findProductVariantBySku":
+			id0, err := ec.unmarshalNString2string(ctx, rep["sku"])
+			if err != nil {
+				return nil, fmt.Errorf(`unmarshalling param 0 for findProductVariantBySku(): %w`, err)
+			}
+			entity, err := ec.resolvers.Entity().FindProductVariantBySku(ctx, id0)
+			if err != nil {
+				return nil, fmt.Errorf(`resolving Entity "ProductVariant": %w`, err)
+			}
+
 			return entity, nil
 		}
 
@@ -232,3 +251,38 @@ func entityResolverNameForProduct(ctx context.Context, rep EntityRepresentation)
 	return "", fmt.Errorf("%w for Product due to %v", ErrTypeNotFound,
 		errors.Join(entityResolverErrs...).Error())
 }
+
+func entityResolverNameForProductVariant(ctx context.Context, rep EntityRepresentation) (string, error) {
+	// we collect errors because a later entity resolver may work fine
+	// when an entity has multiple keys
+	entityResolverErrs := []error{}
+	for {
+		var (
+			m   EntityRepresentation
+			val any
+			ok  bool
+		)
+		_ = val
+		// if all of the KeyFields values for this resolver are null,
+		// we shouldn't use use it
+		allNull := true
+		m = rep
+		val, ok = m["sku"]
+		if !ok {
+			entityResolverErrs = append(entityResolverErrs,
+				fmt.Errorf("%w due to missing Key Field \"sku\" for ProductVariant", ErrTypeNotFound))
+			break
+		}
+		if allNull {
+			allNull = val == nil
+		}
+		if allNull {
+			entityResolverErrs = append(entityResolverErrs,
+				fmt.Errorf("%w due to all null value KeyFields for ProductVariant", ErrTypeNotFound))
+			break
+		}
+		return "findProductVariantBySku", nil
+	}
+	return "", fmt.Errorf("%w for ProductVariant due to %v", ErrTypeNotFound,
+		errors.Join(entityResolverErrs...).Error())
+}
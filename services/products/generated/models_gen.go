@@ -3,21 +3,42 @@
 package generated
 
 import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/tagaertner/e-commerce-graphql/pkg/inventory"
+	"github.com/tagaertner/e-commerce-graphql/pkg/pagination"
 	"github.com/tagaertner/e-commerce-graphql/services/products/models"
 )
 
-type Mutation struct {
+type AdjustVariantStockInput struct {
+	Sku string `json:"sku"`
+	// Units to add, or remove when negative.
+	Change int `json:"change"`
+	// Why stock is corrected, e.g. "damaged in storage".
+	Reason string `json:"reason"`
+}
+
+type InventoryLedgerConnection struct {
+	Edges      []*InventoryLedgerEdge `json:"edges"`
+	PageInfo   *pagination.PageInfo   `json:"pageInfo"`
+	TotalCount int                    `json:"totalCount"`
 }
 
-type PageInfo struct {
-	HasNextPage bool    `json:"hasNextPage"`
-	EndCursor   *string `json:"endCursor,omitempty"`
+type InventoryLedgerEdge struct {
+	Cursor string           `json:"cursor"`
+	Node   *inventory.Entry `json:"node"`
+}
+
+type Mutation struct {
 }
 
 type ProductConnection struct {
-	Edges      []*ProductEdge `json:"edges"`
-	PageInfo   *PageInfo      `json:"pageInfo"`
-	TotalCount int            `json:"totalCount"`
+	Edges      []*ProductEdge       `json:"edges"`
+	PageInfo   *pagination.PageInfo `json:"pageInfo"`
+	TotalCount int                  `json:"totalCount"`
 }
 
 type ProductEdge struct {
@@ -25,15 +46,150 @@ type ProductEdge struct {
 	Node   *models.Product `json:"node"`
 }
 
+type ProductOrderBy struct {
+	Field     ProductSortField `json:"field"`
+	Direction SortDirection    `json:"direction"`
+}
+
 type Query struct {
 }
 
 type RestockProductInput struct {
 	ID       string `json:"id"`
 	Quantity int    `json:"quantity"`
+	// Rejects the restock with a CONFLICT error unless the product is at this version.
+	ExpectedVersion *int `json:"expectedVersion,omitempty"`
+}
+
+type RestockVariantInput struct {
+	Sku      string `json:"sku"`
+	Quantity int    `json:"quantity"`
+	// Recorded in the inventory ledger.
+	Reason *string `json:"reason,omitempty"`
 }
 
 type SetProductAvailabilityInput struct {
 	ID        string `json:"id"`
 	Available bool   `json:"available"`
+	// Rejects the change with a CONFLICT error unless the product is at this version.
+	ExpectedVersion *int `json:"expectedVersion,omitempty"`
+}
+
+type SetVariantAvailabilityInput struct {
+	Sku       string `json:"sku"`
+	Available bool   `json:"available"`
+}
+
+type ProductSortField string
+
+const (
+	ProductSortFieldPrice     ProductSortField = "PRICE"
+	ProductSortFieldName      ProductSortField = "NAME"
+	ProductSortFieldInventory ProductSortField = "INVENTORY"
+	ProductSortFieldCreatedAt ProductSortField = "CREATED_AT"
+)
+
+var AllProductSortField = []ProductSortField{
+	ProductSortFieldPrice,
+	ProductSortFieldName,
+	ProductSortFieldInventory,
+	ProductSortFieldCreatedAt,
+}
+
+func (e ProductSortField) IsValid() bool {
+	switch e {
+	case ProductSortFieldPrice, ProductSortFieldName, ProductSortFieldInventory, ProductSortFieldCreatedAt:
+		return true
+	}
+	return false
+}
+
+func (e ProductSortField) String() string {
+	return string(e)
+}
+
+func (e *ProductSortField) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ProductSortField(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ProductSortField", str)
+	}
+	return nil
+}
+
+func (e ProductSortField) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *ProductSortField) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e ProductSortField) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type SortDirection string
+
+const (
+	SortDirectionAsc  SortDirection = "ASC"
+	SortDirectionDesc SortDirection = "DESC"
+)
+
+var AllSortDirection = []SortDirection{
+	SortDirectionAsc,
+	SortDirectionDesc,
+}
+
+func (e SortDirection) IsValid() bool {
+	switch e {
+	case SortDirectionAsc, SortDirectionDesc:
+		return true
+	}
+	return false
+}
+
+func (e SortDirection) String() string {
+	return string(e)
+}
+
+func (e *SortDirection) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = SortDirection(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid SortDirection", str)
+	}
+	return nil
+}
+
+func (e SortDirection) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *SortDirection) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e SortDirection) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
 }
@@ -0,0 +1,32 @@
+// Package loaders holds the request-scoped DataLoaders used to resolve
+// federated Product references and the category tree in batches.
+package loaders
+
+import (
+	"github.com/tagaertner/e-commerce-graphql/pkg/loader"
+	"github.com/tagaertner/e-commerce-graphql/services/products/models"
+	"github.com/tagaertner/e-commerce-graphql/services/products/services"
+)
+
+// Loaders are created per request, so cached results never outlive it.
+type Loaders struct {
+	ProductByID  *loader.Loader[string, *models.Product]
+	CategoryByID *loader.Loader[string, *models.Category]
+	// Category children and product categories; empty when there are none.
+	ChildCategories   *loader.Loader[string, []*models.Category]
+	ProductCategories *loader.Loader[string, []*models.Category]
+	VariantBySKU      *loader.Loader[string, *models.ProductVariant]
+	// Empty when a product has no variants.
+	ProductVariants *loader.Loader[string, []*models.ProductVariant]
+}
+
+func New(products *services.ProductService, categories *services.CategoryService, variants *services.VariantService) *Loaders {
+	return &Loaders{
+		ProductByID:       loader.New(products.GetProductsByIDs, loader.NotFound),
+		CategoryByID:      loader.New(categories.GetCategoriesByIDs, loader.NotFound),
+		ChildCategories:   loader.New(categories.GetChildrenByParentIDs, nil),
+		ProductCategories: loader.New(categories.GetCategoriesByProductIDs, nil),
+		VariantBySKU:      loader.New(variants.GetVariantsBySKUs, loader.NotFound),
+		ProductVariants:   loader.New(variants.GetVariantsByProductIDs, nil),
+	}
+}
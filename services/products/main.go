@@ -1,19 +1,27 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/99designs/gqlgen/graphql/handler"
 	"github.com/99designs/gqlgen/graphql/handler/extension"
 	"github.com/99designs/gqlgen/graphql/handler/transport"
 	"github.com/99designs/gqlgen/graphql/playground"
+	dbconn "github.com/tagaertner/e-commerce-graphql/pkg/db"
+	"github.com/tagaertner/e-commerce-graphql/pkg/apperr"
+	"github.com/tagaertner/e-commerce-graphql/pkg/auth"
+	"github.com/tagaertner/e-commerce-graphql/pkg/loader"
+	"github.com/tagaertner/e-commerce-graphql/pkg/validate"
 	"github.com/joho/godotenv"
 	"github.com/tagaertner/e-commerce-graphql/services/products/database"
 	"github.com/tagaertner/e-commerce-graphql/services/products/generated"
+	"github.com/tagaertner/e-commerce-graphql/services/products/loaders"
 	"github.com/tagaertner/e-commerce-graphql/services/products/resolvers"
     "github.com/tagaertner/e-commerce-graphql/services/products/services"
 )
@@ -38,7 +46,7 @@ func main() {
 	flag.Parse()
 
 	// Connect to the database
-	db := database.Connect()
+	db := dbconn.MustOpen(dbconn.ConfigFromEnv())
 
 	if *testDB {
 		sqlDB, err := db.DB()
@@ -53,6 +61,14 @@ func main() {
 		return // exit after test
 	}
 
+    // "migrate up|down|status" manages the schema and exits
+    if flag.Arg(0) == "migrate" {
+        if err := database.Migrate(db, flag.Args()[1:]); err != nil {
+            log.Fatalf("❌ migrate: %v", err)
+        }
+        return
+    }
+
     database.RunMigrations(db)
 
 
@@ -62,16 +78,34 @@ func main() {
     }
 
   
+    // Access tokens are verified offline against the users service's public keys
+    jwksURL := os.Getenv("AUTH_JWKS_URL")
+    if jwksURL == "" {
+        jwksURL = "http://users:4002/.well-known/jwks.json"
+    }
+    issuer := os.Getenv("JWT_ISSUER")
+    if issuer == "" {
+        issuer = "e-commerce-users"
+    }
+    verifier := auth.NewJWKSVerifier(jwksURL, issuer)
+
     // Creates Product services with data
     productService := services.NewProductService(db)
+    categoryService := services.NewCategoryService(db)
+    variantService := services.NewVariantService(db)
+
+    go purgeDeletedProducts(productService, durationEnv("DELETED_RETENTION", 30*24*time.Hour), time.Hour)
 
     resolver := &resolvers.Resolver{
-        ProductService: productService,
+        ProductService:  productService,
+        CategoryService: categoryService,
+        VariantService:  variantService,
     }
 
 	srv := handler.New(generated.NewExecutableSchema(
 		generated.Config{
-			Resolvers: resolver,
+			Resolvers:  resolver,
+			Directives: generated.DirectiveRoot{Auth: auth.Directive, Constraint: validate.Constraint},
 		},
         ),
     )
@@ -79,6 +113,14 @@ func main() {
     // Just enable introspection (this is what you actually need)
     srv.Use(extension.Introspection{})
 
+    // Errors carry extensions.code, and panics become INTERNAL errors
+    srv.SetErrorPresenter(apperr.Presenter)
+    srv.SetRecoverFunc(apperr.Recover)
+
+    // @constraint violations are gathered per field and reported together
+    srv.AroundRootFields(validate.Collect)
+    srv.AroundFields(validate.Enforce)
+
     // Add supported transport methods for GraphQL requests:
 	// - POST and GET for queries/mutations
 	// - WebSocket transport enables live data features like subscriptions
@@ -87,7 +129,8 @@ func main() {
     srv.AddTransport(transport.Websocket{}) 
 
     http.Handle("/", playground.Handler("GraphQL playground", "/query"))
-    http.Handle("/query", srv)
+    newLoaders := func() *loaders.Loaders { return loaders.New(productService, categoryService, variantService) }
+    http.Handle("/query", apperr.Middleware(auth.Middleware(verifier)(loader.Middleware(newLoaders)(srv))))
 
     // Health check
     http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
@@ -100,3 +143,30 @@ func main() {
     log.Fatal(http.ListenAndServe("0.0.0.0:"+port, nil))
 }
 
+// purgeDeletedProducts permanently removes products deleted longer than
+// retention ago every interval. Until then they can be restored.
+func purgeDeletedProducts(products *services.ProductService, retention, interval time.Duration) {
+    for range time.Tick(interval) {
+        n, err := products.PurgeDeleted(context.Background(), time.Now().Add(-retention))
+        if err != nil {
+            log.Printf("⚠️  Failed to purge deleted products: %v", err)
+            continue
+        }
+        if n > 0 {
+            log.Printf("🗑️  Purged %d deleted products", n)
+        }
+    }
+}
+
+// durationEnv reads a duration such as "720h" from the environment.
+func durationEnv(name string, fallback time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("❌ Invalid %s %q: %v", name, value, err)
+	}
+	return d
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Category is a node in the product taxonomy. Root categories have no
+// parent.
+type Category struct {
+	ID        string    `json:"id" gorm:"primarykey"`
+	Name      string    `json:"name"`
+	Slug      string    `json:"slug"`
+	ParentID  *string   `json:"parentId"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type CreateCategoryInput struct {
+	Name     string  `json:"name"`
+	Slug     *string `json:"slug"`
+	ParentID *string `json:"parentId"`
+}
+
+// UpdateCategoryInput changes a category. An empty parentId moves the
+// category to the root.
+type UpdateCategoryInput struct {
+	Name     *string `json:"name"`
+	Slug     *string `json:"slug"`
+	ParentID *string `json:"parentId"`
+}
@@ -1,28 +1,49 @@
 package models
 
+import (
+	"time"
 
+	"github.com/tagaertner/e-commerce-graphql/pkg/money"
+	"gorm.io/gorm"
+)
 
 type Product struct {
 	 ID         string  `json:"id" gorm:"primarykey"` 
 	Name        string  `json:"name"`
-	Price       float64 `json:"price"`
+	Price       money.Money `json:"price" gorm:"embedded;embeddedPrefix:price_"`
 	Description *string `json:"description"`
 	Inventory   int     `json:"inventory"`
 	Available   bool    `json:"available"`
+	CreatedAt   time.Time `json:"createdAt"`
+	// Version counts the writes made through product mutations. Stock that
+	// orders and variant mutations move does not change it.
+	Version     int     `json:"version" gorm:"not null;default:1"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
 type CreateProductInput struct {
 	Name        string   `json:"name"`
-	Price       float64  `json:"price"`
+	Price       money.Money `json:"price"`
 	Description *string  `json:"description"`
 	Inventory   int      `json:"inventory"`
+	SKU         *string  `json:"sku"`
 }
 
 type UpdateProductInput struct {
 	Name        *string  `json:"name"`
-	Price       *float64 `json:"price"`
+	Price       *money.Money `json:"price"`
 	Description *string  `json:"description"`
 	Inventory   *int     `json:"inventory"`
+	ExpectedVersion *int `json:"expectedVersion"`
+}
+
+// ProductFilter narrows searchProducts. Nil fields don't filter.
+type ProductFilter struct {
+	MinPrice     *money.Money `json:"minPrice"`
+	MaxPrice     *money.Money `json:"maxPrice"`
+	Available    *bool    `json:"available"`
+	MinInventory *int     `json:"minInventory"`
+	CategoryID   *string  `json:"categoryId"`
 }
 
 type DeleteProductInput struct {
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/tagaertner/e-commerce-graphql/pkg/money"
+)
+
+// ProductVariant is a sellable version of a product, e.g. one size and
+// colour of a T-shirt. Stock is kept per variant; PriceOverride replaces the
+// product's price when set, in minor units of the product's currency.
+type ProductVariant struct {
+	ID            string         `json:"id" gorm:"primarykey"`
+	ProductID     string         `json:"productId"`
+	SKU           string         `json:"sku" gorm:"column:sku"`
+	Options       VariantOptions `json:"options"`
+	PriceOverride *int64         `json:"priceOverride" gorm:"column:price_amount"`
+	Inventory     int            `json:"inventory"`
+	Available     bool           `json:"available"`
+	CreatedAt     time.Time      `json:"createdAt"`
+}
+
+func (ProductVariant) IsEntity() {}
+
+// VariantOption is one option value of a variant, e.g. size M.
+type VariantOption struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// VariantOptions is stored as a JSON array.
+type VariantOptions []*VariantOption
+
+func (o VariantOptions) Value() (driver.Value, error) {
+	if o == nil {
+		o = VariantOptions{}
+	}
+	b, err := json.Marshal(o)
+	return string(b), err
+}
+
+func (o *VariantOptions) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, o)
+	case string:
+		return json.Unmarshal([]byte(v), o)
+	case nil:
+		*o = nil
+		return nil
+	}
+	return errors.New("unsupported type for variant options")
+}
+
+type CreateProductVariantInput struct {
+	ProductID string           `json:"productId"`
+	SKU       string           `json:"sku"`
+	Options   []*VariantOption `json:"options"`
+	Price     *money.Money     `json:"price"`
+	Inventory int              `json:"inventory"`
+}
+
+type UpdateProductVariantInput struct {
+	Options   []*VariantOption `json:"options"`
+	Price     *money.Money     `json:"price"`
+	Inventory *int             `json:"inventory"`
+}
@@ -0,0 +1,17 @@
+package resolvers
+
+import (
+	"github.com/tagaertner/e-commerce-graphql/services/products/models"
+)
+
+func ToGraphQLCategory(c *models.Category) *models.Category {
+	return c
+}
+
+func ToGraphQLCategoryList(categories []*models.Category) []*models.Category {
+	gqlCategories := make([]*models.Category, 0, len(categories))
+	for _, c := range categories {
+		gqlCategories = append(gqlCategories, ToGraphQLCategory(c))
+	}
+	return gqlCategories
+}
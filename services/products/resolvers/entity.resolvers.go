@@ -8,19 +8,30 @@ package resolvers
 import (
 	"context"
 
+	"github.com/tagaertner/e-commerce-graphql/pkg/loader"
 	"github.com/tagaertner/e-commerce-graphql/services/products/generated"
+	"github.com/tagaertner/e-commerce-graphql/services/products/loaders"
 	"github.com/tagaertner/e-commerce-graphql/services/products/models"
 )
 
 // FindProductByID is the resolver for the findProductByID field.
 func (r *entityResolver) FindProductByID(ctx context.Context, id string) (*models.Product, error) {
-	product, err := r.ProductService.GetProductByID(id)
+	product, err := loader.For[*loaders.Loaders](ctx).ProductByID.Load(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 	return ToGraphQLProduct(product), nil
 }
 
+// FindProductVariantBySku is the resolver for the findProductVariantBySku field.
+func (r *entityResolver) FindProductVariantBySku(ctx context.Context, sku string) (*models.ProductVariant, error) {
+	variant, err := loader.For[*loaders.Loaders](ctx).VariantBySKU.Load(ctx, sku)
+	if err != nil {
+		return nil, err
+	}
+	return ToGraphQLVariant(variant), nil
+}
+
 // Entity returns generated.EntityResolver implementation.
 func (r *Resolver) Entity() generated.EntityResolver { return &entityResolver{r} }
 
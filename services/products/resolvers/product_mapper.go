@@ -1,8 +1,10 @@
 package resolvers
 
 import (
-	// "github.com/tagaertner/e-commerce-graphql/services/products/generated"
+	"github.com/tagaertner/e-commerce-graphql/pkg/pagination"
+	"github.com/tagaertner/e-commerce-graphql/services/products/generated"
 	"github.com/tagaertner/e-commerce-graphql/services/products/models"
+	"github.com/tagaertner/e-commerce-graphql/services/products/services"
 )
 
 func ToGraphQLProduct(p *models.Product) *models.Product {
@@ -16,3 +18,21 @@ func ToGraphQLProductList(products []*models.Product) []*models.Product {
 	}
 	return gqlProducts
 }
+
+func ToGraphQLProductConnection(page *services.ProductPage) *generated.ProductConnection {
+	edges := make([]*generated.ProductEdge, len(page.Products))
+	for i, p := range page.Products {
+		edges[i] = &generated.ProductEdge{Cursor: page.Cursors[i], Node: ToGraphQLProduct(p)}
+	}
+	pageInfo := page.PageInfo
+	return &generated.ProductConnection{Edges: edges, PageInfo: &pageInfo, TotalCount: page.TotalCount}
+}
+
+// ToProductSort maps a ProductOrderBy input to its sort, or returns fallback
+// when orderBy is nil.
+func ToProductSort(orderBy *generated.ProductOrderBy, fallback pagination.Sort) (pagination.Sort, error) {
+	if orderBy == nil {
+		return fallback, nil
+	}
+	return services.ProductSort(string(orderBy.Field), orderBy.Direction == generated.SortDirectionDesc)
+}
@@ -6,11 +6,15 @@ import (
 )
 
 type Resolver struct {
-	ProductService *services.ProductService
+	ProductService  *services.ProductService
+	CategoryService *services.CategoryService
+	VariantService  *services.VariantService
 }
 
 func NewResolver(db *gorm.DB) *Resolver {
 	return &Resolver{
-		ProductService: services.NewProductService(db),
+		ProductService:  services.NewProductService(db),
+		CategoryService: services.NewCategoryService(db),
+		VariantService:  services.NewVariantService(db),
 	}
 }
\ No newline at end of file
@@ -7,16 +7,47 @@ package resolvers
 
 import (
 	"context"
-	"fmt"
+	"strings"
+	"time"
 
+	"github.com/tagaertner/e-commerce-graphql/pkg/auth"
+	"github.com/tagaertner/e-commerce-graphql/pkg/loader"
+	"github.com/tagaertner/e-commerce-graphql/pkg/money"
+	"github.com/tagaertner/e-commerce-graphql/pkg/pagination"
 	"github.com/tagaertner/e-commerce-graphql/services/products/generated"
+	"github.com/tagaertner/e-commerce-graphql/services/products/loaders"
 	"github.com/tagaertner/e-commerce-graphql/services/products/models"
 	"github.com/tagaertner/e-commerce-graphql/services/products/services"
 )
 
+// Parent is the resolver for the parent field.
+func (r *categoryResolver) Parent(ctx context.Context, obj *models.Category) (*models.Category, error) {
+	if obj.ParentID == nil {
+		return nil, nil
+	}
+	parent, err := loader.For[*loaders.Loaders](ctx).CategoryByID.Load(ctx, *obj.ParentID)
+	if err != nil {
+		return nil, err
+	}
+	return ToGraphQLCategory(parent), nil
+}
+
+// Children is the resolver for the children field.
+func (r *categoryResolver) Children(ctx context.Context, obj *models.Category) ([]*models.Category, error) {
+	children, err := loader.For[*loaders.Loaders](ctx).ChildCategories.Load(ctx, obj.ID)
+	if err != nil {
+		return nil, err
+	}
+	return ToGraphQLCategoryList(children), nil
+}
+
 // CreateProduct is the resolver for the createProduct field.
 func (r *mutationResolver) CreateProduct(ctx context.Context, input models.CreateProductInput) (*models.Product, error) {
-	product, err := r.ProductService.CreateProduct(ctx, input.Name, input.Price, *input.Description, input.Inventory)
+	var sku string
+	if input.SKU != nil {
+		sku = *input.SKU
+	}
+	product, err := r.ProductService.CreateProduct(ctx, input.Name, input.Price, *input.Description, input.Inventory, sku)
 	if err != nil {
 		return nil, err
 	}
@@ -37,9 +68,18 @@ func (r *mutationResolver) DeleteProduct(ctx context.Context, input models.Delet
 	return r.ProductService.DeleteProduct(ctx, input)
 }
 
+// RestoreProduct is the resolver for the restoreProduct field.
+func (r *mutationResolver) RestoreProduct(ctx context.Context, id string) (*models.Product, error) {
+	product, err := r.ProductService.RestoreProduct(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return ToGraphQLProduct(product), nil
+}
+
 // RestockProduct is the resolver for the restockProduct field.
 func (r *mutationResolver) RestockProduct(ctx context.Context, input generated.RestockProductInput) (*models.Product, error) {
-	updatedProduct, err := r.ProductService.RestockProduct(ctx, input.ID, input.Quantity)
+	updatedProduct, err := r.ProductService.RestockProduct(ctx, input.ID, input.Quantity, input.ExpectedVersion)
 	if err != nil {
 		return nil, err
 	}
@@ -48,16 +88,170 @@ func (r *mutationResolver) RestockProduct(ctx context.Context, input generated.R
 
 // SetProductAvailability is the resolver for the setProductAvailability field.
 func (r *mutationResolver) SetProductAvailability(ctx context.Context, input generated.SetProductAvailabilityInput) (*models.Product, error) {
-	product, err := r.ProductService.SetProductAvailability(ctx, input.ID, input.Available)
+	product, err := r.ProductService.SetProductAvailability(ctx, input.ID, input.Available, input.ExpectedVersion)
+	if err != nil {
+		return nil, err
+	}
+	return ToGraphQLProduct(product), nil
+}
+
+// CreateProductVariant is the resolver for the createProductVariant field.
+func (r *mutationResolver) CreateProductVariant(ctx context.Context, input models.CreateProductVariantInput) (*models.ProductVariant, error) {
+	variant, err := r.VariantService.CreateVariant(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	return ToGraphQLVariant(variant), nil
+}
+
+// UpdateProductVariant is the resolver for the updateProductVariant field.
+func (r *mutationResolver) UpdateProductVariant(ctx context.Context, id string, input models.UpdateProductVariantInput) (*models.ProductVariant, error) {
+	variant, err := r.VariantService.UpdateVariant(ctx, id, input)
+	if err != nil {
+		return nil, err
+	}
+	return ToGraphQLVariant(variant), nil
+}
+
+// DeleteProductVariant is the resolver for the deleteProductVariant field.
+func (r *mutationResolver) DeleteProductVariant(ctx context.Context, id string) (bool, error) {
+	return r.VariantService.DeleteVariant(ctx, id)
+}
+
+// RestockVariant is the resolver for the restockVariant field.
+func (r *mutationResolver) RestockVariant(ctx context.Context, input generated.RestockVariantInput) (*models.ProductVariant, error) {
+	var reason string
+	if input.Reason != nil {
+		reason = *input.Reason
+	}
+	variant, err := r.VariantService.RestockVariant(ctx, input.Sku, input.Quantity, reason)
+	if err != nil {
+		return nil, err
+	}
+	return ToGraphQLVariant(variant), nil
+}
+
+// AdjustVariantStock is the resolver for the adjustVariantStock field.
+func (r *mutationResolver) AdjustVariantStock(ctx context.Context, input generated.AdjustVariantStockInput) (*models.ProductVariant, error) {
+	variant, err := r.VariantService.AdjustStock(ctx, input.Sku, input.Change, input.Reason)
+	if err != nil {
+		return nil, err
+	}
+	return ToGraphQLVariant(variant), nil
+}
+
+// SetVariantAvailability is the resolver for the setVariantAvailability field.
+func (r *mutationResolver) SetVariantAvailability(ctx context.Context, input generated.SetVariantAvailabilityInput) (*models.ProductVariant, error) {
+	variant, err := r.VariantService.SetVariantAvailability(ctx, input.Sku, input.Available)
+	if err != nil {
+		return nil, err
+	}
+	return ToGraphQLVariant(variant), nil
+}
+
+// CreateCategory is the resolver for the createCategory field.
+func (r *mutationResolver) CreateCategory(ctx context.Context, input models.CreateCategoryInput) (*models.Category, error) {
+	category, err := r.CategoryService.CreateCategory(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	return ToGraphQLCategory(category), nil
+}
+
+// UpdateCategory is the resolver for the updateCategory field.
+func (r *mutationResolver) UpdateCategory(ctx context.Context, id string, input models.UpdateCategoryInput) (*models.Category, error) {
+	category, err := r.CategoryService.UpdateCategory(ctx, id, input)
+	if err != nil {
+		return nil, err
+	}
+	return ToGraphQLCategory(category), nil
+}
+
+// DeleteCategory is the resolver for the deleteCategory field.
+func (r *mutationResolver) DeleteCategory(ctx context.Context, id string) (bool, error) {
+	return r.CategoryService.DeleteCategory(ctx, id)
+}
+
+// SetProductCategories is the resolver for the setProductCategories field.
+func (r *mutationResolver) SetProductCategories(ctx context.Context, productID string, categoryIds []string) (*models.Product, error) {
+	product, err := r.CategoryService.SetProductCategories(ctx, productID, categoryIds)
+	if err != nil {
+		return nil, err
+	}
+	return ToGraphQLProduct(product), nil
+}
+
+// Categories is the resolver for the categories field.
+func (r *productResolver) Categories(ctx context.Context, obj *models.Product) ([]*models.Category, error) {
+	categories, err := loader.For[*loaders.Loaders](ctx).ProductCategories.Load(ctx, obj.ID)
+	if err != nil {
+		return nil, err
+	}
+	return ToGraphQLCategoryList(categories), nil
+}
+
+// Variants is the resolver for the variants field.
+func (r *productResolver) Variants(ctx context.Context, obj *models.Product) ([]*models.ProductVariant, error) {
+	variants, err := loader.For[*loaders.Loaders](ctx).ProductVariants.Load(ctx, obj.ID)
+	if err != nil {
+		return nil, err
+	}
+	return ToGraphQLVariantList(variants), nil
+}
+
+// DeletedAt is the resolver for the deletedAt field.
+func (r *productResolver) DeletedAt(ctx context.Context, obj *models.Product) (*time.Time, error) {
+	if !obj.DeletedAt.Valid {
+		return nil, nil
+	}
+	return &obj.DeletedAt.Time, nil
+}
+
+// Product is the resolver for the product field.
+func (r *productVariantResolver) Product(ctx context.Context, obj *models.ProductVariant) (*models.Product, error) {
+	product, err := loader.For[*loaders.Loaders](ctx).ProductByID.Load(ctx, obj.ProductID)
 	if err != nil {
 		return nil, err
 	}
 	return ToGraphQLProduct(product), nil
 }
 
+// Options is the resolver for the options field.
+func (r *productVariantResolver) Options(ctx context.Context, obj *models.ProductVariant) ([]*models.VariantOption, error) {
+	return obj.Options, nil
+}
+
+// Price is the resolver for the price field.
+func (r *productVariantResolver) Price(ctx context.Context, obj *models.ProductVariant) (*money.Money, error) {
+	product, err := loader.For[*loaders.Loaders](ctx).ProductByID.Load(ctx, obj.ProductID)
+	if err != nil {
+		return nil, err
+	}
+	if obj.PriceOverride != nil {
+		return &money.Money{Amount: *obj.PriceOverride, Currency: product.Price.Currency}, nil
+	}
+	return &product.Price, nil
+}
+
+// PriceOverride is the resolver for the priceOverride field.
+func (r *productVariantResolver) PriceOverride(ctx context.Context, obj *models.ProductVariant) (*money.Money, error) {
+	if obj.PriceOverride == nil {
+		return nil, nil
+	}
+	product, err := loader.For[*loaders.Loaders](ctx).ProductByID.Load(ctx, obj.ProductID)
+	if err != nil {
+		return nil, err
+	}
+	return &money.Money{Amount: *obj.PriceOverride, Currency: product.Price.Currency}, nil
+}
+
 // Products is the resolver for the products field.
-func (r *queryResolver) Product(ctx context.Context, id string) (*models.Product, error) {
-	product, err := r.ProductService.GetProductByID(id)
+func (r *queryResolver) Product(ctx context.Context, id string, includeDeleted *bool) (*models.Product, error) {
+	withDeleted, err := auth.AdminFlag(ctx, includeDeleted)
+	if err != nil {
+		return nil, err
+	}
+	product, err := r.ProductService.GetProductByID(ctx, id, withDeleted)
 	if err != nil {
 		return nil, err
 	}
@@ -73,59 +267,128 @@ func (r *queryResolver) Products(ctx context.Context) ([]*models.Product, error)
 	return ToGraphQLProductList(products), nil
 }
 
+// Categories is the resolver for the categories field.
+func (r *queryResolver) Categories(ctx context.Context) ([]*models.Category, error) {
+	categories, err := r.CategoryService.GetRootCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ToGraphQLCategoryList(categories), nil
+}
+
+// Category is the resolver for the category field.
+func (r *queryResolver) Category(ctx context.Context, id string) (*models.Category, error) {
+	category, err := r.CategoryService.GetCategoryByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return ToGraphQLCategory(category), nil
+}
+
+// Variant is the resolver for the variant field.
+func (r *queryResolver) Variant(ctx context.Context, sku string) (*models.ProductVariant, error) {
+	variant, err := r.VariantService.GetVariantBySKU(ctx, sku)
+	if err != nil {
+		return nil, err
+	}
+	return ToGraphQLVariant(variant), nil
+}
+
 // ProductsCursor is the resolver for the productsCursor field.
-func (r *queryResolver) ProductsCursor(ctx context.Context, after *string, first *int) (*generated.ProductConnection, error) {
-	// set default
-	f := 10
-	if first != nil {
-		f = *first
+func (r *queryResolver) ProductsCursor(ctx context.Context, first *int, after *string, last *int, before *string, orderBy *generated.ProductOrderBy, categoryID *string, includeDeleted *bool) (*generated.ProductConnection, error) {
+	withDeleted, err := auth.AdminFlag(ctx, includeDeleted)
+	if err != nil {
+		return nil, err
+	}
+
+	sort, err := ToProductSort(orderBy, pagination.ByID)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := pagination.Args{First: first, After: after, Last: last, Before: before}.Page(sort)
+	if err != nil {
+		return nil, err
+	}
+
+	var category string
+	if categoryID != nil {
+		category = *categoryID
+	}
+
+	products, err := r.ProductService.GetAllProductsCursor(ctx, category, page, withDeleted)
+	if err != nil {
+		return nil, err
+	}
+	return ToGraphQLProductConnection(products), nil
+}
+
+// SearchProducts is the resolver for the searchProducts field.
+func (r *queryResolver) SearchProducts(ctx context.Context, filter *models.ProductFilter, query *string, first *int, after *string, last *int, before *string, orderBy *generated.ProductOrderBy, includeDeleted *bool) (*generated.ProductConnection, error) {
+	withDeleted, err := auth.AdminFlag(ctx, includeDeleted)
+	if err != nil {
+		return nil, err
+	}
+
+	var q string
+	if query != nil {
+		q = *query
 	}
 
-	// Validate first
-	if f <= 0 || f > 100 {
-		return nil, fmt.Errorf("first must be between 1 - 100")
+	fallback := pagination.ByID
+	if strings.TrimSpace(q) != "" {
+		fallback = services.RelevanceSort
 	}
-	// Get products from service
-	products, hasNextPage, err := r.ProductService.GetAllProductsCursor(ctx, after, f)
+	sort, err := ToProductSort(orderBy, fallback)
 	if err != nil {
 		return nil, err
 	}
 
-	//Build edges
-	edges := make([]*generated.ProductEdge, len(products))
-	for i, product := range products {
-		edges[i] = &generated.ProductEdge{
-			Cursor: services.EncodeCursor(product),
-			Node:   ToGraphQLProduct(product),
-		}
+	page, err := pagination.Args{First: first, After: after, Last: last, Before: before}.Page(sort)
+	if err != nil {
+		return nil, err
 	}
-	var endCursor *string
-	if len(edges) > 0 {
-		cursor := edges[len(edges)-1].Cursor
-		endCursor = &cursor
+
+	products, err := r.ProductService.SearchProducts(ctx, filter, q, page, withDeleted)
+	if err != nil {
+		return nil, err
 	}
+	return ToGraphQLProductConnection(products), nil
+}
 
-	totalCount, err := r.ProductService.CountProducts(ctx)
+// InventoryLedger is the resolver for the inventoryLedger field.
+func (r *queryResolver) InventoryLedger(ctx context.Context, sku string, first *int, after *string, last *int, before *string) (*generated.InventoryLedgerConnection, error) {
+	page, err := pagination.Args{First: first, After: after, Last: last, Before: before}.Page(services.LedgerSort)
 	if err != nil {
 		return nil, err
 	}
-	// Build connection
-	connection := &generated.ProductConnection{
-		Edges: edges,
-		PageInfo: &generated.PageInfo{
-			HasNextPage: hasNextPage,
-			EndCursor:   endCursor,
-		},
-		TotalCount: totalCount,
+
+	entries, err := r.VariantService.GetLedgerPage(ctx, sku, page)
+	if err != nil {
+		return nil, err
 	}
-	return connection, nil
+	return ToGraphQLLedgerConnection(entries), nil
 }
 
+// Category returns generated.CategoryResolver implementation.
+func (r *Resolver) Category() generated.CategoryResolver { return &categoryResolver{r} }
+
 // Mutation returns generated.MutationResolver implementation.
 func (r *Resolver) Mutation() generated.MutationResolver { return &mutationResolver{r} }
 
+// Product returns generated.ProductResolver implementation.
+func (r *Resolver) Product() generated.ProductResolver { return &productResolver{r} }
+
+// ProductVariant returns generated.ProductVariantResolver implementation.
+func (r *Resolver) ProductVariant() generated.ProductVariantResolver {
+	return &productVariantResolver{r}
+}
+
 // Query returns generated.QueryResolver implementation.
 func (r *Resolver) Query() generated.QueryResolver { return &queryResolver{r} }
 
+type categoryResolver struct{ *Resolver }
 type mutationResolver struct{ *Resolver }
+type productResolver struct{ *Resolver }
+type productVariantResolver struct{ *Resolver }
 type queryResolver struct{ *Resolver }
@@ -0,0 +1,28 @@
+package resolvers
+
+import (
+	"github.com/tagaertner/e-commerce-graphql/services/products/generated"
+	"github.com/tagaertner/e-commerce-graphql/services/products/models"
+	"github.com/tagaertner/e-commerce-graphql/services/products/services"
+)
+
+func ToGraphQLVariant(v *models.ProductVariant) *models.ProductVariant {
+	return v
+}
+
+func ToGraphQLVariantList(variants []*models.ProductVariant) []*models.ProductVariant {
+	gqlVariants := make([]*models.ProductVariant, 0, len(variants))
+	for _, v := range variants {
+		gqlVariants = append(gqlVariants, ToGraphQLVariant(v))
+	}
+	return gqlVariants
+}
+
+func ToGraphQLLedgerConnection(page *services.LedgerPage) *generated.InventoryLedgerConnection {
+	edges := make([]*generated.InventoryLedgerEdge, len(page.Entries))
+	for i, e := range page.Entries {
+		edges[i] = &generated.InventoryLedgerEdge{Cursor: page.Cursors[i], Node: e}
+	}
+	pageInfo := page.PageInfo
+	return &generated.InventoryLedgerConnection{Edges: edges, PageInfo: &pageInfo, TotalCount: page.TotalCount}
+}
@@ -0,0 +1,268 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/tagaertner/e-commerce-graphql/pkg/apperr"
+	"github.com/tagaertner/e-commerce-graphql/services/products/models"
+	"gorm.io/gorm"
+)
+
+// categorySubtree selects the IDs of a category and all its descendants.
+const categorySubtree = `WITH RECURSIVE subtree AS (
+	SELECT id FROM categories WHERE id = ?
+	UNION ALL
+	SELECT c.id FROM categories c JOIN subtree ON c.parent_id = subtree.id
+) SELECT id FROM subtree`
+
+// categorySlugKey is the unique constraint on categories.slug.
+const categorySlugKey = "categories_slug_key"
+
+type CategoryService struct {
+	db *gorm.DB
+}
+
+func NewCategoryService(db *gorm.DB) *CategoryService {
+	return &CategoryService{db: db}
+}
+
+func (s *CategoryService) GetCategoryByID(ctx context.Context, id string) (*models.Category, error) {
+	var category models.Category
+	if err := s.db.WithContext(ctx).First(&category, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+// GetRootCategories returns the top of the taxonomy, by name.
+func (s *CategoryService) GetRootCategories(ctx context.Context) ([]*models.Category, error) {
+	var categories []*models.Category
+	if err := s.db.WithContext(ctx).Where("parent_id IS NULL").Order("name").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	return categories, nil
+}
+
+// GetCategoriesByIDs loads many categories in one query, keyed by ID.
+// Unknown IDs are left out.
+func (s *CategoryService) GetCategoriesByIDs(ctx context.Context, ids []string) (map[string]*models.Category, error) {
+	var categories []*models.Category
+	if err := s.db.WithContext(ctx).Where("id IN ?", ids).Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*models.Category, len(categories))
+	for _, c := range categories {
+		byID[c.ID] = c
+	}
+	return byID, nil
+}
+
+// GetChildrenByParentIDs loads the direct children of many categories in one
+// query, keyed by parent ID and sorted by name.
+func (s *CategoryService) GetChildrenByParentIDs(ctx context.Context, parentIDs []string) (map[string][]*models.Category, error) {
+	var categories []*models.Category
+	if err := s.db.WithContext(ctx).Where("parent_id IN ?", parentIDs).Order("name").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	byParent := make(map[string][]*models.Category, len(parentIDs))
+	for _, c := range categories {
+		byParent[*c.ParentID] = append(byParent[*c.ParentID], c)
+	}
+	return byParent, nil
+}
+
+// GetCategoriesByProductIDs loads the categories of many products in one
+// query, keyed by product ID and sorted by name.
+func (s *CategoryService) GetCategoriesByProductIDs(ctx context.Context, productIDs []string) (map[string][]*models.Category, error) {
+	var rows []struct {
+		ProductID string
+		models.Category
+	}
+	if err := s.db.WithContext(ctx).
+		Table("categories").
+		Select("product_categories.product_id, categories.*").
+		Joins("JOIN product_categories ON product_categories.category_id = categories.id").
+		Where("product_categories.product_id IN ?", productIDs).
+		Order("categories.name").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	byProduct := make(map[string][]*models.Category, len(productIDs))
+	for i := range rows {
+		byProduct[rows[i].ProductID] = append(byProduct[rows[i].ProductID], &rows[i].Category)
+	}
+	return byProduct, nil
+}
+
+func (s *CategoryService) CreateCategory(ctx context.Context, input models.CreateCategoryInput) (*models.Category, error) {
+	if strings.TrimSpace(input.Name) == "" {
+		return nil, apperr.Field("name", "is required")
+	}
+
+	category := &models.Category{
+		ID:   uuid.NewString(),
+		Name: strings.TrimSpace(input.Name),
+	}
+	slug, err := categorySlug(category.Name, input.Slug)
+	if err != nil {
+		return nil, err
+	}
+	category.Slug = slug
+
+	if input.ParentID != nil && *input.ParentID != "" {
+		if _, err := s.GetCategoryByID(ctx, *input.ParentID); err != nil {
+			return nil, fmt.Errorf("parent category: %w", err)
+		}
+		category.ParentID = input.ParentID
+	}
+
+	if err := s.db.WithContext(ctx).Create(category).Error; err != nil {
+		return nil, slugTaken(err, category.Slug)
+	}
+	return category, nil
+}
+
+func (s *CategoryService) UpdateCategory(ctx context.Context, id string, input models.UpdateCategoryInput) (*models.Category, error) {
+	category, err := s.GetCategoryByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Name != nil {
+		if strings.TrimSpace(*input.Name) == "" {
+			return nil, apperr.Field("name", "is required")
+		}
+		category.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.Slug != nil {
+		if category.Slug, err = categorySlug(category.Name, input.Slug); err != nil {
+			return nil, err
+		}
+	}
+
+	if input.ParentID != nil {
+		if *input.ParentID == "" {
+			category.ParentID = nil
+		} else {
+			// A category cannot move under itself or one of its descendants
+			var inSubtree int64
+			if err := s.db.WithContext(ctx).Raw("SELECT COUNT(*) FROM ("+categorySubtree+") AS subtree WHERE id = ?", id, *input.ParentID).
+				Scan(&inSubtree).Error; err != nil {
+				return nil, err
+			}
+			if inSubtree > 0 {
+				return nil, apperr.Field("parentId", "a category cannot be moved under itself or its subcategories")
+			}
+			if _, err := s.GetCategoryByID(ctx, *input.ParentID); err != nil {
+				return nil, fmt.Errorf("parent category: %w", err)
+			}
+			category.ParentID = input.ParentID
+		}
+	}
+
+	if err := s.db.WithContext(ctx).Save(category).Error; err != nil {
+		return nil, slugTaken(err, category.Slug)
+	}
+	return category, nil
+}
+
+// DeleteCategory removes a category and its product links. Categories with
+// subcategories cannot be deleted.
+func (s *CategoryService) DeleteCategory(ctx context.Context, id string) (bool, error) {
+	var children int64
+	if err := s.db.WithContext(ctx).Model(&models.Category{}).Where("parent_id = ?", id).Count(&children).Error; err != nil {
+		return false, err
+	}
+	if children > 0 {
+		return false, apperr.Conflict("category has subcategories; move or delete them first")
+	}
+
+	result := s.db.WithContext(ctx).Delete(&models.Category{}, "id = ?", id)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, apperr.NotFound("category %s not found", id)
+	}
+	return true, nil
+}
+
+// SetProductCategories replaces the categories a product is listed in.
+func (s *CategoryService) SetProductCategories(ctx context.Context, productID string, categoryIDs []string) (*models.Product, error) {
+	ids := make([]string, 0, len(categoryIDs))
+	seen := make(map[string]bool, len(categoryIDs))
+	for _, id := range categoryIDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	var product models.Product
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&product, "id = ?", productID).Error; err != nil {
+			return err
+		}
+
+		if len(ids) > 0 {
+			var found int64
+			if err := tx.Model(&models.Category{}).Where("id IN ?", ids).Count(&found).Error; err != nil {
+				return err
+			}
+			if int(found) != len(ids) {
+				return apperr.NotFound("category not found")
+			}
+		}
+
+		if err := tx.Exec("DELETE FROM product_categories WHERE product_id = ?", productID).Error; err != nil {
+			return err
+		}
+		for _, id := range ids {
+			if err := tx.Exec("INSERT INTO product_categories (product_id, category_id) VALUES (?, ?)", productID, id).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+// inCategorySubtree keeps the products listed in a category or any of its
+// descendants.
+func inCategorySubtree(q *gorm.DB, categoryID string) *gorm.DB {
+	return q.Where("id IN (SELECT product_id FROM product_categories WHERE category_id IN ("+categorySubtree+"))", categoryID)
+}
+
+// slugTaken turns a violation of the unique slug constraint into a CONFLICT
+// and returns any other error unchanged.
+func slugTaken(err error, slug string) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == categorySlugKey {
+		return apperr.Conflict("category slug %q is already taken", slug)
+	}
+	return err
+}
+
+var nonSlugChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// categorySlug returns the requested slug, or one derived from name.
+func categorySlug(name string, requested *string) (string, error) {
+	source := name
+	if requested != nil {
+		source = *requested
+	}
+	slug := strings.Trim(nonSlugChars.ReplaceAllString(strings.ToLower(source), "-"), "-")
+	if slug == "" {
+		return "", apperr.Field("slug", "invalid category slug %q", source)
+	}
+	return slug, nil
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tagaertner/e-commerce-graphql/pkg/apperr"
+	"github.com/tagaertner/e-commerce-graphql/pkg/pagination"
+	"github.com/tagaertner/e-commerce-graphql/services/products/models"
+)
+
+// firstPage returns the first page of products by name.
+func firstPage(t *testing.T) pagination.Page {
+	t.Helper()
+	sort, err := ProductSort("NAME", false)
+	require.NoError(t, err)
+	page, err := pagination.Args{}.Page(sort)
+	require.NoError(t, err)
+	return page
+}
+
+// productNames returns the names of products, in order.
+func productNames(products []*models.Product) []string {
+	names := make([]string, len(products))
+	for i, p := range products {
+		names[i] = p.Name
+	}
+	return names
+}
+
+// TestSlugTaken checks that only violations of the slug constraint become
+// CONFLICT errors.
+func TestSlugTaken(t *testing.T) {
+	violation := fmt.Errorf("insert category: %w", &pgconn.PgError{Code: "23505", ConstraintName: categorySlugKey})
+	err := slugTaken(violation, "shirts")
+	assert.Equal(t, apperr.CodeConflict, apperr.CodeOf(err))
+	assert.Contains(t, err.Error(), `"shirts"`)
+
+	other := &pgconn.PgError{Code: "23505", ConstraintName: "categories_pkey"}
+	assert.Same(t, error(other), slugTaken(other, "shirts"))
+
+	plain := errors.New("connection refused")
+	assert.Equal(t, plain, slugTaken(plain, "shirts"))
+}
+
+// TestCreateCategory_DuplicateSlug A second category with the same slug is a CONFLICT.
+func TestCreateCategory_DuplicateSlug(t *testing.T){
+	db, _, ctx := setupTestEnv(t)
+	categoryService := NewCategoryService(db)
+
+	// ---Arrange ---
+	_, err := categoryService.CreateCategory(ctx, models.CreateCategoryInput{Name: "Shirts"})
+	require.NoError(t, err)
+
+	// ---Act --- the derived slug is "shirts" again
+	created, err := categoryService.CreateCategory(ctx, models.CreateCategoryInput{Name: "SHIRTS"})
+
+	// ---Assert ---
+	assert.Nil(t, created)
+	assert.Equal(t, apperr.CodeConflict, apperr.CodeOf(err))
+}
+
+// TestUpdateCategory_DuplicateSlug Taking another category's slug is a CONFLICT.
+func TestUpdateCategory_DuplicateSlug(t *testing.T){
+	db, _, ctx := setupTestEnv(t)
+	categoryService := NewCategoryService(db)
+
+	// ---Arrange ---
+	_, err := categoryService.CreateCategory(ctx, models.CreateCategoryInput{Name: "Shirts"})
+	require.NoError(t, err)
+	trousers, err := categoryService.CreateCategory(ctx, models.CreateCategoryInput{Name: "Trousers"})
+	require.NoError(t, err)
+
+	// ---Act ---
+	updated, err := categoryService.UpdateCategory(ctx, trousers.ID, models.UpdateCategoryInput{Slug: strPtr("shirts")})
+
+	// ---Assert ---
+	assert.Nil(t, updated)
+	assert.Equal(t, apperr.CodeConflict, apperr.CodeOf(err))
+}
+
+// TestUpdateCategory_MoveUnderDescendant A category cannot move into its own subtree.
+func TestUpdateCategory_MoveUnderDescendant(t *testing.T){
+	db, _, ctx := setupTestEnv(t)
+	categoryService := NewCategoryService(db)
+
+	// ---Arrange ---
+	clothing, err := categoryService.CreateCategory(ctx, models.CreateCategoryInput{Name: "Clothing"})
+	require.NoError(t, err)
+	shirts, err := categoryService.CreateCategory(ctx, models.CreateCategoryInput{Name: "Shirts", ParentID: &clothing.ID})
+	require.NoError(t, err)
+
+	// ---Act ---
+	updated, err := categoryService.UpdateCategory(ctx, clothing.ID, models.UpdateCategoryInput{ParentID: &shirts.ID})
+
+	// ---Assert ---
+	assert.Nil(t, updated)
+	assert.Equal(t, apperr.CodeValidation, apperr.CodeOf(err))
+}
+
+// TestGetAllProductsCursor_CategorySubtree Filtering by a category includes
+// products listed anywhere below it, and nothing else.
+func TestGetAllProductsCursor_CategorySubtree(t *testing.T){
+	db, productService, ctx := setupTestEnv(t)
+	categoryService := NewCategoryService(db)
+
+	// ---Arrange --- Clothing > Shirts > T-Shirts, and Books
+	clothing, err := categoryService.CreateCategory(ctx, models.CreateCategoryInput{Name: "Clothing"})
+	require.NoError(t, err)
+	shirts, err := categoryService.CreateCategory(ctx, models.CreateCategoryInput{Name: "Shirts", ParentID: &clothing.ID})
+	require.NoError(t, err)
+	tShirts, err := categoryService.CreateCategory(ctx, models.CreateCategoryInput{Name: "T-Shirts", ParentID: &shirts.ID})
+	require.NoError(t, err)
+	books, err := categoryService.CreateCategory(ctx, models.CreateCategoryInput{Name: "Books"})
+	require.NoError(t, err)
+
+	listings := map[string][]string{
+		"Scarf":    {clothing.ID},
+		"Tee":      {tShirts.ID},
+		"Novel":    {books.ID},
+		"Tee Book": {tShirts.ID, books.ID},
+		"Unlisted": nil,
+	}
+	for name, categoryIDs := range listings {
+		product := createProduct(t, productService, name, 999, 1)
+		_, err := categoryService.SetProductCategories(ctx, product.ID, categoryIDs)
+		require.NoError(t, err)
+	}
+
+	// ---Act ---
+	clothingPage, err := productService.GetAllProductsCursor(ctx, clothing.ID, firstPage(t), false)
+	require.NoError(t, err)
+	shirtsPage, err := productService.GetAllProductsCursor(ctx, shirts.ID, firstPage(t), false)
+	require.NoError(t, err)
+
+	// ---Assert ---
+	assert.Equal(t, []string{"Scarf", "Tee", "Tee Book"}, productNames(clothingPage.Products))
+	assert.Equal(t, 3, clothingPage.TotalCount)
+	assert.Equal(t, []string{"Tee", "Tee Book"}, productNames(shirtsPage.Products))
+	assert.Equal(t, 2, shirtsPage.TotalCount)
+}
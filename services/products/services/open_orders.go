@@ -0,0 +1,24 @@
+package services
+
+import "gorm.io/gorm"
+
+// stockHoldingStatuses are the order statuses that still hold reserved stock,
+// as in the orders service's OrderStatus.HoldsStock.
+var stockHoldingStatuses = []string{"PENDING", "PAID"}
+
+// openOrderCount counts the live orders holding stock of any of the given
+// SKUs. Orders and products share one database, so the orders service's
+// tables can be read in the same transaction; until that service has
+// migrated there are no orders at all.
+func openOrderCount(tx *gorm.DB, skus []string) (int64, error) {
+	if len(skus) == 0 || !tx.Migrator().HasTable("order_line_items") {
+		return 0, nil
+	}
+	var count int64
+	err := tx.Table("orders").
+		Joins("JOIN order_line_items ON order_line_items.order_id = orders.id").
+		Where("order_line_items.sku IN ? AND orders.status IN ? AND orders.deleted_at IS NULL", skus, stockHoldingStatuses).
+		Distinct("orders.id").
+		Count(&count).Error
+	return count, err
+}
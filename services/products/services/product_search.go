@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"strings"
+
+	"github.com/tagaertner/e-commerce-graphql/pkg/apperr"
+	"github.com/tagaertner/e-commerce-graphql/pkg/money"
+	"github.com/tagaertner/e-commerce-graphql/pkg/pagination"
+	"github.com/tagaertner/e-commerce-graphql/services/products/models"
+	"gorm.io/gorm"
+)
+
+// RelevanceSort orders search results best match first.
+var RelevanceSort = pagination.Sort{Name: "RELEVANCE", Column: "relevance", Desc: true, Key: float64(0)}
+
+// ProductPage is one page of products with each product's cursor.
+type ProductPage struct {
+	Products   []*models.Product
+	Cursors    []string
+	PageInfo   pagination.PageInfo
+	TotalCount int
+}
+
+// productRow is a product with its relevance to the search query.
+type productRow struct {
+	models.Product
+	Relevance float64
+}
+
+// SearchProducts returns one page of the products that match filter and
+// query. query is matched against names and descriptions, by word stems and
+// by trigram similarity so misspellings still match. Relevance is the full
+// text rank plus the name's similarity, and is zero without a query.
+// Deleted products are left out unless includeDeleted is set.
+func (s *ProductService) SearchProducts(ctx context.Context, filter *models.ProductFilter, query string, page pagination.Page, includeDeleted bool) (*ProductPage, error) {
+	db := s.scope(ctx, includeDeleted)
+
+	scope, err := filterProducts(db.Model(&models.Product{}), filter)
+	if err != nil {
+		return nil, err
+	}
+
+	relevance := "0::float8"
+	var args []interface{}
+	if query = strings.TrimSpace(query); query != "" {
+		scope = scope.Where("(search_vector @@ websearch_to_tsquery('english', ?) OR name % ? OR description % ?)", query, query, query)
+		relevance = "(ts_rank(search_vector, websearch_to_tsquery('english', ?)) + similarity(name, ?))::float8"
+		args = []interface{}{query, query}
+	}
+
+	var total int64
+	if err := scope.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	ranked := scope.Session(&gorm.Session{}).Select("products.*, "+relevance+" AS relevance", args...)
+
+	var rows []*productRow
+	if err := page.Apply(db.Table("(?) AS products", ranked)).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	rows, cursors, info := pagination.Trim(rows, page, func(r *productRow) pagination.Cursor {
+		if page.Sort.Column == RelevanceSort.Column {
+			return pagination.Cursor{Key: r.Relevance, ID: r.ID}
+		}
+		return pagination.Cursor{Key: productSortKey(page.Sort, &r.Product), ID: r.ID}
+	})
+
+	products := make([]*models.Product, len(rows))
+	for i, r := range rows {
+		products[i] = &r.Product
+	}
+	return &ProductPage{Products: products, Cursors: cursors, PageInfo: info, TotalCount: int(total)}, nil
+}
+
+func filterProducts(q *gorm.DB, f *models.ProductFilter) (*gorm.DB, error) {
+	if f == nil {
+		return q, nil
+	}
+	if f.MinPrice != nil && f.MaxPrice != nil {
+		if f.MinPrice.Currency != f.MaxPrice.Currency {
+			return nil, apperr.Errorf(apperr.CodeValidation, "minPrice and maxPrice: %w", money.ErrCurrencyMismatch)
+		}
+		if f.MinPrice.Amount > f.MaxPrice.Amount {
+			return nil, apperr.Field("minPrice", "cannot be greater than maxPrice")
+		}
+	}
+	if f.MinPrice != nil {
+		q = q.Where("price_currency = ? AND price_amount >= ?", f.MinPrice.Currency, f.MinPrice.Amount)
+	}
+	if f.MaxPrice != nil {
+		q = q.Where("price_currency = ? AND price_amount <= ?", f.MaxPrice.Currency, f.MaxPrice.Amount)
+	}
+	if f.Available != nil {
+		q = q.Where("available = ?", *f.Available)
+	}
+	if f.MinInventory != nil {
+		q = q.Where("inventory >= ?", *f.MinInventory)
+	}
+	if f.CategoryID != nil && *f.CategoryID != "" {
+		q = inCategorySubtree(q, *f.CategoryID)
+	}
+	return q, nil
+}
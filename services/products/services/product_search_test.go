@@ -0,0 +1,136 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tagaertner/e-commerce-graphql/pkg/apperr"
+	"github.com/tagaertner/e-commerce-graphql/pkg/money"
+	"github.com/tagaertner/e-commerce-graphql/pkg/pagination"
+	"github.com/tagaertner/e-commerce-graphql/services/products/models"
+)
+
+// relevancePage returns the first page of search results, best match first.
+func relevancePage(t *testing.T) pagination.Page {
+	t.Helper()
+	page, err := pagination.Args{}.Page(RelevanceSort)
+	require.NoError(t, err)
+	return page
+}
+
+// TestSearchProducts_WordStems A query matches other forms of the same word.
+func TestSearchProducts_WordStems(t *testing.T){
+	_, productService, ctx := setupTestEnv(t)
+
+	// ---Arrange ---
+	createProduct(t, productService, "Running Shoes", 8999, 5)
+	createProduct(t, productService, "Coffee Mug", 1299, 5)
+
+	// ---Act ---
+	result, err := productService.SearchProducts(ctx, nil, "run", relevancePage(t), false)
+
+	// ---Assert ---
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Running Shoes"}, productNames(result.Products))
+	assert.Equal(t, 1, result.TotalCount)
+}
+
+// TestSearchProducts_Misspelling A misspelt query still finds similar names.
+func TestSearchProducts_Misspelling(t *testing.T){
+	_, productService, ctx := setupTestEnv(t)
+
+	// ---Arrange ---
+	createProduct(t, productService, "Running Shoes", 8999, 5)
+	createProduct(t, productService, "Coffee Mug", 1299, 5)
+
+	// ---Act ---
+	result, err := productService.SearchProducts(ctx, nil, "cofee mug", relevancePage(t), false)
+
+	// ---Assert ---
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Coffee Mug"}, productNames(result.Products))
+}
+
+// TestSearchProducts_Relevance The closest name comes first.
+func TestSearchProducts_Relevance(t *testing.T){
+	_, productService, ctx := setupTestEnv(t)
+
+	// ---Arrange ---
+	createProduct(t, productService, "Wireless Mouse Pad", 1999, 5)
+	createProduct(t, productService, "Mouse", 2999, 5)
+
+	// ---Act ---
+	result, err := productService.SearchProducts(ctx, nil, "mouse", relevancePage(t), false)
+
+	// ---Assert ---
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Mouse", "Wireless Mouse Pad"}, productNames(result.Products))
+}
+
+// TestSearchProducts_Filters Filters apply with or without a query.
+func TestSearchProducts_Filters(t *testing.T){
+	_, productService, ctx := setupTestEnv(t)
+
+	// ---Arrange ---
+	createProduct(t, productService, "Cheap Lamp", 999, 20)
+	createProduct(t, productService, "Desk Lamp", 4999, 2)
+	offSale := createProduct(t, productService, "Floor Lamp", 5999, 20)
+	_, err := productService.SetProductAvailability(ctx, offSale.ID, false, nil)
+	require.NoError(t, err)
+
+	minPrice := money.New(1000, "USD")
+	maxPrice := money.New(6000, "USD")
+	available := true
+	minInventory := 10
+
+	// ---Act & Assert ---
+	result, err := productService.SearchProducts(ctx, &models.ProductFilter{MinPrice: &minPrice, MaxPrice: &maxPrice}, "", firstPage(t), false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Desk Lamp", "Floor Lamp"}, productNames(result.Products))
+
+	result, err = productService.SearchProducts(ctx, &models.ProductFilter{Available: &available}, "lamp", firstPage(t), false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Cheap Lamp", "Desk Lamp"}, productNames(result.Products))
+
+	result, err = productService.SearchProducts(ctx, &models.ProductFilter{MinInventory: &minInventory}, "", firstPage(t), false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Cheap Lamp", "Floor Lamp"}, productNames(result.Products))
+}
+
+// TestSearchProducts_InvalidPriceRange Mismatched or inverted price ranges are rejected.
+func TestSearchProducts_InvalidPriceRange(t *testing.T){
+	_, productService, ctx := setupTestEnv(t)
+
+	usdPrice := money.New(1000, "USD")
+	eurPrice := money.New(2000, "EUR")
+	lowPrice := money.New(500, "USD")
+
+	_, err := productService.SearchProducts(ctx, &models.ProductFilter{MinPrice: &usdPrice, MaxPrice: &eurPrice}, "", firstPage(t), false)
+	assert.Equal(t, apperr.CodeValidation, apperr.CodeOf(err))
+
+	_, err = productService.SearchProducts(ctx, &models.ProductFilter{MinPrice: &usdPrice, MaxPrice: &lowPrice}, "", firstPage(t), false)
+	assert.Equal(t, apperr.CodeValidation, apperr.CodeOf(err))
+}
+
+// TestSearchProducts_Deleted Deleted products only show up with includeDeleted.
+func TestSearchProducts_Deleted(t *testing.T){
+	_, productService, ctx := setupTestEnv(t)
+
+	// ---Arrange ---
+	createProduct(t, productService, "Desk Lamp", 4999, 2)
+	deleted := createProduct(t, productService, "Floor Lamp", 5999, 20)
+	_, err := productService.DeleteProduct(ctx, models.DeleteProductInput{ID: &deleted.ID})
+	require.NoError(t, err)
+
+	// ---Act ---
+	live, err := productService.SearchProducts(ctx, nil, "lamp", firstPage(t), false)
+	require.NoError(t, err)
+	all, err := productService.SearchProducts(ctx, nil, "lamp", firstPage(t), true)
+	require.NoError(t, err)
+
+	// ---Assert ---
+	assert.Equal(t, []string{"Desk Lamp"}, productNames(live.Products))
+	assert.Equal(t, []string{"Desk Lamp", "Floor Lamp"}, productNames(all.Products))
+	assert.Equal(t, 2, all.TotalCount)
+}
@@ -2,14 +2,18 @@ package services
 
 import (
 	"context"
-	"errors"
-	"fmt"
 	"strings"
-	"github.com/google/uuid"
+	"time"
 
+	"github.com/google/uuid"
+	"github.com/tagaertner/e-commerce-graphql/pkg/apperr"
+	"github.com/tagaertner/e-commerce-graphql/pkg/money"
+	"github.com/tagaertner/e-commerce-graphql/pkg/pagination"
+	"github.com/tagaertner/e-commerce-graphql/pkg/validate"
 	// "github.com/tagaertner/e-commerce-graphql/services/products/generated"
 	"github.com/tagaertner/e-commerce-graphql/services/products/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ProductService struct {
@@ -28,24 +32,55 @@ func (s *ProductService) GetAllProducts() ([]*models.Product, error) {
 	return products, nil
 }
 
-func (s *ProductService) GetProductByID(id string) (*models.Product, error) {
+// scope returns the service's DB for ctx, including deleted products when
+// includeDeleted is set.
+func (s *ProductService) scope(ctx context.Context, includeDeleted bool) *gorm.DB {
+	db := s.db.WithContext(ctx)
+	if includeDeleted {
+		db = db.Unscoped()
+	}
+	return db
+}
+
+// GetProductByID returns a product. Deleted products are only found with
+// includeDeleted.
+func (s *ProductService) GetProductByID(ctx context.Context, id string, includeDeleted bool) (*models.Product, error) {
 	var product models.Product
-	if err := s.db.First(&product, "id = ?", id).Error; err != nil {
+	if err := s.scope(ctx, includeDeleted).First(&product, "id = ?", id).Error; err != nil {
 		return nil, err
 	}
 	return &product, nil
 }
 
-func (s *ProductService) CreateProduct(ctx context.Context,  name string, price float64, description string, inventory int ) (*models.Product, error){
+// GetProductsByIDs loads many products in one query, keyed by ID. Unknown
+// IDs are left out. Deleted products are included, so orders for them
+// still show what was bought.
+func (s *ProductService) GetProductsByIDs(ctx context.Context, ids []string) (map[string]*models.Product, error) {
+	var products []*models.Product
+	if err := s.scope(ctx, true).Where("id IN ?", ids).Find(&products).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*models.Product, len(products))
+	for _, p := range products {
+		byID[p.ID] = p
+	}
+	return byID, nil
+}
 
+// CreateProduct creates a product with a single variant holding its stock.
+// The variant's SKU defaults to the product ID.
+func (s *ProductService) CreateProduct(ctx context.Context,  name string, price money.Money, description string, stock int, sku string) (*models.Product, error){
+
+	var violations validate.Violations
 	if strings.TrimSpace(name) == ""{
-		return nil, fmt.Errorf("invalid product name: missing or invalid field")
+		violations.Add("name", "is required")
 	}
-	if price <= 0 {
-		return nil, fmt.Errorf("price must be greater than zero")
+	checkPrice(&violations, price)
+	if stock < 0 {
+		violations.Add("inventory", "cannot be negative")
 	}
-	if inventory < 0 {
-		return nil, fmt.Errorf(" inventory cannot be negative")
+	if err := violations.Err(); err != nil {
+		return nil, err
 	}
 
 	product := &models.Product{
@@ -53,154 +88,280 @@ func (s *ProductService) CreateProduct(ctx context.Context,  name string, price
 		Name: name,
 		Price: price,
 		Description: &description,
-		Inventory: inventory,
-		Available: inventory > 0,
+		Available: stock > 0,
+		Version: 1,
 	}
-	
-	if err := s.db.WithContext(ctx).Create(product).Error; err != nil{
+	if sku = strings.TrimSpace(sku); sku == "" {
+		sku = product.ID
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(product).Error; err != nil {
+			return err
+		}
+		variant := &models.ProductVariant{
+			ID:        "var_" + uuid.NewString(),
+			ProductID: product.ID,
+			SKU:       sku,
+			Available: stock > 0,
+		}
+		if err := tx.Create(variant).Error; err != nil {
+			return err
+		}
+		return initialStock(ctx, tx, variant, stock)
+	})
+	if err != nil {
 		return nil, err
 	}
-	return product, nil
+	return s.GetProductByID(ctx, product.ID, false)
 }
 
+// UpdateProduct changes the fields set in input. With input.ExpectedVersion
+// set, it fails with a ConflictError unless the product is at that version.
 func (s *ProductService)UpdateProduct(ctx context.Context, id string,  input models.UpdateProductInput) (*models.Product, error){
-	product := &models.Product{ID: id}
+	var violations validate.Violations
+	if input.Name != nil && strings.TrimSpace(*input.Name) == "" {
+		violations.Add("name", "is required")
+	}
+	if input.Price != nil {
+		checkPrice(&violations, *input.Price)
+	}
+	if input.Inventory != nil && *input.Inventory < 0 {
+		violations.Add("inventory", "cannot be negative")
+	}
+	if err := violations.Err(); err != nil {
+		return nil, err
+	}
 
-	updates := s.db.WithContext(ctx).Model(&models.Product{}).Where("id = ?", id)
+	updates := map[string]interface{}{}
 
 	if input.Name != nil{
-		updates = updates.Update("name", *input.Name)
+		updates["name"] = *input.Name
 	}
-	
 	if input.Price != nil{
-		updates = updates.Update("price", *input.Price)
+		updates["price_amount"] = input.Price.Amount
+		updates["price_currency"] = input.Price.Currency
 	}
 	if input.Description != nil{
-		updates = updates.Update("description", *input.Description)
-	}
-	if input.Inventory != nil{
-		updates = updates.Update("inventory", *input.Inventory)
-	}
-
-	// Execute the update
-	if err := updates.Error; err != nil{
-		return nil, err
+		updates["description"] = *input.Description
 	}
 
-	// Return the updated user
-	if err := s.db.WithContext(ctx).First(&product, "id = ?", id).Error; err != nil{
-		return nil, err
-	}
-	return product, nil
+	return s.writeProduct(ctx, id, input.ExpectedVersion, func(tx *gorm.DB, product *models.Product) (map[string]interface{}, error) {
+		if input.Price != nil {
+			if err := checkCurrencyChange(tx, product, input.Price.Currency); err != nil {
+				return nil, err
+			}
+		}
+
+		// Inventory belongs to the product's variant
+		if input.Inventory != nil {
+			variant, err := soleVariant(tx, id)
+			if err != nil {
+				return nil, err
+			}
+			if err := setStock(ctx, tx, variant, *input.Inventory, "set by updateProduct"); err != nil {
+				return nil, err
+			}
+		}
+		return updates, nil
+	})
 }
 
+// DeleteProduct soft-deletes a product, by ID or else by name. Deleted
+// products can no longer be ordered, and can be restored until PurgeDeleted
+// removes them. A product whose variants PENDING or PAID orders still hold
+// stock of cannot be deleted, as those orders could no longer be repriced or
+// settled; they have to be cancelled or fulfilled first.
 func (s *ProductService)DeleteProduct(ctx context.Context, input models.DeleteProductInput) (bool, error){
-	var result *gorm.DB
-
 	if input.ID == nil && input.Name == nil {
-		return false, errors.New("either ID or Name must be provided for deletion")
+		return false, apperr.Validation("either id or name must be provided for deletion")
 	}
 
-	if input.ID != nil {
-		result = s.db.WithContext(ctx).Delete(&models.Product{}, "id = ?", *input.ID)
-	} else if input.Name != nil {
-		result = s.db.WithContext(ctx).Delete(&models.Product{}, "name = ?", *input.Name)
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		query := tx.Model(&models.Product{})
+		if input.ID != nil {
+			query = query.Where("id = ?", *input.ID)
+		} else {
+			query = query.Where("name = ?", *input.Name)
+		}
+		var ids []string
+		if err := query.Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return apperr.NotFound("product not found")
+		}
+
+		// Orders reserve stock with the variant locked, so none can start
+		// holding it between this check and the delete
+		var skus []string
+		if err := tx.Model(&models.ProductVariant{}).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("product_id IN ?", ids).
+			Pluck("sku", &skus).Error; err != nil {
+			return err
+		}
+		open, err := openOrderCount(tx, skus)
+		if err != nil {
+			return err
+		}
+		if open > 0 {
+			return apperr.Conflict("product is held by %d open orders; cancel or fulfil them first", open)
+		}
+		return tx.Delete(&models.Product{}, "id IN ?", ids).Error
+	})
+	if err != nil {
+		return false, err
 	}
+	return true, nil
+}
 
+// RestoreProduct undoes DeleteProduct.
+func (s *ProductService) RestoreProduct(ctx context.Context, id string) (*models.Product, error) {
+	result := s.db.WithContext(ctx).Unscoped().
+		Model(&models.Product{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Update("deleted_at", nil)
 	if result.Error != nil {
-		return false, result.Error
+		return nil, result.Error
 	}
 	if result.RowsAffected == 0 {
-		return false, fmt.Errorf("product not found")
+		return nil, apperr.NotFound("deleted product %s not found", id)
 	}
-	return true, nil
+	return s.GetProductByID(ctx, id, false)
 }
 
-func (s *ProductService)RestockProduct(ctx context.Context, id string, quantity int)(*models.Product, error) {
-	var product models.Product
-
-	// Fetching product
-	if err := s.db.WithContext(ctx).First(&product, "id = ?", id).Error; err != nil {
-		return nil, err
-	}
+// PurgeDeleted permanently removes products deleted before cutoff, along
+// with their variants and category listings, and returns how many products
+// it removed. Their inventory ledger entries are kept.
+func (s *ProductService) PurgeDeleted(ctx context.Context, cutoff time.Time) (int64, error) {
+	result := s.db.WithContext(ctx).Unscoped().Where("deleted_at < ?", cutoff).Delete(&models.Product{})
+	return result.RowsAffected, result.Error
+}
 
+// RestockProduct restocks the only variant of a product. With
+// expectedVersion set, it fails with a ConflictError unless the product is
+// at that version.
+func (s *ProductService)RestockProduct(ctx context.Context, id string, quantity int, expectedVersion *int)(*models.Product, error) {
 	// Validate the restock amount
 	if quantity <= 0 {
-		return nil, fmt.Errorf("invalid restock amount: must be greater than zero")
+		return nil, apperr.Field("quantity", "must be greater than zero")
 	}
-	// Update inventory
-	product.Inventory += quantity
 
-	// Save updated product
-	if err := s.db.WithContext(ctx).Save(&product).Error; err != nil {
-		return nil, err
-	}
-	return &product, nil 
+	return s.writeProduct(ctx, id, expectedVersion, func(tx *gorm.DB, product *models.Product) (map[string]interface{}, error) {
+		variant, err := soleVariant(tx, id)
+		if err != nil {
+			return nil, err
+		}
+		return nil, restock(ctx, tx, variant, quantity, "")
+	})
 }
 
-func (s *ProductService)SetProductAvailability(ctx context.Context, id string, available bool) (*models.Product, error){
-	var product models.Product
+// SetProductAvailability puts the only variant of a product on or off sale.
+// With expectedVersion set, it fails with a ConflictError unless the product
+// is at that version.
+func (s *ProductService)SetProductAvailability(ctx context.Context, id string, available bool, expectedVersion *int) (*models.Product, error){
+	return s.writeProduct(ctx, id, expectedVersion, func(tx *gorm.DB, product *models.Product) (map[string]interface{}, error) {
+		variant, err := soleVariant(tx, id)
+		if err != nil {
+			return nil, err
+		}
+		return nil, setAvailability(tx, variant, available)
+	})
+}
 
-	// Fetch product
-	if err := s.db.WithContext(ctx).First(&product, "id = ?", id).Error; err != nil{
-		return nil, err
+// checkPrice records what is wrong with a product price.
+func checkPrice(violations *validate.Violations, price money.Money) {
+	if err := money.ValidateCurrency(price.Currency); err != nil {
+		violations.Add("price.currency", "%v", err)
 	}
+	if price.Amount <= 0 {
+		violations.Add("price", "must be greater than zero")
+	}
+}
 
-	// Prevent redundate updates
-	if product.Available == available {
-		return &product, fmt.Errorf("product already availability set to %t", available)
-
+// checkCurrencyChange refuses to change the currency of a product whose
+// variants override its price, since overrides are in the product's currency.
+func checkCurrencyChange(tx *gorm.DB, product *models.Product, currency string) error {
+	if product.Price.Currency == currency {
+		return nil
 	}
 
-	// safegard
-	if available && product.Inventory <= 0{
-		return nil, fmt.Errorf("cannot mark product as available with zero inventory")
+	var overrides int64
+	if err := tx.Model(&models.ProductVariant{}).
+		Where("product_id = ? AND price_amount IS NOT NULL", product.ID).
+		Count(&overrides).Error; err != nil {
+		return err
+	}
+	if overrides > 0 {
+		return apperr.Conflict("product has variant price overrides; clear them before changing its currency")
 	}
+	return nil
+}
 
-	// Udate availability
-	product.Available = available
+// productSortColumns maps the ProductSortField values to their columns and
+// the Go type of their cursor keys.
+var productSortColumns = map[string]struct {
+	column string
+	key    any
+}{
+	"PRICE":      {"price_amount", int64(0)},
+	"NAME":       {"name", ""},
+	"INVENTORY":  {"inventory", 0},
+	"CREATED_AT": {"created_at", time.Time{}},
+}
 
-	if err := s.db.WithContext(ctx).Save(&product).Error; err != nil{
-		return nil, err
+// ProductSort returns the sort for a ProductSortField, e.g. "PRICE". Each
+// field and direction gets its own cursors.
+func ProductSort(field string, desc bool) (pagination.Sort, error) {
+	col, ok := productSortColumns[field]
+	if !ok {
+		return pagination.Sort{}, apperr.Validation("unknown product sort field %q", field)
 	}
-	return &product, nil
+	name := field + "_ASC"
+	if desc {
+		name = field + "_DESC"
+	}
+	return pagination.Sort{Name: name, Column: col.column, Desc: desc, Key: col.key}, nil
 }
 
 // GetAllProducts returns filderd products from db
-func (s *ProductService) GetAllProductsCursor(ctx context.Context,  after *string, first int) ([]*models.Product, bool, error ){
+// GetAllProductsCursor returns one page of products in the page's sort.
+// A non-empty categoryID keeps only products in that category's subtree.
+// Deleted products are left out unless includeDeleted is set.
+func (s *ProductService) GetAllProductsCursor(ctx context.Context, categoryID string, page pagination.Page, includeDeleted bool) (*ProductPage, error) {
+	scope := s.scope(ctx, includeDeleted).Model(&models.Product{})
+	if categoryID != "" {
+		scope = inCategorySubtree(scope, categoryID)
+	}
+
+	var total int64
+	if err := scope.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, err
+	}
+
 	var products []*models.Product
+	if err := page.Apply(scope.Session(&gorm.Session{})).Find(&products).Error; err != nil {
+		return nil, err
+	}
 
-    query := s.db.WithContext(ctx).
-        Model(&models.Product{}).
-        Order("id ASC")
-
-    // If we have a cursor, decode it and filter
-    if after != nil && *after != "" {
-        lastID, err := DecodeCursor(*after)
-        if err != nil {
-            return nil, false, fmt.Errorf("invalid cursor: %w", err)
-        }
-
-        // Only return products *after* this ID
-        query = query.Where("id > ?", lastID)
-    }
-
-    // Fetch first + 1 to check if there's a next page
-    if err := query.Limit(first + 1).Find(&products).Error; err != nil {
-        return nil, false, err
-    }
-
-    // Check if there are more results
-    hasNextPage := len(products) > first
-    if hasNextPage {
-        products = products[:first]
-    }
-
-    return products, hasNextPage, nil
-}
-func (s *ProductService) CountProducts(ctx context.Context) (int, error) {
-    var total int64
-    err := s.db.Model(&models.Product{}).Count(&total).Error
-    return int(total), err
+	products, cursors, info := pagination.Trim(products, page, func(p *models.Product) pagination.Cursor {
+		return pagination.Cursor{Key: productSortKey(page.Sort, p), ID: p.ID}
+	})
+	return &ProductPage{Products: products, Cursors: cursors, PageInfo: info, TotalCount: int(total)}, nil
+}
+
+func productSortKey(sort pagination.Sort, p *models.Product) any {
+	switch sort.Column {
+	case "price_amount":
+		return p.Price.Amount
+	case "name":
+		return p.Name
+	case "inventory":
+		return p.Inventory
+	case "created_at":
+		return p.CreatedAt
+	}
+	return nil
 }
 
@@ -0,0 +1,536 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/docker/go-connections/nat"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tagaertner/e-commerce-graphql/pkg/apperr"
+	"github.com/tagaertner/e-commerce-graphql/pkg/inventory"
+	"github.com/tagaertner/e-commerce-graphql/pkg/money"
+	"github.com/tagaertner/e-commerce-graphql/services/products/database"
+	"github.com/tagaertner/e-commerce-graphql/services/products/models"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// === SET UP ===
+// setupTestDB starts a temporary Postgres container using testcontainers-go.
+// Requires Docker to be running. The container is created automatically for tests
+// and removed after they complete, providing an isolated Postgres instance that
+// matches production behavior. The schema comes from the service's own
+// migrations, since search and variants rely on more than AutoMigrate builds.
+func setupTestDB(t *testing.T) *gorm.DB {
+    ctx := context.Background()
+	req := testcontainers.ContainerRequest{
+		Image:        "postgres:15",
+		Env: map[string]string{
+			"POSTGRES_USER":     "testuser",
+			"POSTGRES_PASSWORD": "testpass",
+			"POSTGRES_DB":       "testdb",
+		},
+		ExposedPorts: []string{"5432/tcp"},
+		WaitingFor:   wait.ForSQL("5432/tcp", "postgres", func(host string, port nat.Port) string {
+			return fmt.Sprintf("host=%s port=%s user=testuser password=testpass dbname=testdb sslmode=disable", host, port.Port())
+		}).WithStartupTimeout(60 * time.Second), // ⏳ give it a full minute
+	}
+
+    pgContainer, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+        ContainerRequest: req,
+        Started:          true,
+    })
+    require.NoError(t, err)
+	testcontainers.CleanupContainer(t, pgContainer)
+
+    host, _ := pgContainer.Host(ctx)
+    port, _ := pgContainer.MappedPort(ctx, "5432/tcp")
+
+    dsn := fmt.Sprintf("host=%s port=%s user=testuser password=testpass dbname=testdb sslmode=disable", host, port.Port())
+    db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+    require.NoError(t, err)
+
+	migrator, err := database.Migrator(db)
+	require.NoError(t, err)
+	_, err = migrator.Up(ctx)
+	require.NoError(t, err)
+    return db
+}
+
+// setupTestEnv initializes a fresh test environment for Product service tests.
+// It sets up the database, creates a new ProductService instance,
+// and returns the DB, service, and context for use within tests.
+func setupTestEnv(t *testing.T) (*gorm.DB, *ProductService, context.Context) {
+	db := setupTestDB(t)
+	productService := NewProductService(db)
+    ctx := context.Background()
+	return db, productService, ctx
+}
+
+// Helper function for string literals
+func strPtr(s string) *string {
+    return &s
+}
+
+// usd returns an amount in cents as US dollars.
+func usd(cents int64) money.Money {
+	return money.New(cents, "USD")
+}
+
+// createProduct creates a product, with its variant, through the service.
+func createProduct(t *testing.T, productService *ProductService, name string, cents int64, stock int) *models.Product {
+	t.Helper()
+	product, err := productService.CreateProduct(context.Background(), name, usd(cents), name+" description", stock, "")
+	require.NoError(t, err)
+	return product
+}
+
+// ledgerFor returns a SKU's ledger entries, oldest first.
+func ledgerFor(t *testing.T, db *gorm.DB, sku string) []inventory.Entry {
+	t.Helper()
+	var entries []inventory.Entry
+	require.NoError(t, db.Where("sku = ?", sku).Order("created_at, id").Find(&entries).Error)
+	return entries
+}
+
+// === Tests ===
+
+// TestCreateProduct_Success verifies that a product with valid name, price, inventory, and description
+// is created successfully without errors, along with the variant and ledger entry holding its stock.
+func TestCreateProduct_Success(t *testing.T){
+	db, productService, ctx := setupTestEnv(t)
+	desc := "Simple widget"
+	created, err := productService.CreateProduct(ctx, "Widget", usd(2999), desc, 50, "")
+
+	require.NoError(t, err)
+	assert.NotEmpty(t, created.ID)
+	assert.Equal(t, "Widget", created.Name)
+	assert.Equal(t, usd(2999), created.Price)
+	assert.Equal(t, 50, created.Inventory)
+	assert.True(t, created.Available)
+	assert.Equal(t, 1, created.Version)
+
+	// The SKU defaults to the product ID
+	var variant models.ProductVariant
+	require.NoError(t, db.First(&variant, "product_id = ?", created.ID).Error)
+	assert.Equal(t, created.ID, variant.SKU)
+	assert.Equal(t, 50, variant.Inventory)
+
+	entries := ledgerFor(t, db, variant.SKU)
+	require.Len(t, entries, 1)
+	assert.Equal(t, inventory.KindRestock, entries[0].Kind)
+	assert.Equal(t, 50, entries[0].Balance)
+	assert.Equal(t, "initial stock", entries[0].Reason)
+}
+
+// TestCreateProduct_Failure verifies that a product without a name is rejected
+func TestCreateProduct_Failure(t *testing.T) {
+	_, productService, ctx := setupTestEnv(t)
+	desc := "Simple widget"
+	created, err := productService.CreateProduct(
+		ctx,
+		"",
+		usd(2999),
+		desc,
+		50,
+		"",
+	)
+	assert.Equal(t, apperr.CodeValidation, apperr.CodeOf(err))
+	assert.Nil(t, created, "Product should not be created")
+}
+
+// 	TestUpdateProduct_Success update inventory to 8
+func TestUpdateProduct_Success(t *testing.T){
+	db, productService, ctx := setupTestEnv(t)
+
+	// ---Arrange ---
+	product := createProduct(t, productService, "Widgets", 499, 10)
+
+	// Prepare input for update
+	newInventory := 8
+	updateInput := &models.UpdateProductInput{
+		Inventory: &newInventory,
+	}
+
+	// ---Act---
+	updated, err := productService.UpdateProduct(ctx, product.ID, *updateInput)
+
+	// ---Assert---
+	require.NoError(t, err)
+	require.NotNil(t, updated)
+	assert.Equal(t, newInventory, updated.Inventory)
+	assert.Equal(t, 2, updated.Version)
+
+	// The change is recorded as an adjustment
+	entries := ledgerFor(t, db, product.ID)
+	require.Len(t, entries, 2)
+	assert.Equal(t, inventory.KindAdjustment, entries[1].Kind)
+	assert.Equal(t, -2, entries[1].Change)
+	assert.Equal(t, 8, entries[1].Balance)
+}
+
+// TestUpdateProduct_OutOfStockUnavailable verifies that running out of
+// stock takes a product off sale.
+func TestUpdateProduct_OutOfStockUnavailable(t *testing.T){
+	_, productService, ctx := setupTestEnv(t)
+
+	// ---Arrange ---
+	product := createProduct(t, productService, "Widgets", 499, 10)
+	zero := 0
+
+	// ---Act---
+	updated, err := productService.UpdateProduct(ctx, product.ID, models.UpdateProductInput{Inventory: &zero})
+
+	// ---Assert---
+	require.NoError(t, err)
+	assert.Equal(t, 0, updated.Inventory)
+	assert.False(t, updated.Available, "a product without stock should be off sale")
+}
+
+//TestUpdateProduct_Failure Update non-existent ID should error.
+func TestUpdateProduct_Failure(t *testing.T){
+	_, productService, ctx := setupTestEnv(t)
+
+	// ---Arrange ---
+	newName := " Update Widget"
+	updateInput := &models.UpdateProductInput{
+		Name: &newName,
+	}
+
+	// ---Act---
+	updated, err := productService.UpdateProduct(ctx, "non-existent-id", *updateInput)
+
+	// ---Assert ---
+	assert.Error(t, err, "expected error when updating non-existent product")
+	assert.Nil(t, updated, "no product should be returned on failure")
+}
+
+//TestDeleteProduct_Success Creates product, deletes it, then verifies it is only found with includeDeleted.
+func TestDeleteProduct_Success (t *testing.T){
+	_, productService, ctx := setupTestEnv(t)
+
+	// ---Arrange --- ID, Name
+	product := createProduct(t, productService, "Old Widget", 999, 1)
+
+	_, err := productService.DeleteProduct(ctx, models.DeleteProductInput{
+		ID: &product.ID,
+		Name: strPtr("Old Widget"),
+	})
+	require.NoError(t, err)
+
+	_, err = productService.GetProductByID(ctx, product.ID, false)
+	assert.Error(t, err, "product should be deleted")
+
+	found, err := productService.GetProductByID(ctx, product.ID, true)
+	require.NoError(t, err, "deleted product should still be found with includeDeleted")
+	assert.True(t, found.DeletedAt.Valid)
+}
+
+// TestDeleteProduct_OpenOrders A product cannot be deleted while PENDING or
+// PAID orders hold stock of one of its variants.
+func TestDeleteProduct_OpenOrders(t *testing.T){
+	db, productService, ctx := setupTestEnv(t)
+	migrateOrders(t, db)
+
+	// ---Arrange --- the product's variant SKU is its ID
+	product := createProduct(t, productService, "Old Widget", 999, 5)
+	insertOrder(t, db, "order_pending", "PENDING", product.ID, 2)
+
+	// ---Act---
+	deleted, err := productService.DeleteProduct(ctx, models.DeleteProductInput{Name: strPtr("Old Widget")})
+
+	// ---Assert---
+	assert.False(t, deleted)
+	assert.Equal(t, apperr.CodeConflict, apperr.CodeOf(err))
+	_, err = productService.GetProductByID(ctx, product.ID, false)
+	require.NoError(t, err, "product should not be deleted")
+
+	// Once the order is cancelled the product can go
+	require.NoError(t, db.Exec(`UPDATE orders SET status = 'CANCELLED' WHERE id = 'order_pending'`).Error)
+	deleted, err = productService.DeleteProduct(ctx, models.DeleteProductInput{ID: &product.ID})
+	require.NoError(t, err)
+	assert.True(t, deleted)
+}
+
+//TestDeleteProduct_Failure Delete with bad ID returns error, no rows affected.
+func TestDeleteProduct_Failure(t *testing.T){
+	db, productServices, ctx := setupTestEnv(t)
+
+	var countBefore int64
+	db.Model(&models.Product{}).Count(&countBefore)
+
+	// Capture both return values and assert on the error only
+	_, err := productServices.DeleteProduct(ctx, models.DeleteProductInput{
+		ID: strPtr("non-existent-id"),
+		Name: strPtr("Old Widget"),
+	})
+	assert.Equal(t, apperr.CodeNotFound, apperr.CodeOf(err), "should return error for non-existant id")
+
+	var countAfter int64
+	db.Model(&models.Product{}).Count(&countAfter)
+	assert.Equal(t, countBefore, countAfter, "no rows should be deleted")
+
+}
+
+// TestCreateProduct_ZeroOrNegativeInventory Accept zero inventory, reject inventory < 0.
+func TestCreateProduct_ZeroOrNegativeInventory(t *testing.T){
+	_, productService, ctx := setupTestEnv(t)
+
+	// ---Arrange --- name, price, description, inventory, sku
+	created, err := productService.CreateProduct(
+		ctx,
+		"Zero Widget",
+		usd(5199),
+		"Something invisable but awesome",
+		0,
+		"",
+	)
+
+	// ---Assert---
+	require.NoError(t, err, "should accept zero inventory")
+	assert.Equal(t, 0, created.Inventory)
+	assert.False(t, created.Available, "a product without stock starts off sale")
+
+	// Test negative inventory
+	created, err = productService.CreateProduct(
+		ctx,
+		"Nil Widget",
+		usd(2999),
+		"Not so awesome",
+		-5,
+		"",
+	)
+
+	assert.Equal(t, apperr.CodeValidation, apperr.CodeOf(err), "should reject negative inventory")
+	assert.Nil(t, created, "product should not be created with negative inventory")
+}
+
+// TestDeleteProduct_Twice First delete succeeds; second delete returns “not found”. id and name
+func TestDeleteProduct_Twice (t *testing.T){
+	_, productService, ctx := setupTestEnv(t)
+
+	// ---Arrange --
+	product := createProduct(t, productService, "Gadget", 1999, 3)
+
+	// ---Act: First dlection should succeed ---
+	success, err := productService.DeleteProduct(ctx, models.DeleteProductInput{
+		ID: &product.ID,
+		Name: strPtr("Gadget"),
+	})
+	require.NoError(t, err, "first deletion should succeed")
+	require.True(t, success, "first deletion should return true")
+
+	// ---Act: Second deletion should fail gracefully ---
+	success, err = productService.DeleteProduct(ctx, models.DeleteProductInput{
+		ID: &product.ID,
+		Name: strPtr("Gadget"),
+	})
+
+	// ---Assert ---
+	assert.Error(t, err, "second deletion should return an error")
+	assert.False(t, success, "second deletion should return false")
+	assert.Contains(t, err.Error(), "not found", "error shoul dindicate product not found")
+}
+
+// TestRestoreProduct_Success Deleted product comes back.
+func TestRestoreProduct_Success(t *testing.T){
+	_, productService, ctx := setupTestEnv(t)
+
+	// ---Arrange ---
+	product := createProduct(t, productService, "Gadget", 1999, 3)
+	_, err := productService.DeleteProduct(ctx, models.DeleteProductInput{ID: &product.ID})
+	require.NoError(t, err)
+
+	// ---Act ---
+	restored, err := productService.RestoreProduct(ctx, product.ID)
+
+	// ---Assert ---
+	require.NoError(t, err)
+	assert.False(t, restored.DeletedAt.Valid)
+
+	// Restoring a product that is not deleted fails
+	_, err = productService.RestoreProduct(ctx, product.ID)
+	assert.Equal(t, apperr.CodeNotFound, apperr.CodeOf(err))
+}
+
+//TestGetAllProducts_Success Insert multiple products, call GetAllProducts(), verify correct count + order.
+func TestGetAllProducts_Success(t *testing.T){
+	_, productService, _ := setupTestEnv(t)
+		// Create some products
+	createProduct(t, productService, "Widget", 5999, 40)
+	createProduct(t, productService, "Gadget", 3899, 51)
+	createProduct(t, productService, "Widgy Gadget", 5599, 30)
+
+	// ---Act ---
+	products, err :=productService.GetAllProducts()
+
+	// -- Assert ---
+	require.NoError(t, err)
+	require.Len(t, products, 3, "should return all inserted products")
+
+	// Verfiy all 3 names exits
+	names := []string{products[0].Name, products[1].Name, products[2].Name}
+	assert.Contains(t, names, "Widget")
+	assert.Contains(t, names, "Gadget")
+	assert.Contains(t, names, "Widgy Gadget")
+}
+// TestGetProductByID_Success Valid ID returns correct product.
+func TestGetProductByID_Success(t *testing.T){
+	_, productService, ctx := setupTestEnv(t)
+		// Create some products
+	createProduct(t, productService, "Widget", 5999, 40)
+	createProduct(t, productService, "Gadget", 3899, 51)
+	product3 := createProduct(t, productService, "Thingamajig", 1999, 30)
+
+	// ---Act ---
+	product, err :=productService.GetProductByID(ctx, product3.ID, false)
+
+	// ---Assert ---
+	require.NoError(t, err)
+	require.NotNil(t, product)
+	assert.Equal(t, product3.ID, product.ID)
+	assert.Equal(t, "Thingamajig", product.Name)
+	assert.Equal(t, usd(1999), product.Price)
+
+}
+
+// TestGetProductByID_FailureInvalid ID returns nil and error.
+func TestGetProductByID_Failure (t *testing.T){
+	_, productService, ctx := setupTestEnv(t)
+
+	// ---Arrange ---
+	// No products created for this
+
+	// ---Act ---
+	products, err := productService.GetProductByID(ctx, "p2", false)
+
+	// ---Assert---
+	require.Error(t, err, "expected error when querying non-existent product")
+	require.Nil(t, products, "expected nil product for non-existent ID")
+
+}
+
+//TestRestockProduct_Success
+func TestRestockProduct_Success(t *testing.T){
+	db, productServices, ctx := setupTestEnv(t)
+
+	// ---Arrange ---
+	product := createProduct(t, productServices, "Widget", 999, 5)
+
+	// ---Act ---
+	updated, err := productServices.RestockProduct(ctx, product.ID, 10, nil)
+
+	// ---Assert ---
+	require.NoError(t, err)
+	assert.Equal(t, 15, updated.Inventory)
+
+	entries := ledgerFor(t, db, product.ID)
+	require.Len(t, entries, 2)
+	assert.Equal(t, inventory.KindRestock, entries[1].Kind)
+	assert.Equal(t, 10, entries[1].Change)
+	assert.Equal(t, 15, entries[1].Balance)
+}
+
+// TestRestockProduct_Failure
+func TestRestockProduct_Failure(t *testing.T){
+	_, productService, ctx := setupTestEnv(t)
+
+	//---Act---
+	product, err := productService.RestockProduct(ctx, "non-existent-id", 10, nil)
+
+	// ----Assert ---
+	assert.Error(t, err, "should return error when restocking non-existent product")
+	assert.Nil(t, product, "no product should be returned on failure")
+
+}
+
+// TestRestockProduct_Failure_NegativeAmount reject negative amount
+func TestRestockProduct_Failure_NegativeAmount(t *testing.T){
+	db, productService, ctx := setupTestEnv(t)
+
+	product := createProduct(t, productService, "Thingamajig", 1999, 30)
+
+	updated, err := productService.RestockProduct(ctx, product.ID, -10, nil)
+
+	// ---Assert---
+	assert.Equal(t, apperr.CodeValidation, apperr.CodeOf(err), "should reject negative restock amount")
+	assert.Nil(t, updated)
+	assert.Len(t, ledgerFor(t, db, product.ID), 1, "nothing should be recorded")
+
+}
+
+//TestSetProductAvailability
+func TestSetProductAvailability_Success(t *testing.T){
+	db, productService, ctx := setupTestEnv(t)
+
+	product := createProduct(t, productService, "Gadget", 1999, 12)
+
+	// ---Act ---
+	updated , err := productService.SetProductAvailability(ctx, product.ID, false, nil)
+
+	// --Assert --
+	require.NoError(t, err)
+	assert.False(t, updated.Available)
+	assert.Equal(t, 12, updated.Inventory, "stock should be untouched")
+
+	var variant models.ProductVariant
+	require.NoError(t, db.First(&variant, "product_id = ?", product.ID).Error)
+	assert.False(t, variant.Available)
+}
+
+func TestSetProductAvailability_Failure(t *testing.T){
+	_, productService, ctx := setupTestEnv(t)
+
+	// ---Act ---
+	product, err := productService.SetProductAvailability(ctx, "non-existent-id", false, nil)
+
+	// ---Assert ---
+	assert.Error(t, err, "should return error when toggling non-existent product")
+	assert.Nil(t, product)
+}
+
+// TestSetProductAvailability_Failure_NotFound
+func TestSetProductAvailability_Failure_NotFound(t *testing.T){
+	_,productService, ctx := setupTestEnv(t)
+
+	// ---Act ---
+	product, err := productService.SetProductAvailability(ctx, "non-existent-id", false, nil)
+
+	// --- Assert ---
+	assert.Error(t, err, "should return error when product does not exist")
+	assert.Nil(t, product, "expected nil product on failure")
+}
+
+// TestPurgeDeleted Only products deleted before the cutoff are removed.
+func TestPurgeDeleted(t *testing.T){
+	db, productService, ctx := setupTestEnv(t)
+
+	// ---Arrange ---
+	kept := createProduct(t, productService, "Widget", 999, 1)
+	purged := createProduct(t, productService, "Gadget", 999, 1)
+	for _, id := range []string{kept.ID, purged.ID} {
+		_, err := productService.DeleteProduct(ctx, models.DeleteProductInput{ID: strPtr(id)})
+		require.NoError(t, err)
+	}
+	require.NoError(t, db.Unscoped().Model(&models.Product{}).
+		Where("id = ?", purged.ID).
+		Update("deleted_at", time.Now().Add(-48*time.Hour)).Error)
+
+	// ---Act ---
+	count, err := productService.PurgeDeleted(ctx, time.Now().Add(-24*time.Hour))
+
+	// ---Assert ---
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	_, err = productService.GetProductByID(ctx, purged.ID, true)
+	assert.Error(t, err, "purged product should be gone")
+	_, err = productService.GetProductByID(ctx, kept.ID, true)
+	assert.NoError(t, err, "recently deleted product should be kept")
+	assert.Len(t, ledgerFor(t, db, purged.ID), 1, "ledger entries should be kept")
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/tagaertner/e-commerce-graphql/pkg/apperr"
+	"github.com/tagaertner/e-commerce-graphql/services/products/models"
+	"gorm.io/gorm"
+)
+
+// maxVersionRetries is how often a product write that lost a race with
+// another writer is retried from a fresh read.
+const maxVersionRetries = 3
+
+// ConflictError is returned when a product is not at the version the
+// client expected, or kept changing underneath a write until it gave up.
+type ConflictError struct {
+	ProductID string
+	Expected  int
+	Current   int
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("product %s was changed concurrently: expected version %d, current version %d", e.ProductID, e.Expected, e.Current)
+}
+
+// conflict reports a ConflictError as CONFLICT, with the current version in
+// the error's extensions.
+func conflict(e *ConflictError) error {
+	err := apperr.Wrap(apperr.CodeConflict, e)
+	err.Extensions = map[string]interface{}{"currentVersion": e.Current}
+	return err
+}
+
+// errVersionChanged aborts a write whose product was changed after it was
+// read.
+var errVersionChanged = errors.New("product version changed")
+
+// writeProduct runs apply against the current version of a product and
+// commits only if nobody changed the product meanwhile: the transaction ends
+// with a conditional update of the fields apply returns that also bumps the
+// version. A write that loses the race is retried from a fresh read, unless
+// the client pinned expectedVersion, in which case it is a ConflictError.
+//
+// The version only covers writes made here. Orders and variant mutations
+// move stock without bumping it, so a pinned expectedVersion does not guard
+// against inventory changing.
+func (s *ProductService) writeProduct(ctx context.Context, id string, expectedVersion *int, apply func(tx *gorm.DB, product *models.Product) (map[string]interface{}, error)) (*models.Product, error) {
+	for attempt := 0; ; attempt++ {
+		var product models.Product
+		err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := tx.First(&product, "id = ?", id).Error; err != nil {
+				return err
+			}
+			if expectedVersion != nil && *expectedVersion != product.Version {
+				return conflict(&ConflictError{ProductID: id, Expected: *expectedVersion, Current: product.Version})
+			}
+
+			updates, err := apply(tx, &product)
+			if err != nil {
+				return err
+			}
+			if updates == nil {
+				updates = map[string]interface{}{}
+			}
+			updates["version"] = gorm.Expr("version + 1")
+			result := tx.Model(&models.Product{}).
+				Where("id = ? AND version = ?", id, product.Version).
+				Updates(updates)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return errVersionChanged
+			}
+			return nil
+		})
+		if !errors.Is(err, errVersionChanged) {
+			if err != nil {
+				return nil, err
+			}
+			return s.GetProductByID(ctx, id, false)
+		}
+
+		if expectedVersion != nil || attempt == maxVersionRetries {
+			current, err := s.GetProductByID(ctx, id, false)
+			if err != nil {
+				return nil, err
+			}
+			return nil, conflict(&ConflictError{ProductID: id, Expected: product.Version, Current: current.Version})
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tagaertner/e-commerce-graphql/pkg/apperr"
+	"github.com/tagaertner/e-commerce-graphql/services/products/models"
+	"gorm.io/gorm"
+)
+
+// bumpVersion stands in for another writer changing the product outside the
+// write's transaction.
+func bumpVersion(t *testing.T, db *gorm.DB, id string) {
+	t.Helper()
+	require.NoError(t, db.Model(&models.Product{}).Where("id = ?", id).
+		Update("version", gorm.Expr("version + 1")).Error)
+}
+
+// requireConflict checks err is a CONFLICT carrying a ConflictError and the
+// current version in its extensions.
+func requireConflict(t *testing.T, err error, expected, current int) {
+	t.Helper()
+	var appErr *apperr.Error
+	require.True(t, errors.As(err, &appErr), "expected an apperr.Error, got %v", err)
+	assert.Equal(t, apperr.CodeConflict, appErr.Code)
+	assert.Equal(t, current, appErr.Extensions["currentVersion"])
+
+	var conflictErr *ConflictError
+	require.True(t, errors.As(err, &conflictErr))
+	assert.Equal(t, expected, conflictErr.Expected)
+	assert.Equal(t, current, conflictErr.Current)
+}
+
+// TestConflict reports the current version to clients.
+func TestConflict(t *testing.T){
+	err := conflict(&ConflictError{ProductID: "p1", Expected: 2, Current: 4})
+
+	requireConflict(t, err, 2, 4)
+	assert.Contains(t, err.Error(), "expected version 2, current version 4")
+}
+
+// TestWriteProduct_Success applies the updates and bumps the version.
+func TestWriteProduct_Success(t *testing.T){
+	_, productService, ctx := setupTestEnv(t)
+	product := createProduct(t, productService, "Widget", 999, 5)
+
+	// ---Act ---
+	updated, err := productService.writeProduct(ctx, product.ID, &product.Version, func(tx *gorm.DB, p *models.Product) (map[string]interface{}, error) {
+		return map[string]interface{}{"name": "Renamed Widget"}, nil
+	})
+
+	// ---Assert ---
+	require.NoError(t, err)
+	assert.Equal(t, "Renamed Widget", updated.Name)
+	assert.Equal(t, product.Version+1, updated.Version)
+}
+
+// TestWriteProduct_StaleExpectedVersion refuses a write pinned to another
+// version without running it.
+func TestWriteProduct_StaleExpectedVersion(t *testing.T){
+	_, productService, ctx := setupTestEnv(t)
+	product := createProduct(t, productService, "Widget", 999, 5)
+	stale := product.Version + 1
+
+	// ---Act ---
+	calls := 0
+	updated, err := productService.writeProduct(ctx, product.ID, &stale, func(tx *gorm.DB, p *models.Product) (map[string]interface{}, error) {
+		calls++
+		return map[string]interface{}{"name": "Renamed Widget"}, nil
+	})
+
+	// ---Assert ---
+	assert.Nil(t, updated)
+	assert.Equal(t, 0, calls, "apply should not run")
+	requireConflict(t, err, stale, product.Version)
+
+	current, err := productService.GetProductByID(ctx, product.ID, false)
+	require.NoError(t, err)
+	assert.Equal(t, "Widget", current.Name)
+}
+
+// TestWriteProduct_RetriesLostRace retries a write whose conditional update
+// found the product changed, and commits once it wins.
+func TestWriteProduct_RetriesLostRace(t *testing.T){
+	db, productService, ctx := setupTestEnv(t)
+	product := createProduct(t, productService, "Widget", 999, 5)
+
+	// ---Act --- another writer gets in during the first attempt only
+	calls := 0
+	updated, err := productService.writeProduct(ctx, product.ID, nil, func(tx *gorm.DB, p *models.Product) (map[string]interface{}, error) {
+		calls++
+		if calls == 1 {
+			bumpVersion(t, db, product.ID)
+		}
+		return map[string]interface{}{"name": "Renamed Widget"}, nil
+	})
+
+	// ---Assert ---
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, "Renamed Widget", updated.Name)
+	assert.Equal(t, product.Version+2, updated.Version)
+}
+
+// TestWriteProduct_PinnedVersionLostRace does not retry a write pinned to a
+// version, since a retry could only see a different version.
+func TestWriteProduct_PinnedVersionLostRace(t *testing.T){
+	db, productService, ctx := setupTestEnv(t)
+	product := createProduct(t, productService, "Widget", 999, 5)
+
+	// ---Act ---
+	calls := 0
+	updated, err := productService.writeProduct(ctx, product.ID, &product.Version, func(tx *gorm.DB, p *models.Product) (map[string]interface{}, error) {
+		calls++
+		bumpVersion(t, db, product.ID)
+		return map[string]interface{}{"name": "Renamed Widget"}, nil
+	})
+
+	// ---Assert ---
+	assert.Nil(t, updated)
+	assert.Equal(t, 1, calls)
+	requireConflict(t, err, product.Version, product.Version+1)
+}
+
+// TestWriteProduct_GivesUpAfterMaxRetries returns a ConflictError once the
+// product has changed under every attempt.
+func TestWriteProduct_GivesUpAfterMaxRetries(t *testing.T){
+	db, productService, ctx := setupTestEnv(t)
+	product := createProduct(t, productService, "Widget", 999, 5)
+
+	// ---Act ---
+	calls := 0
+	updated, err := productService.writeProduct(ctx, product.ID, nil, func(tx *gorm.DB, p *models.Product) (map[string]interface{}, error) {
+		calls++
+		bumpVersion(t, db, product.ID)
+		return map[string]interface{}{"name": "Renamed Widget"}, nil
+	})
+
+	// ---Assert --- the first attempt plus maxVersionRetries retries
+	assert.Nil(t, updated)
+	assert.Equal(t, maxVersionRetries+1, calls)
+	requireConflict(t, err, product.Version+calls-1, product.Version+calls)
+
+	current, err := productService.GetProductByID(ctx, product.ID, false)
+	require.NoError(t, err)
+	assert.Equal(t, "Widget", current.Name, "no attempt should have been committed")
+}
+
+// TestWriteProduct_StockDoesNotBumpVersion leaves the version alone when
+// only a variant's stock moves.
+func TestWriteProduct_StockDoesNotBumpVersion(t *testing.T){
+	_, productService, ctx := setupTestEnv(t)
+	product := createProduct(t, productService, "Widget", 999, 5)
+	variants := NewVariantService(productService.db)
+
+	// ---Act ---
+	_, err := variants.RestockVariant(ctx, product.ID, 3, "delivery")
+	require.NoError(t, err)
+
+	// ---Assert ---
+	current, err := productService.GetProductByID(ctx, product.ID, false)
+	require.NoError(t, err)
+	assert.Equal(t, 8, current.Inventory)
+	assert.Equal(t, product.Version, current.Version)
+}
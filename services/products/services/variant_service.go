@@ -0,0 +1,388 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tagaertner/e-commerce-graphql/pkg/apperr"
+	"github.com/tagaertner/e-commerce-graphql/pkg/auth"
+	"github.com/tagaertner/e-commerce-graphql/pkg/inventory"
+	"github.com/tagaertner/e-commerce-graphql/pkg/money"
+	"github.com/tagaertner/e-commerce-graphql/pkg/pagination"
+	"github.com/tagaertner/e-commerce-graphql/pkg/validate"
+	"github.com/tagaertner/e-commerce-graphql/services/products/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type VariantService struct {
+	db *gorm.DB
+}
+
+func NewVariantService(db *gorm.DB) *VariantService {
+	return &VariantService{db: db}
+}
+
+func (s *VariantService) GetVariantBySKU(ctx context.Context, sku string) (*models.ProductVariant, error) {
+	var variant models.ProductVariant
+	if err := s.db.WithContext(ctx).First(&variant, "sku = ?", sku).Error; err != nil {
+		return nil, err
+	}
+	return &variant, nil
+}
+
+// GetVariantsBySKUs loads many variants in one query, keyed by SKU. Unknown
+// SKUs are left out.
+func (s *VariantService) GetVariantsBySKUs(ctx context.Context, skus []string) (map[string]*models.ProductVariant, error) {
+	var variants []*models.ProductVariant
+	if err := s.db.WithContext(ctx).Where("sku IN ?", skus).Find(&variants).Error; err != nil {
+		return nil, err
+	}
+	bySKU := make(map[string]*models.ProductVariant, len(variants))
+	for _, v := range variants {
+		bySKU[v.SKU] = v
+	}
+	return bySKU, nil
+}
+
+// GetVariantsByProductIDs loads the variants of many products in one query,
+// keyed by product ID, oldest first.
+func (s *VariantService) GetVariantsByProductIDs(ctx context.Context, productIDs []string) (map[string][]*models.ProductVariant, error) {
+	var variants []*models.ProductVariant
+	if err := s.db.WithContext(ctx).Where("product_id IN ?", productIDs).Order("created_at, id").Find(&variants).Error; err != nil {
+		return nil, err
+	}
+	byProduct := make(map[string][]*models.ProductVariant, len(productIDs))
+	for _, v := range variants {
+		byProduct[v.ProductID] = append(byProduct[v.ProductID], v)
+	}
+	return byProduct, nil
+}
+
+func (s *VariantService) CreateVariant(ctx context.Context, input models.CreateProductVariantInput) (*models.ProductVariant, error) {
+	variant := &models.ProductVariant{
+		ID:        "var_" + uuid.NewString(),
+		ProductID: input.ProductID,
+		SKU:       strings.TrimSpace(input.SKU),
+		Options:   variantOptions(input.Options),
+		Available: input.Inventory > 0,
+	}
+	violations := checkVariant(variant)
+	if input.Inventory < 0 {
+		violations.Add("inventory", "cannot be negative")
+	}
+	if err := violations.Err(); err != nil {
+		return nil, err
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var product models.Product
+		if err := tx.First(&product, "id = ?", input.ProductID).Error; err != nil {
+			return err
+		}
+		if input.Price != nil {
+			amount, err := priceOverride(&product, *input.Price)
+			if err != nil {
+				return err
+			}
+			variant.PriceOverride = &amount
+		}
+		if err := tx.Create(variant).Error; err != nil {
+			return err
+		}
+		return initialStock(ctx, tx, variant, input.Inventory)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return variant, nil
+}
+
+// UpdateVariant changes a variant's options, price and stock. Its SKU is
+// fixed once created, as orders, carts and the ledger refer to it.
+func (s *VariantService) UpdateVariant(ctx context.Context, id string, input models.UpdateProductVariantInput) (*models.ProductVariant, error) {
+	var variant models.ProductVariant
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&variant, "id = ?", id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apperr.NotFound("variant %s not found", id)
+			}
+			return err
+		}
+
+		if input.Options != nil {
+			variant.Options = variantOptions(input.Options)
+		}
+		if input.Price != nil {
+			var product models.Product
+			if err := tx.First(&product, "id = ?", variant.ProductID).Error; err != nil {
+				return err
+			}
+			amount, err := priceOverride(&product, *input.Price)
+			if err != nil {
+				return err
+			}
+			variant.PriceOverride = &amount
+		}
+		if err := checkVariant(&variant).Err(); err != nil {
+			return err
+		}
+
+		// Stock only changes through the ledger
+		if err := tx.Omit("inventory", "available").Save(&variant).Error; err != nil {
+			return err
+		}
+		if input.Inventory != nil {
+			return setStock(ctx, tx, &variant, *input.Inventory, "set by updateProductVariant")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &variant, nil
+}
+
+// DeleteVariant removes a variant. Any stock it still holds is written off
+// with a closing adjustment first, so its ledger ends at zero. A variant that
+// PENDING or PAID orders still hold stock of cannot be deleted.
+func (s *VariantService) DeleteVariant(ctx context.Context, id string) (bool, error) {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var variant models.ProductVariant
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&variant, "id = ?", id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apperr.NotFound("variant %s not found", id)
+			}
+			return err
+		}
+		// Orders reserve stock with the variant locked, so none can start
+		// holding it between this check and the delete
+		open, err := openOrderCount(tx, []string{variant.SKU})
+		if err != nil {
+			return err
+		}
+		if open > 0 {
+			return apperr.Conflict("variant %s is held by %d open orders", variant.SKU, open)
+		}
+		if err := setStock(ctx, tx, &variant, 0, "variant deleted"); err != nil {
+			return err
+		}
+		if err := tx.Delete(&variant).Error; err != nil {
+			return err
+		}
+		return inventory.SyncProducts(tx, variant.ProductID)
+	})
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RestockVariant adds quantity to a variant's inventory.
+func (s *VariantService) RestockVariant(ctx context.Context, sku string, quantity int, reason string) (*models.ProductVariant, error) {
+	if quantity <= 0 {
+		return nil, apperr.Field("quantity", "must be greater than zero")
+	}
+
+	var variant models.ProductVariant
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&variant, "sku = ?", sku).Error; err != nil {
+			return err
+		}
+		return restock(ctx, tx, &variant, quantity, reason)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &variant, nil
+}
+
+// SetVariantAvailability puts a variant on or takes it off sale. A variant
+// without stock cannot be put on sale.
+func (s *VariantService) SetVariantAvailability(ctx context.Context, sku string, available bool) (*models.ProductVariant, error) {
+	var variant models.ProductVariant
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&variant, "sku = ?", sku).Error; err != nil {
+			return err
+		}
+		return setAvailability(tx, &variant, available)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &variant, nil
+}
+
+// AdjustStock corrects a variant's inventory by change units, e.g. after a
+// stock count. A variant left without stock is taken off sale.
+func (s *VariantService) AdjustStock(ctx context.Context, sku string, change int, reason string) (*models.ProductVariant, error) {
+	if change == 0 {
+		return nil, apperr.Field("change", "cannot be zero")
+	}
+	if strings.TrimSpace(reason) == "" {
+		return nil, apperr.Field("reason", "is required")
+	}
+
+	var variant models.ProductVariant
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&variant, "sku = ?", sku).Error; err != nil {
+			return err
+		}
+		return recordStock(ctx, tx, &variant, inventory.KindAdjustment, change, strings.TrimSpace(reason))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &variant, nil
+}
+
+// LedgerSort is the order a variant's ledger is paged in, oldest first.
+var LedgerSort = pagination.Sort{Name: "CREATED_AT", Column: "created_at", Key: time.Time{}}
+
+// LedgerPage is one page of a variant's ledger.
+type LedgerPage struct {
+	Entries    []*inventory.Entry
+	Cursors    []string
+	PageInfo   pagination.PageInfo
+	TotalCount int
+}
+
+// GetLedgerPage returns one page of the stock movements of a SKU.
+func (s *VariantService) GetLedgerPage(ctx context.Context, sku string, page pagination.Page) (*LedgerPage, error) {
+	scope := s.db.WithContext(ctx).Model(&inventory.Entry{}).Where("sku = ?", sku)
+
+	var total int64
+	if err := scope.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	var entries []*inventory.Entry
+	if err := page.Apply(scope.Session(&gorm.Session{})).Find(&entries).Error; err != nil {
+		return nil, err
+	}
+
+	entries, cursors, info := pagination.Trim(entries, page, func(e *inventory.Entry) pagination.Cursor {
+		return pagination.Cursor{Key: e.CreatedAt, ID: e.ID}
+	})
+	return &LedgerPage{Entries: entries, Cursors: cursors, PageInfo: info, TotalCount: int(total)}, nil
+}
+
+func restock(ctx context.Context, tx *gorm.DB, variant *models.ProductVariant, quantity int, reason string) error {
+	return recordStock(ctx, tx, variant, inventory.KindRestock, quantity, reason)
+}
+
+// initialStock records the stock a new variant starts with.
+func initialStock(ctx context.Context, tx *gorm.DB, variant *models.ProductVariant, quantity int) error {
+	if quantity == 0 {
+		return inventory.SyncProducts(tx, variant.ProductID)
+	}
+	return restock(ctx, tx, variant, quantity, "initial stock")
+}
+
+// setStock records the adjustment that takes a locked variant's inventory
+// to quantity.
+func setStock(ctx context.Context, tx *gorm.DB, variant *models.ProductVariant, quantity int, reason string) error {
+	if quantity < 0 {
+		return apperr.Field("inventory", "cannot be negative")
+	}
+	if quantity == variant.Inventory {
+		return nil
+	}
+	return recordStock(ctx, tx, variant, inventory.KindAdjustment, quantity-variant.Inventory, reason)
+}
+
+// recordStock moves a variant's stock through the ledger, takes the variant
+// off sale if it runs out and updates its product's totals.
+func recordStock(ctx context.Context, tx *gorm.DB, variant *models.ProductVariant, kind inventory.Kind, quantity int, reason string) error {
+	entry, err := inventory.Record(tx, inventory.Movement{
+		SKU:      variant.SKU,
+		Kind:     kind,
+		Quantity: quantity,
+		Reason:   reason,
+		Actor:    auth.ActorFromContext(ctx),
+	})
+	if err != nil {
+		return err
+	}
+	variant.Inventory = entry.Balance
+	if variant.Inventory == 0 && variant.Available {
+		variant.Available = false
+		if err := tx.Model(variant).Update("available", false).Error; err != nil {
+			return err
+		}
+	}
+	return inventory.SyncProducts(tx, variant.ProductID)
+}
+
+func setAvailability(tx *gorm.DB, variant *models.ProductVariant, available bool) error {
+	if variant.Available == available {
+		return apperr.Conflict("variant %s already availability set to %t", variant.SKU, available)
+	}
+	if available && variant.Inventory <= 0 {
+		return apperr.Conflict("cannot mark variant %s as available with zero inventory", variant.SKU)
+	}
+	variant.Available = available
+	if err := tx.Model(variant).Update("available", available).Error; err != nil {
+		return err
+	}
+	return inventory.SyncProducts(tx, variant.ProductID)
+}
+
+// soleVariant locks and returns the only variant of a product, for the
+// product level stock mutations that predate variants.
+func soleVariant(tx *gorm.DB, productID string) (*models.ProductVariant, error) {
+	var variants []models.ProductVariant
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("product_id = ?", productID).
+		Limit(2).
+		Find(&variants).Error; err != nil {
+		return nil, err
+	}
+	switch len(variants) {
+	case 0:
+		return nil, apperr.NotFound("product %s has no variants", productID)
+	case 1:
+		return &variants[0], nil
+	}
+	return nil, apperr.Conflict("product %s has several variants; change stock by SKU instead", productID)
+}
+
+// priceOverride returns the amount of a variant price, which must be in its
+// product's currency.
+func priceOverride(product *models.Product, price money.Money) (int64, error) {
+	if price.Currency != product.Price.Currency {
+		return 0, apperr.Errorf(apperr.CodeValidation, "variant price must be in the product's currency %s: %w", product.Price.Currency, money.ErrCurrencyMismatch)
+	}
+	return price.Amount, nil
+}
+
+// checkVariant returns what is wrong with a variant about to be saved.
+func checkVariant(v *models.ProductVariant) validate.Violations {
+	var violations validate.Violations
+	if v.SKU == "" {
+		violations.Add("sku", "is required")
+	}
+	if v.PriceOverride != nil && *v.PriceOverride <= 0 {
+		violations.Add("price", "must be greater than zero")
+	}
+	for _, o := range v.Options {
+		if o == nil || strings.TrimSpace(o.Name) == "" || strings.TrimSpace(o.Value) == "" {
+			violations.Add("options", "option names and values are required")
+			break
+		}
+	}
+	return violations
+}
+
+func variantOptions(input []*models.VariantOption) models.VariantOptions {
+	options := make(models.VariantOptions, 0, len(input))
+	for _, o := range input {
+		if o != nil {
+			options = append(options, o)
+		}
+	}
+	return options
+}
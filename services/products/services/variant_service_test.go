@@ -0,0 +1,248 @@
+package services
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tagaertner/e-commerce-graphql/pkg/apperr"
+	"github.com/tagaertner/e-commerce-graphql/pkg/inventory"
+	"github.com/tagaertner/e-commerce-graphql/pkg/migrate"
+	"github.com/tagaertner/e-commerce-graphql/pkg/money"
+	"github.com/tagaertner/e-commerce-graphql/services/products/models"
+	"gorm.io/gorm"
+)
+
+// migrateOrders adds the orders service's tables, which share the database.
+func migrateOrders(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	m, err := migrate.New(db, "orders", os.DirFS("../../orders/database/migrations"))
+	require.NoError(t, err)
+	_, err = m.Up(context.Background())
+	require.NoError(t, err)
+}
+
+// insertOrder writes an order for quantity of a SKU straight into the
+// orders service's tables.
+func insertOrder(t *testing.T, db *gorm.DB, id, status, sku string, quantity int) {
+	t.Helper()
+	require.NoError(t, db.Exec(`INSERT INTO orders (id, user_id, status, total_price_amount, created_at) VALUES (?, 'u1', ?, 0, now())`, id, status).Error)
+	require.NoError(t, db.Exec(`INSERT INTO order_line_items (id, order_id, product_id, sku, quantity, unit_price_amount) VALUES (?, ?, '', ?, ?, 0)`, id+"-1", id, sku, quantity).Error)
+}
+
+// TestCreateVariant_Success A new variant adds its stock to the product's totals.
+func TestCreateVariant_Success(t *testing.T){
+	db, productService, ctx := setupTestEnv(t)
+	variantService := NewVariantService(db)
+
+	// ---Arrange ---
+	product := createProduct(t, productService, "T-Shirt", 1999, 5)
+	price := money.New(2499, "USD")
+
+	// ---Act ---
+	variant, err := variantService.CreateVariant(ctx, models.CreateProductVariantInput{
+		ProductID: product.ID,
+		SKU:       "TSHIRT-XL",
+		Options:   []*models.VariantOption{{Name: "size", Value: "XL"}},
+		Price:     &price,
+		Inventory: 3,
+	})
+
+	// ---Assert ---
+	require.NoError(t, err)
+	assert.Equal(t, "TSHIRT-XL", variant.SKU)
+	assert.Equal(t, 3, variant.Inventory)
+	require.NotNil(t, variant.PriceOverride)
+	assert.Equal(t, int64(2499), *variant.PriceOverride)
+
+	updated, err := productService.GetProductByID(ctx, product.ID, false)
+	require.NoError(t, err)
+	assert.Equal(t, 8, updated.Inventory)
+
+	entries := ledgerFor(t, db, "TSHIRT-XL")
+	require.Len(t, entries, 1)
+	assert.Equal(t, "initial stock", entries[0].Reason)
+}
+
+// TestCreateVariant_Failure Bad options, duplicate SKUs and prices in another currency are rejected.
+func TestCreateVariant_Failure(t *testing.T){
+	db, productService, ctx := setupTestEnv(t)
+	variantService := NewVariantService(db)
+
+	product := createProduct(t, productService, "T-Shirt", 1999, 5)
+	euros := money.New(2499, "EUR")
+
+	_, err := variantService.CreateVariant(ctx, models.CreateProductVariantInput{
+		ProductID: product.ID,
+		SKU:       "TSHIRT-XL",
+		Options:   []*models.VariantOption{{Name: "size"}},
+	})
+	assert.Equal(t, apperr.CodeValidation, apperr.CodeOf(err), "option values are required")
+
+	_, err = variantService.CreateVariant(ctx, models.CreateProductVariantInput{
+		ProductID: product.ID,
+		SKU:       "TSHIRT-XL",
+		Price:     &euros,
+	})
+	assert.Equal(t, apperr.CodeValidation, apperr.CodeOf(err), "price must be in the product's currency")
+
+	_, err = variantService.CreateVariant(ctx, models.CreateProductVariantInput{
+		ProductID: "non-existent-id",
+		SKU:       "TSHIRT-XL",
+	})
+	assert.Equal(t, apperr.CodeNotFound, apperr.CodeOf(err))
+}
+
+// TestUpdateVariant_Success Stock set through an update is recorded as an adjustment.
+func TestUpdateVariant_Success(t *testing.T){
+	db, productService, ctx := setupTestEnv(t)
+	variantService := NewVariantService(db)
+
+	// ---Arrange ---
+	product := createProduct(t, productService, "T-Shirt", 1999, 5)
+	variant, err := variantService.GetVariantBySKU(ctx, product.ID)
+	require.NoError(t, err)
+	inventoryLevel := 9
+
+	// ---Act ---
+	updated, err := variantService.UpdateVariant(ctx, variant.ID, models.UpdateProductVariantInput{
+		Options:   []*models.VariantOption{{Name: "size", Value: "M"}},
+		Inventory: &inventoryLevel,
+	})
+
+	// ---Assert ---
+	require.NoError(t, err)
+	assert.Equal(t, 9, updated.Inventory)
+	require.Len(t, updated.Options, 1)
+	assert.Equal(t, "M", updated.Options[0].Value)
+
+	entries := ledgerFor(t, db, product.ID)
+	require.Len(t, entries, 2)
+	assert.Equal(t, inventory.KindAdjustment, entries[1].Kind)
+	assert.Equal(t, 4, entries[1].Change)
+}
+
+// TestUpdateVariant_NotFound
+func TestUpdateVariant_NotFound(t *testing.T){
+	db, _, ctx := setupTestEnv(t)
+	variantService := NewVariantService(db)
+	inventoryLevel := 9
+
+	updated, err := variantService.UpdateVariant(ctx, "non-existent-id", models.UpdateProductVariantInput{Inventory: &inventoryLevel})
+
+	assert.Nil(t, updated)
+	assert.Equal(t, apperr.CodeNotFound, apperr.CodeOf(err))
+}
+
+// TestDeleteVariant_WritesOffStock Deleting a variant closes its ledger at zero
+// and takes its stock out of the product's totals.
+func TestDeleteVariant_WritesOffStock(t *testing.T){
+	db, productService, ctx := setupTestEnv(t)
+	variantService := NewVariantService(db)
+
+	// ---Arrange ---
+	product := createProduct(t, productService, "T-Shirt", 1999, 5)
+	variant, err := variantService.CreateVariant(ctx, models.CreateProductVariantInput{
+		ProductID: product.ID,
+		SKU:       "TSHIRT-XL",
+		Inventory: 3,
+	})
+	require.NoError(t, err)
+
+	// ---Act ---
+	deleted, err := variantService.DeleteVariant(ctx, variant.ID)
+
+	// ---Assert ---
+	require.NoError(t, err)
+	assert.True(t, deleted)
+
+	entries := ledgerFor(t, db, "TSHIRT-XL")
+	require.Len(t, entries, 2, "the ledger should outlive the variant")
+	assert.Equal(t, inventory.KindAdjustment, entries[1].Kind)
+	assert.Equal(t, -3, entries[1].Change)
+	assert.Equal(t, 0, entries[1].Balance)
+	assert.Equal(t, "variant deleted", entries[1].Reason)
+
+	updated, err := productService.GetProductByID(ctx, product.ID, false)
+	require.NoError(t, err)
+	assert.Equal(t, 5, updated.Inventory)
+
+	_, err = variantService.GetVariantBySKU(ctx, "TSHIRT-XL")
+	assert.Error(t, err, "variant should be gone")
+}
+
+// TestDeleteVariant_OutOfStock A variant without stock is deleted without a ledger entry.
+func TestDeleteVariant_OutOfStock(t *testing.T){
+	db, productService, ctx := setupTestEnv(t)
+	variantService := NewVariantService(db)
+
+	product := createProduct(t, productService, "T-Shirt", 1999, 5)
+	variant, err := variantService.CreateVariant(ctx, models.CreateProductVariantInput{
+		ProductID: product.ID,
+		SKU:       "TSHIRT-XL",
+	})
+	require.NoError(t, err)
+
+	_, err = variantService.DeleteVariant(ctx, variant.ID)
+	require.NoError(t, err)
+	assert.Empty(t, ledgerFor(t, db, "TSHIRT-XL"))
+}
+
+// TestDeleteVariant_OpenOrders A variant cannot be deleted while PENDING or
+// PAID orders hold its stock; finished and deleted orders do not count.
+func TestDeleteVariant_OpenOrders(t *testing.T){
+	db, productService, ctx := setupTestEnv(t)
+	variantService := NewVariantService(db)
+	migrateOrders(t, db)
+
+	// ---Arrange ---
+	product := createProduct(t, productService, "T-Shirt", 1999, 5)
+	variant, err := variantService.CreateVariant(ctx, models.CreateProductVariantInput{
+		ProductID: product.ID,
+		SKU:       "TSHIRT-XL",
+		Inventory: 3,
+	})
+	require.NoError(t, err)
+	insertOrder(t, db, "order_shipped", "SHIPPED", "TSHIRT-XL", 1)
+	insertOrder(t, db, "order_paid", "PAID", "TSHIRT-XL", 1)
+
+	// ---Act ---
+	deleted, err := variantService.DeleteVariant(ctx, variant.ID)
+
+	// ---Assert ---
+	assert.False(t, deleted)
+	assert.Equal(t, apperr.CodeConflict, apperr.CodeOf(err))
+	assert.Len(t, ledgerFor(t, db, "TSHIRT-XL"), 1, "nothing should be written off")
+
+	// Once the open order is deleted the variant can go
+	require.NoError(t, db.Exec(`UPDATE orders SET deleted_at = now() WHERE id = 'order_paid'`).Error)
+	deleted, err = variantService.DeleteVariant(ctx, variant.ID)
+	require.NoError(t, err)
+	assert.True(t, deleted)
+}
+
+// TestDeleteVariant_NotFound
+func TestDeleteVariant_NotFound(t *testing.T){
+	db, _, ctx := setupTestEnv(t)
+	variantService := NewVariantService(db)
+
+	deleted, err := variantService.DeleteVariant(ctx, "non-existent-id")
+
+	assert.False(t, deleted)
+	assert.Equal(t, apperr.CodeNotFound, apperr.CodeOf(err))
+}
+
+// TestAdjustStock_Insufficient Stock cannot be adjusted below zero.
+func TestAdjustStock_Insufficient(t *testing.T){
+	db, productService, ctx := setupTestEnv(t)
+	variantService := NewVariantService(db)
+
+	product := createProduct(t, productService, "T-Shirt", 1999, 5)
+
+	_, err := variantService.AdjustStock(ctx, product.ID, -6, "stock count")
+
+	assert.ErrorIs(t, err, inventory.ErrInsufficientStock)
+	assert.Len(t, ledgerFor(t, db, product.ID), 1)
+}
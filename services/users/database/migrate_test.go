@@ -0,0 +1,91 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/docker/go-connections/nat"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// setupTestDB starts a temporary Postgres container using testcontainers-go.
+// Requires Docker to be running.
+func setupTestDB(t *testing.T) *gorm.DB {
+	ctx := context.Background()
+	req := testcontainers.ContainerRequest{
+		Image: "postgres:15",
+		Env: map[string]string{
+			"POSTGRES_USER":     "testuser",
+			"POSTGRES_PASSWORD": "testpass",
+			"POSTGRES_DB":       "testdb",
+		},
+		ExposedPorts: []string{"5432/tcp"},
+		WaitingFor: wait.ForSQL("5432/tcp", "postgres", func(host string, port nat.Port) string {
+			return fmt.Sprintf("host=%s port=%s user=testuser password=testpass dbname=testdb sslmode=disable", host, port.Port())
+		}).WithStartupTimeout(60 * time.Second),
+	}
+
+	pgContainer, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: req,
+		Started:          true,
+	})
+	require.NoError(t, err)
+	testcontainers.CleanupContainer(t, pgContainer)
+
+	host, _ := pgContainer.Host(ctx)
+	port, _ := pgContainer.MappedPort(ctx, "5432/tcp")
+
+	dsn := fmt.Sprintf("host=%s port=%s user=testuser password=testpass dbname=testdb sslmode=disable", host, port.Port())
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+	return db
+}
+
+// TestMigrations_DuplicateEmails upgrades a legacy database whose accounts
+// share emails that differ only in case. The oldest account keeps the email
+// and the others are renamed, so the unique index can be built.
+func TestMigrations_DuplicateEmails(t *testing.T) {
+	db := setupTestDB(t)
+
+	// --- Arrange --- the users table AutoMigrate left behind
+	for _, stmt := range []string{
+		`CREATE TABLE users (id text PRIMARY KEY, name text, email text, password text, role text, active boolean)`,
+		`INSERT INTO users (id, name, email) VALUES
+			('u2', 'Alice', 'Alice@Example.com '),
+			('u1', 'Alice too', 'alice@example.com'),
+			('u3', 'Bob', 'bob@example.com'),
+			('u4', 'Nobody', NULL),
+			('u5', 'Nobody either', NULL)`,
+	} {
+		require.NoError(t, db.Exec(stmt).Error)
+	}
+
+	// --- Act ---
+	m, err := Migrator(db)
+	require.NoError(t, err)
+	_, err = m.Up(context.Background())
+	require.NoError(t, err)
+
+	// --- Assert --- existing users share a created_at, so the lower ID wins
+	var users []struct {
+		ID    string
+		Email *string
+	}
+	require.NoError(t, db.Raw(`SELECT id, email FROM users ORDER BY id`).Scan(&users).Error)
+	require.Len(t, users, 5)
+	assert.Equal(t, "alice@example.com", *users[0].Email)
+	assert.Equal(t, "dup-u2+alice@example.com", *users[1].Email)
+	assert.Equal(t, "bob@example.com", *users[2].Email)
+	assert.Nil(t, users[3].Email)
+
+	err = db.Exec(`INSERT INTO users (id, email) VALUES ('u6', 'BOB@example.com')`).Error
+	assert.Error(t, err, "emails should be unique regardless of case")
+}
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS users (
+    id       text PRIMARY KEY,
+    name     text,
+    email    text,
+    password text,
+    role     text,
+    active   boolean
+);
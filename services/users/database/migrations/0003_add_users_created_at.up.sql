@@ -0,0 +1,5 @@
+-- Users are paged in (created_at, id) order. Existing users all get the
+-- time of the migration and fall back to ID order among themselves.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS created_at timestamptz NOT NULL DEFAULT now();
+
+CREATE INDEX IF NOT EXISTS idx_users_created_at_id ON users (created_at, id);
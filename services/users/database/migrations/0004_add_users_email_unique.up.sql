@@ -0,0 +1,15 @@
+-- Emails are stored trimmed and lower-cased, and unique regardless of case.
+UPDATE users SET email = lower(trim(email)) WHERE email <> lower(trim(email));
+
+-- Accounts that already share an email leave it with the oldest of them,
+-- by (created_at, id). The others become dup-<id>+<email> so they stay
+-- findable and can be merged or given a new email by hand.
+UPDATE users u SET email = 'dup-' || u.id || '+' || u.email
+FROM (
+    SELECT id, row_number() OVER (PARTITION BY email ORDER BY created_at, id) AS n
+    FROM users
+    WHERE email IS NOT NULL
+) ranked
+WHERE ranked.id = u.id AND ranked.n > 1;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_lower ON users (lower(email));
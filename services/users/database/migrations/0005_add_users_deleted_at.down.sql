@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_users_email_lower;
+CREATE UNIQUE INDEX idx_users_email_lower ON users (lower(email));
+
+DROP INDEX IF EXISTS idx_users_deleted_at;
+ALTER TABLE users DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,9 @@
+-- Deleted users are kept, with deleted_at set, until they are purged after
+-- the retention window. Only live users need unique emails, so an email is
+-- free again once its account is deleted.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+
+DROP INDEX IF EXISTS idx_users_email_lower;
+CREATE UNIQUE INDEX idx_users_email_lower ON users (lower(email)) WHERE deleted_at IS NULL;
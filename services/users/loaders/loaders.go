@@ -0,0 +1,20 @@
+// Package loaders holds the request-scoped DataLoaders used to resolve
+// federated User references in batches.
+package loaders
+
+import (
+	"github.com/tagaertner/e-commerce-graphql/pkg/loader"
+	"github.com/tagaertner/e-commerce-graphql/services/users/models"
+	"github.com/tagaertner/e-commerce-graphql/services/users/services"
+)
+
+// Loaders are created per request, so cached results never outlive it.
+type Loaders struct {
+	UserByID *loader.Loader[string, *models.User]
+}
+
+func New(users *services.UserService) *Loaders {
+	return &Loaders{
+		UserByID: loader.New(users.GetUsersByIDs, loader.NotFound),
+	}
+}
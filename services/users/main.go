@@ -1,16 +1,25 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"log"
 	"net/http"
 	"os"
     "flag"
     "fmt"
+    "time"
 
 	"github.com/99designs/gqlgen/graphql/handler"
 	"github.com/99designs/gqlgen/graphql/handler/transport"
 	"github.com/99designs/gqlgen/graphql/playground"
+	dbconn "github.com/tagaertner/e-commerce-graphql/pkg/db"
+	"github.com/tagaertner/e-commerce-graphql/pkg/apperr"
+	"github.com/tagaertner/e-commerce-graphql/pkg/auth"
+	"github.com/tagaertner/e-commerce-graphql/pkg/loader"
+	"github.com/tagaertner/e-commerce-graphql/pkg/validate"
 	"github.com/tagaertner/e-commerce-graphql/services/users/generated"
+	"github.com/tagaertner/e-commerce-graphql/services/users/loaders"
 	"github.com/tagaertner/e-commerce-graphql/services/users/resolvers"
 	"github.com/tagaertner/e-commerce-graphql/services/users/database" 
     "github.com/joho/godotenv"
@@ -37,7 +46,7 @@ func main() {
 	testDB := flag.Bool("test-db", false, "Test DB connection and exit")
 	flag.Parse()
 
-	db := database.Connect()
+	db := dbconn.MustOpen(dbconn.ConfigFromEnv())
 
 	if *testDB {
 		sqlDB, err := db.DB()
@@ -52,6 +61,14 @@ func main() {
 		return 
 	}
 
+    // "migrate up|down|status" manages the schema and exits
+    if flag.Arg(0) == "migrate" {
+        if err := database.Migrate(db, flag.Args()[1:]); err != nil {
+            log.Fatalf("❌ migrate: %v", err)
+        }
+        return
+    }
+
     database.RunMigrations(db)
 
 	port := os.Getenv("PORT")
@@ -59,17 +76,36 @@ func main() {
 		port = "4002"
 	}
 
+	// Access tokens are signed with JWT_PRIVATE_KEY (PEM, PKCS#8 Ed25519)
+	signingKey := os.Getenv("JWT_PRIVATE_KEY")
+	if signingKey == "" {
+		log.Println("⚠️  JWT_PRIVATE_KEY not set, using a throwaway signing key")
+	}
+	issuer := os.Getenv("JWT_ISSUER")
+	if issuer == "" {
+		issuer = "e-commerce-users"
+	}
+	tokens, err := services.NewTokenIssuer(signingKey, issuer, durationEnv("ACCESS_TOKEN_TTL", 15*time.Minute))
+	if err != nil {
+		log.Fatalf("❌ Failed to load JWT signing key: %v", err)
+	}
+
 	// Pass db into resolver
 	userService := services.NewUserService(db)
+	authService := services.NewAuthService(db, userService, tokens, durationEnv("REFRESH_TOKEN_TTL", 30*24*time.Hour))
+
+    go purgeDeletedUsers(userService, durationEnv("DELETED_RETENTION", 30*24*time.Hour), time.Hour)
 
 	resolver := &resolvers.Resolver{
 		UserService: userService,
+		AuthService: authService,
 	}
 
 	srv := handler.NewDefaultServer(
 		generated.NewExecutableSchema(
 			generated.Config{
-				Resolvers: resolver,
+				Resolvers:  resolver,
+				Directives: generated.DirectiveRoot{Auth: auth.Directive, Constraint: validate.Constraint},
 			},
 		),
 	)
@@ -77,6 +113,14 @@ func main() {
 	// Enable introspection 
     srv.Use(extension.Introspection{})
 
+    // Errors carry extensions.code, and panics become INTERNAL errors
+    srv.SetErrorPresenter(apperr.Presenter)
+    srv.SetRecoverFunc(apperr.Recover)
+
+    // @constraint violations are gathered per field and reported together
+    srv.AroundRootFields(validate.Collect)
+    srv.AroundFields(validate.Enforce)
+
 	// Supported transport methods for GraphQL requests:
 	// - POST and GET for queries/mutations
 	// - WebSocket transport enables live data features like subscriptions
@@ -86,14 +130,49 @@ func main() {
 
 	// Routes
 	http.Handle("/", playground.Handler("GraphQL playground", "/query"))
-	http.Handle("/query", srv)
+	newLoaders := func() *loaders.Loaders { return loaders.New(userService) }
+	http.Handle("/query", apperr.Middleware(auth.Middleware(tokens.Verifier())(loader.Middleware(newLoaders)(srv))))
 
 	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		w.Write([]byte(`{"status": "healthy", "service": "users"}`))
 	})
 
+	// Public keys for verifying access tokens offline
+	http.HandleFunc("/.well-known/jwks.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		json.NewEncoder(w).Encode(authService.JWKS())
+	})
+
 	log.Printf("🛍️ [users] service ready at http://users:%s/query", port)
 	log.Fatal(http.ListenAndServe("0.0.0.0:"+port, nil))
 }
 
+// purgeDeletedUsers permanently removes users deleted longer than retention
+// ago every interval. Until then they can be restored.
+func purgeDeletedUsers(users *services.UserService, retention, interval time.Duration) {
+    for range time.Tick(interval) {
+        n, err := users.PurgeDeleted(context.Background(), time.Now().Add(-retention))
+        if err != nil {
+            log.Printf("⚠️  Failed to purge deleted users: %v", err)
+            continue
+        }
+        if n > 0 {
+            log.Printf("🗑️  Purged %d deleted users", n)
+        }
+    }
+}
+
+// durationEnv reads a duration such as "15m" from the environment.
+func durationEnv(name string, fallback time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("❌ Invalid %s %q: %v", name, value, err)
+	}
+	return d
+}
@@ -2,8 +2,24 @@
 
 package models
 
+import (
+	"github.com/tagaertner/e-commerce-graphql/pkg/pagination"
+)
+
 type Mutation struct {
 }
 
 type Query struct {
 }
+
+// A page of users, oldest first.
+type UserConnection struct {
+	Edges      []*UserEdge          `json:"edges"`
+	PageInfo   *pagination.PageInfo `json:"pageInfo"`
+	TotalCount int                  `json:"totalCount"`
+}
+
+type UserEdge struct {
+	Cursor string `json:"cursor"`
+	Node   *User  `json:"node"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// RefreshToken is the server-side record of an issued refresh token. Only a
+// SHA-256 hash of the token is stored. Each token can be used once: using it
+// revokes it and links to its replacement.
+type RefreshToken struct {
+    ID         string     `gorm:"primaryKey"`
+    UserID     string     `gorm:"not null;index"`
+    TokenHash  string     `gorm:"not null;uniqueIndex"`
+    ExpiresAt  time.Time  `gorm:"not null"`
+    RevokedAt  *time.Time
+    ReplacedBy *string
+    CreatedAt  time.Time
+}
+
+// AuthPayload is returned by login and refreshToken.
+type AuthPayload struct {
+    AccessToken  string `json:"accessToken"`
+    RefreshToken string `json:"refreshToken"`
+    TokenType    string `json:"tokenType"`
+    ExpiresIn    int    `json:"expiresIn"`
+    User         *User  `json:"user"`
+}
@@ -1,19 +1,35 @@
 package models
 
-type Role string
+import (
+    "time"
+
+    "github.com/tagaertner/e-commerce-graphql/pkg/auth"
+    "gorm.io/gorm"
+)
+
+// Role is shared with the other subgraphs through the auth package.
+type Role = auth.Role
 
 const (
-    RoleAdmin    Role = "ADMIN"
-    RoleCustomer Role = "CUSTOMER"
+    RoleAdmin    = auth.RoleAdmin
+    RoleCustomer = auth.RoleCustomer
 )
 
 type User struct {
     ID       string `json:"id" gorm:"primarykey"`
     Name     string `json:"name"`
-    Email    string `json:"email"`
-    Password string `json:"password"`
+    // Email is stored normalized and is unique among live users regardless
+    // of case.
+    Email    string `json:"email" gorm:"index:idx_users_email_lower,unique,expression:lower(email),where:deleted_at IS NULL"`
+    // PasswordHash is a bcrypt hash. It is never serialised or exposed in
+    // the GraphQL schema.
+    PasswordHash string `json:"-" gorm:"column:password"`
     Role     Role   `json:"role"`
     Active   bool   `json:"active"`
+    CreatedAt time.Time `json:"createdAt"`
+    // DeletedAt is set when the user is deleted. GORM leaves deleted users
+    // out of queries unless they are Unscoped.
+    DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
 type CreateUserInput struct {
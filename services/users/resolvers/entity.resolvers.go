@@ -8,13 +8,20 @@ package resolvers
 import (
 	"context"
 
+	"github.com/tagaertner/e-commerce-graphql/pkg/auth"
+	"github.com/tagaertner/e-commerce-graphql/pkg/loader"
 	"github.com/tagaertner/e-commerce-graphql/services/users/generated"
+	"github.com/tagaertner/e-commerce-graphql/services/users/loaders"
 	"github.com/tagaertner/e-commerce-graphql/services/users/models"
 )
 
 // FindUserByID is the resolver for the findUserByID field.
 func (r *entityResolver) FindUserByID(ctx context.Context, id string) (*models.User, error) {
-	return r.UserService.GetUserByID(ctx, id)
+	// Reached through Order.user, so the same ownership rule applies
+	if err := auth.RequireSelfOrAdmin(ctx, id); err != nil {
+		return nil, err
+	}
+	return loader.For[*loaders.Loaders](ctx).UserByID.Load(ctx, id)
 }
 
 // Entity returns generated.EntityResolver implementation.
@@ -7,6 +7,7 @@ import (
 
 type Resolver struct {
 	UserService *services.UserService
+	AuthService *services.AuthService
 }
 
 func NewResolver(db *gorm.DB) *Resolver {
@@ -7,13 +7,22 @@ package resolvers
 
 import (
 	"context"
+	"time"
 
+	"github.com/tagaertner/e-commerce-graphql/pkg/auth"
+	"github.com/tagaertner/e-commerce-graphql/pkg/pagination"
 	"github.com/tagaertner/e-commerce-graphql/services/users/generated"
 	"github.com/tagaertner/e-commerce-graphql/services/users/models"
+	"github.com/tagaertner/e-commerce-graphql/services/users/services"
 )
 
 // CreateUser is the resolver for the createUser field.
 func (r *mutationResolver) CreateUser(ctx context.Context, input models.CreateUserInput) (*models.User, error) {
+	if input.Role == models.RoleAdmin {
+		if err := auth.RequireAdmin(ctx); err != nil {
+			return nil, err
+		}
+	}
 	return r.UserService.CreateUser(
 		ctx,
 		input.Name,
@@ -26,6 +35,14 @@ func (r *mutationResolver) CreateUser(ctx context.Context, input models.CreateUs
 
 // UpdateUser is the resolver for the updateUser field.
 func (r *mutationResolver) UpdateUser(ctx context.Context, id string, input models.UpdateUserInput) (*models.User, error) {
+	if err := auth.RequireSelfOrAdmin(ctx, input.ID); err != nil {
+		return nil, err
+	}
+	if input.Role != nil || input.Active != nil {
+		if err := auth.RequireAdmin(ctx); err != nil {
+			return nil, err
+		}
+	}
 	return r.UserService.UpdateUser(ctx, &input)
 }
 
@@ -34,29 +51,78 @@ func (r *mutationResolver) DeleteUser(ctx context.Context, id string) (bool, err
 	return r.UserService.DeleteUser(ctx, id)
 }
 
+// RestoreUser is the resolver for the restoreUser field.
+func (r *mutationResolver) RestoreUser(ctx context.Context, id string) (*models.User, error) {
+	return r.UserService.RestoreUser(ctx, id)
+}
+
+// Login is the resolver for the login field.
+func (r *mutationResolver) Login(ctx context.Context, email string, password string) (*models.AuthPayload, error) {
+	return r.AuthService.Login(ctx, email, password)
+}
+
+// RefreshToken is the resolver for the refreshToken field.
+func (r *mutationResolver) RefreshToken(ctx context.Context, refreshToken string) (*models.AuthPayload, error) {
+	return r.AuthService.Refresh(ctx, refreshToken)
+}
+
+// Logout is the resolver for the logout field.
+func (r *mutationResolver) Logout(ctx context.Context, refreshToken string) (bool, error) {
+	return r.AuthService.Logout(ctx, refreshToken)
+}
+
 // Users is the resolver for the users field.
-func (r *queryResolver) Users(ctx context.Context) ([]*models.User, error) {
-	users, err := r.UserService.GetAllUsers(ctx)
+func (r *queryResolver) Users(ctx context.Context, first *int, after *string, last *int, before *string, includeDeleted *bool) (*models.UserConnection, error) {
+	page, err := pagination.Args{First: first, After: after, Last: last, Before: before}.Page(services.UserSort)
 	if err != nil {
 		return nil, err
 	}
-	return users, nil
+
+	users, err := r.UserService.GetUsersPage(ctx, page, includeDeleted != nil && *includeDeleted)
+	if err != nil {
+		return nil, err
+	}
+	return ToGraphQLUserConnection(users), nil
 }
 
 // User is the resolver for the user field.
-func (r *queryResolver) User(ctx context.Context, id string) (*models.User, error) {
-	user, err := r.UserService.GetUserByID(ctx, id)
+func (r *queryResolver) User(ctx context.Context, id string, includeDeleted *bool) (*models.User, error) {
+	if err := auth.RequireSelfOrAdmin(ctx, id); err != nil {
+		return nil, err
+	}
+	withDeleted, err := auth.AdminFlag(ctx, includeDeleted)
+	if err != nil {
+		return nil, err
+	}
+	user, err := r.UserService.GetUserByID(ctx, id, withDeleted)
 	if err != nil {
 		return nil, err
 	}
 	return user, nil
 }
 
+// UserByEmail is the resolver for the userByEmail field.
+func (r *queryResolver) UserByEmail(ctx context.Context, email string) (*models.User, error) {
+	return r.UserService.GetUserByEmail(ctx, email)
+}
+
+// DeletedAt is the resolver for the deletedAt field.
+func (r *userResolver) DeletedAt(ctx context.Context, obj *models.User) (*time.Time, error) {
+	if !obj.DeletedAt.Valid {
+		return nil, nil
+	}
+	return &obj.DeletedAt.Time, nil
+}
+
 // Mutation returns generated.MutationResolver implementation.
 func (r *Resolver) Mutation() generated.MutationResolver { return &mutationResolver{r} }
 
 // Query returns generated.QueryResolver implementation.
 func (r *Resolver) Query() generated.QueryResolver { return &queryResolver{r} }
 
+// User returns generated.UserResolver implementation.
+func (r *Resolver) User() generated.UserResolver { return &userResolver{r} }
+
 type mutationResolver struct{ *Resolver }
 type queryResolver struct{ *Resolver }
+type userResolver struct{ *Resolver }
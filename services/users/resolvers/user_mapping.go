@@ -3,6 +3,7 @@ package resolvers
 
 import (
   "github.com/tagaertner/e-commerce-graphql/services/users/models"
+  "github.com/tagaertner/e-commerce-graphql/services/users/services"
 )
 
 // Maps a GORM User (DB) to a GraphQL User (generated by gqlgen)
@@ -15,4 +16,13 @@ func ToGraphQLUser(u *models.User) *models.User {
     Role:   u.Role,
     Active: u.Active,
   }
-}
\ No newline at end of file
+}
+// Maps a page of users to a GraphQL UserConnection
+func ToGraphQLUserConnection(page *services.UserPage) *models.UserConnection {
+  edges := make([]*models.UserEdge, len(page.Users))
+  for i, u := range page.Users {
+    edges[i] = &models.UserEdge{Cursor: page.Cursors[i], Node: ToGraphQLUser(u)}
+  }
+  pageInfo := page.PageInfo
+  return &models.UserConnection{Edges: edges, PageInfo: &pageInfo, TotalCount: page.TotalCount}
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/tagaertner/e-commerce-graphql/pkg/apperr"
+	"github.com/tagaertner/e-commerce-graphql/pkg/auth"
+	"github.com/tagaertner/e-commerce-graphql/services/users/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInvalidRefreshToken is returned for refresh tokens that are unknown,
+// expired or already used.
+var ErrInvalidRefreshToken error = apperr.New(apperr.CodeUnauthenticated, "invalid or expired refresh token")
+
+type AuthService struct {
+	db         *gorm.DB
+	users      *UserService
+	tokens     *TokenIssuer
+	refreshTTL time.Duration
+}
+
+func NewAuthService(db *gorm.DB, users *UserService, tokens *TokenIssuer, refreshTTL time.Duration) *AuthService {
+	return &AuthService{db: db, users: users, tokens: tokens, refreshTTL: refreshTTL}
+}
+
+// Login verifies credentials and issues an access token and a refresh token.
+// Inactive users cannot log in.
+func (s *AuthService) Login(ctx context.Context, email, password string) (*models.AuthPayload, error) {
+	user, err := s.users.VerifyPassword(ctx, email, password)
+	if err != nil {
+		return nil, err
+	}
+	if !user.Active {
+		return nil, ErrInvalidCredentials
+	}
+
+	var payload *models.AuthPayload
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		refresh, _, err := s.storeRefreshToken(tx, user.ID, time.Now())
+		if err != nil {
+			return err
+		}
+		payload, err = s.payload(user, refresh)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// Refresh exchanges a refresh token for a new pair. The presented token is
+// revoked; presenting an already revoked token is treated as theft and
+// revokes every outstanding token of that user.
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string) (*models.AuthPayload, error) {
+	var payload *models.AuthPayload
+	var reused *models.RefreshToken
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var stored models.RefreshToken
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&stored, "token_hash = ?", hashRefreshToken(refreshToken)).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidRefreshToken
+			}
+			return err
+		}
+
+		now := time.Now()
+		if stored.RevokedAt != nil {
+			reused = &stored
+			return ErrInvalidRefreshToken
+		}
+		if now.After(stored.ExpiresAt) {
+			return ErrInvalidRefreshToken
+		}
+
+		// Deleted users cannot refresh
+		var user models.User
+		if err := tx.First(&user, "id = ?", stored.UserID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidRefreshToken
+			}
+			return err
+		}
+		if !user.Active {
+			return ErrInvalidRefreshToken
+		}
+
+		refresh, replacement, err := s.storeRefreshToken(tx, user.ID, now)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&stored).Updates(map[string]interface{}{
+			"revoked_at":  now,
+			"replaced_by": replacement.ID,
+		}).Error; err != nil {
+			return err
+		}
+
+		payload, err = s.payload(&user, refresh)
+		return err
+	})
+
+	if reused != nil {
+		if err := s.revokeAll(ctx, reused.UserID); err != nil {
+			return nil, err
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// Logout revokes a refresh token. Access tokens already issued stay valid
+// until they expire.
+func (s *AuthService) Logout(ctx context.Context, refreshToken string) (bool, error) {
+	result := s.db.WithContext(ctx).
+		Model(&models.RefreshToken{}).
+		Where("token_hash = ? AND revoked_at IS NULL", hashRefreshToken(refreshToken)).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// JWKS returns the public keys used to verify access tokens.
+func (s *AuthService) JWKS() auth.JWKS {
+	return s.tokens.JWKS()
+}
+
+func (s *AuthService) revokeAll(ctx context.Context, userID string) error {
+	return s.db.WithContext(ctx).
+		Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (s *AuthService) storeRefreshToken(tx *gorm.DB, userID string, now time.Time) (string, *models.RefreshToken, error) {
+	token, hash := newRefreshToken()
+	record := &models.RefreshToken{
+		ID:        "rt_" + newTokenID(),
+		UserID:    userID,
+		TokenHash: hash,
+		ExpiresAt: now.Add(s.refreshTTL),
+	}
+	if err := tx.Create(record).Error; err != nil {
+		return "", nil, err
+	}
+	return token, record, nil
+}
+
+func (s *AuthService) payload(user *models.User, refreshToken string) (*models.AuthPayload, error) {
+	access, err := s.tokens.IssueAccessToken(user, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return &models.AuthPayload{
+		AccessToken:  access,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(s.tokens.AccessTTL().Seconds()),
+		User:         user,
+	}, nil
+}
@@ -0,0 +1,39 @@
+package services
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/tagaertner/e-commerce-graphql/pkg/apperr"
+)
+
+// CodeEmailTaken is the code of ErrEmailTaken.
+const CodeEmailTaken apperr.Code = "EMAIL_TAKEN"
+
+// ErrEmailTaken is returned when another account already uses an email,
+// whatever its case.
+var ErrEmailTaken error = &apperr.Error{
+	Code:    CodeEmailTaken,
+	Message: "email is already taken",
+	Fields:  []apperr.FieldViolation{{Field: "email", Message: "is already taken"}},
+}
+
+// emailIndex is the unique index on lower(email).
+const emailIndex = "idx_users_email_lower"
+
+// NormalizeEmail returns email trimmed and lower-cased, the form emails are
+// stored and looked up in.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// emailTaken turns a violation of the unique email index into
+// ErrEmailTaken and returns any other error unchanged.
+func emailTaken(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == emailIndex {
+		return ErrEmailTaken
+	}
+	return err
+}
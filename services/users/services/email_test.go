@@ -0,0 +1,30 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/tagaertner/e-commerce-graphql/pkg/apperr"
+)
+
+func TestNormalizeEmail(t *testing.T) {
+	assert.Equal(t, "jane@example.com", NormalizeEmail("  Jane@Example.COM "))
+}
+
+// TestEmailTaken checks that only violations of the email index become
+// ErrEmailTaken.
+func TestEmailTaken(t *testing.T) {
+	violation := fmt.Errorf("insert user: %w", &pgconn.PgError{Code: "23505", ConstraintName: emailIndex})
+	err := emailTaken(violation)
+	assert.ErrorIs(t, err, ErrEmailTaken)
+	assert.Equal(t, CodeEmailTaken, apperr.CodeOf(err))
+
+	other := &pgconn.PgError{Code: "23505", ConstraintName: "users_pkey"}
+	assert.Same(t, error(other), emailTaken(other))
+
+	plain := errors.New("connection refused")
+	assert.Equal(t, plain, emailTaken(plain))
+}
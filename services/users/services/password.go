@@ -0,0 +1,50 @@
+package services
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"github.com/tagaertner/e-commerce-graphql/pkg/apperr"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// passwordCost is the bcrypt work factor for new hashes. Hashes made with a
+// lower cost are upgraded the next time the user signs in.
+const passwordCost = 12
+
+// ErrInvalidCredentials is returned when an email and password do not match.
+// It deliberately does not say which of the two was wrong.
+var ErrInvalidCredentials error = apperr.New(apperr.CodeUnauthenticated, "invalid email or password")
+
+// dummyHash is compared against when no user matches an email, so a failed
+// lookup takes as long as a wrong password.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), passwordCost)
+
+// hashPassword returns a bcrypt hash of plain.
+func hashPassword(plain string) (string, error) {
+	if strings.TrimSpace(plain) == "" {
+		return "", apperr.Field("password", "must not be empty")
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(plain), passwordCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+// checkPassword reports whether plain matches the stored password and whether
+// the stored value should be replaced with a fresh hash. Rows written before
+// passwords were hashed hold plaintext; those still verify, once, and are
+// flagged for rehashing.
+func checkPassword(stored, plain string) (ok, rehash bool) {
+	cost, err := bcrypt.Cost([]byte(stored))
+	if err != nil {
+		// Legacy plaintext row
+		ok = stored != "" && subtle.ConstantTimeCompare([]byte(stored), []byte(plain)) == 1
+		return ok, ok
+	}
+	if bcrypt.CompareHashAndPassword([]byte(stored), []byte(plain)) != nil {
+		return false, false
+	}
+	return true, cost < passwordCost
+}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// TestHashPassword_NeverStoresPlaintext checks that hashes verify but do not
+// contain the password.
+func TestHashPassword_NeverStoresPlaintext(t *testing.T) {
+	hashed, err := hashPassword("password123")
+	require.NoError(t, err)
+	assert.NotContains(t, hashed, "password123")
+
+	ok, rehash := checkPassword(hashed, "password123")
+	assert.True(t, ok)
+	assert.False(t, rehash, "fresh hashes should not need rehashing")
+
+	ok, _ = checkPassword(hashed, "wrong")
+	assert.False(t, ok)
+}
+
+// TestCheckPassword_LegacyPlaintext covers rows written before hashing: they
+// verify once and are flagged for rehashing.
+func TestCheckPassword_LegacyPlaintext(t *testing.T) {
+	ok, rehash := checkPassword("password123", "password123")
+	assert.True(t, ok)
+	assert.True(t, rehash)
+
+	ok, rehash = checkPassword("password123", "nope")
+	assert.False(t, ok)
+	assert.False(t, rehash)
+
+	ok, _ = checkPassword("", "")
+	assert.False(t, ok, "empty stored passwords must never verify")
+}
+
+// TestCheckPassword_UpgradesWeakCost flags hashes below the current cost.
+func TestCheckPassword_UpgradesWeakCost(t *testing.T) {
+	weak, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	ok, rehash := checkPassword(string(weak), "password123")
+	assert.True(t, ok)
+	assert.True(t, rehash)
+}
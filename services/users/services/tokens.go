@@ -0,0 +1,128 @@
+package services
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/tagaertner/e-commerce-graphql/pkg/auth"
+	"github.com/tagaertner/e-commerce-graphql/services/users/models"
+)
+
+// TokenIssuer signs access tokens with an Ed25519 key and publishes the
+// public half as a JWKS so other services can verify tokens offline.
+type TokenIssuer struct {
+	key       ed25519.PrivateKey
+	keyID     string
+	issuer    string
+	accessTTL time.Duration
+}
+
+// NewTokenIssuer creates an issuer from a PEM encoded PKCS#8 Ed25519 private
+// key. An empty key generates a throwaway one, which is only suitable for
+// local development: tokens stop verifying when the process restarts.
+func NewTokenIssuer(privateKeyPEM, issuer string, accessTTL time.Duration) (*TokenIssuer, error) {
+	var key ed25519.PrivateKey
+	if privateKeyPEM == "" {
+		_, generated, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		key = generated
+	} else {
+		block, _ := pem.Decode([]byte(privateKeyPEM))
+		if block == nil {
+			return nil, errors.New("invalid signing key: not PEM encoded")
+		}
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid signing key: %w", err)
+		}
+		edKey, ok := parsed.(ed25519.PrivateKey)
+		if !ok {
+			return nil, errors.New("invalid signing key: not an Ed25519 key")
+		}
+		key = edKey
+	}
+
+	// The key ID is derived from the public key so it changes with the key
+	pub := key.Public().(ed25519.PublicKey)
+	sum := sha256.Sum256(pub)
+
+	return &TokenIssuer{
+		key:       key,
+		keyID:     hex.EncodeToString(sum[:8]),
+		issuer:    issuer,
+		accessTTL: accessTTL,
+	}, nil
+}
+
+// IssueAccessToken signs a short-lived token for user.
+func (t *TokenIssuer) IssueAccessToken(user *models.User, now time.Time) (string, error) {
+	claims := auth.Claims{
+		Role: user.Role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    t.issuer,
+			Subject:   user.ID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(t.accessTTL)),
+			ID:        newTokenID(),
+		},
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	token.Header["kid"] = t.keyID
+	return token.SignedString(t.key)
+}
+
+// AccessTTL is how long access tokens stay valid.
+func (t *TokenIssuer) AccessTTL() time.Duration {
+	return t.accessTTL
+}
+
+// JWKS returns the public verification keys.
+func (t *TokenIssuer) JWKS() auth.JWKS {
+	return auth.NewJWKS(t.publicKeys())
+}
+
+// Verifier checks access tokens signed by this issuer. The users service
+// verifies its own tokens without fetching the JWKS over HTTP.
+func (t *TokenIssuer) Verifier() *auth.Verifier {
+	return auth.NewStaticVerifier(t.issuer, t.publicKeys())
+}
+
+func (t *TokenIssuer) publicKeys() map[string]ed25519.PublicKey {
+	return map[string]ed25519.PublicKey{t.keyID: t.key.Public().(ed25519.PublicKey)}
+}
+
+// newRefreshToken returns an opaque random refresh token and the hash under
+// which it is stored.
+func newRefreshToken() (token, hash string) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	token = base64.RawURLEncoding.EncodeToString(buf)
+	return token, hashRefreshToken(token)
+}
+
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func newTokenID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(buf)
+}
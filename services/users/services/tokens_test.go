@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tagaertner/e-commerce-graphql/pkg/auth"
+	"github.com/tagaertner/e-commerce-graphql/services/users/models"
+)
+
+// TestTokenIssuer_AccessTokenVerifiesWithJWKS checks that an access token can
+// be verified using nothing but the published JWKS.
+func TestTokenIssuer_AccessTokenVerifiesWithJWKS(t *testing.T) {
+	issuer, err := NewTokenIssuer("", "test-issuer", 15*time.Minute)
+	require.NoError(t, err)
+
+	user := &models.User{ID: "user1", Role: models.RoleAdmin}
+	signed, err := issuer.IssueAccessToken(user, time.Now())
+	require.NoError(t, err)
+
+	jwk := issuer.JWKS().Keys[0]
+	x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+	require.NoError(t, err)
+
+	var claims auth.Claims
+	token, err := jwt.ParseWithClaims(signed, &claims, func(token *jwt.Token) (interface{}, error) {
+		assert.Equal(t, jwk.Kid, token.Header["kid"])
+		return ed25519.PublicKey(x), nil
+	}, jwt.WithValidMethods([]string{"EdDSA"}), jwt.WithIssuer("test-issuer"))
+	require.NoError(t, err)
+	require.True(t, token.Valid)
+
+	assert.Equal(t, "user1", claims.Subject)
+	assert.Equal(t, models.RoleAdmin, claims.Role)
+}
+
+// TestTokenIssuer_ExpiredTokenRejected ensures tokens stop verifying after
+// their TTL.
+func TestTokenIssuer_ExpiredTokenRejected(t *testing.T) {
+	issuer, err := NewTokenIssuer("", "test-issuer", time.Minute)
+	require.NoError(t, err)
+
+	signed, err := issuer.IssueAccessToken(&models.User{ID: "user1", Role: models.RoleCustomer}, time.Now().Add(-2*time.Minute))
+	require.NoError(t, err)
+
+	_, err = jwt.ParseWithClaims(signed, &auth.Claims{}, func(*jwt.Token) (interface{}, error) {
+		return issuer.key.Public(), nil
+	})
+	assert.ErrorIs(t, err, jwt.ErrTokenExpired)
+}
+
+// TestTokenIssuer_VerifierAcceptsOwnTokens checks the verifier used by the
+// auth middleware against tokens from the same issuer.
+func TestTokenIssuer_VerifierAcceptsOwnTokens(t *testing.T) {
+	issuer, err := NewTokenIssuer("", "test-issuer", time.Minute)
+	require.NoError(t, err)
+
+	signed, err := issuer.IssueAccessToken(&models.User{ID: "user1", Role: models.RoleCustomer}, time.Now())
+	require.NoError(t, err)
+
+	caller, err := issuer.Verifier().Verify(context.Background(), signed)
+	require.NoError(t, err)
+	assert.Equal(t, &auth.Caller{UserID: "user1", Role: auth.RoleCustomer}, caller)
+
+	other, err := NewTokenIssuer("", "test-issuer", time.Minute)
+	require.NoError(t, err)
+	_, err = other.Verifier().Verify(context.Background(), signed)
+	assert.Error(t, err)
+}
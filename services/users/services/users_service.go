@@ -2,10 +2,16 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/tagaertner/e-commerce-graphql/pkg/apperr"
+	"github.com/tagaertner/e-commerce-graphql/pkg/pagination"
+	"github.com/tagaertner/e-commerce-graphql/pkg/validate"
 	"github.com/tagaertner/e-commerce-graphql/services/users/models"
+	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
@@ -17,15 +23,84 @@ func NewUserService(db *gorm.DB) *UserService {
 	return &UserService{db: db}
 }
 
+// scope returns the service's DB for ctx, including deleted users when
+// includeDeleted is set.
+func (s *UserService) scope(ctx context.Context, includeDeleted bool) *gorm.DB {
+	db := s.db.WithContext(ctx)
+	if includeDeleted {
+		db = db.Unscoped()
+	}
+	return db
+}
+
 // Query
-func (s *UserService) GetUserByID(ctx context.Context, id string) (*models.User, error) {
+
+// GetUserByID returns a user. Deleted users are only found with
+// includeDeleted.
+func (s *UserService) GetUserByID(ctx context.Context, id string, includeDeleted bool) (*models.User, error) {
+	var user models.User
+	if err := s.scope(ctx, includeDeleted).First(&user, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetUserByEmail returns the user with an email, whatever its case.
+func (s *UserService) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
 	var user models.User
-	if err := s.db.First(&user, "id = ?", id).Error; err != nil {
+	if err := s.db.WithContext(ctx).First(&user, "lower(email) = ?", NormalizeEmail(email)).Error; err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
+// GetUsersByIDs loads many users in one query, keyed by ID. Unknown IDs are
+// left out. Deleted users are included, so the orders they placed still
+// show who placed them.
+func (s *UserService) GetUsersByIDs(ctx context.Context, ids []string) (map[string]*models.User, error) {
+	var users []*models.User
+	if err := s.scope(ctx, true).Where("id IN ?", ids).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*models.User, len(users))
+	for _, u := range users {
+		byID[u.ID] = u
+	}
+	return byID, nil
+}
+
+// UserSort is the order users are paged in, oldest first.
+var UserSort = pagination.Sort{Name: "CREATED_AT", Column: "created_at", Key: time.Time{}}
+
+// UserPage is one page of users, oldest first, with each user's cursor.
+type UserPage struct {
+	Users      []*models.User
+	Cursors    []string
+	PageInfo   pagination.PageInfo
+	TotalCount int
+}
+
+// GetUsersPage returns one page of users in (created_at, id) order, with
+// deleted users only if includeDeleted is set.
+func (s *UserService) GetUsersPage(ctx context.Context, page pagination.Page, includeDeleted bool) (*UserPage, error) {
+	scope := s.scope(ctx, includeDeleted).Model(&models.User{})
+
+	var total int64
+	if err := scope.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	var users []*models.User
+	if err := page.Apply(scope.Session(&gorm.Session{})).Find(&users).Error; err != nil {
+		return nil, err
+	}
+
+	users, cursors, info := pagination.Trim(users, page, func(u *models.User) pagination.Cursor {
+		return pagination.Cursor{Key: u.CreatedAt, ID: u.ID}
+	})
+	return &UserPage{Users: users, Cursors: cursors, PageInfo: info, TotalCount: int(total)}, nil
+}
+
 func (s *UserService) GetAllUsers(ctx context.Context) ([]*models.User, error) {
 	var users []*models.User
 	if err := s.db.Find(&users).Error; err != nil {
@@ -35,27 +110,65 @@ func (s *UserService) GetAllUsers(ctx context.Context) ([]*models.User, error) {
 }
 
 // Mutation
+
+// CreateUser creates a user with a normalized email. It fails with
+// ErrEmailTaken if another account already uses the email.
 func (s *UserService)CreateUser(ctx context.Context, name, email string, password string, role models.Role, active bool) (*models.User, error){
+	email = NormalizeEmail(email)
+	var violations validate.Violations
+	if strings.TrimSpace(name) == "" {
+		violations.Add("name", "must not be blank")
+	}
+	if !validate.IsEmail(email) {
+		violations.Add("email", "must be an email address")
+	}
+	if strings.TrimSpace(password) == "" {
+		violations.Add("password", "must not be empty")
+	}
+	if err := violations.Err(); err != nil {
+		return nil, err
+	}
+
+	hashed, err := hashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
 	user := &models.User{
 		ID: fmt.Sprintf("user_%d", time.Now().UnixNano()),
 		Name:   name,
 		Email:  email,
-		Password: password,
+		PasswordHash: hashed,
 		Role:   role,  
 		Active: true, 
 	} 
 
-	// TODO: Hash password before saving
-	// hashed, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-
 	if err := s.db.WithContext(ctx).Create(user).Error; err !=nil {
-		return nil, err
+		return nil, emailTaken(err)
 	}
 	return user, nil
 
 }
 
+// UpdateUser changes the fields set in input. It fails with ErrEmailTaken if
+// another account already uses the new email.
 func (s *UserService) UpdateUser(ctx context.Context, input *models.UpdateUserInput) (*models.User, error) {
+	if input.Email != nil {
+		email := NormalizeEmail(*input.Email)
+		input.Email = &email
+	}
+
+	var violations validate.Violations
+	if input.Name != nil && strings.TrimSpace(*input.Name) == "" {
+		violations.Add("name", "must not be blank")
+	}
+	if input.Email != nil && !validate.IsEmail(*input.Email) {
+		violations.Add("email", "must be an email address")
+	}
+	if err := violations.Err(); err != nil {
+		return nil, err
+	}
+
 	var user models.User
 
 	// Find the user first
@@ -82,19 +195,93 @@ func (s *UserService) UpdateUser(ctx context.Context, input *models.UpdateUserIn
 	// Apply updates only if something to update
 	if len(updates) > 0 {
 		if err := s.db.WithContext(ctx).Model(&user).Updates(updates).Error; err != nil {
-			return nil, err
+			return nil, emailTaken(err)
 		}
 	}
 
 	return &user, nil
 }
 
+// DeleteUser soft-deletes a user and revokes their refresh tokens. The user
+// can be restored until PurgeDeleted removes them.
 func (s *UserService)DeleteUser(ctx context.Context, id string) (bool, error){
-	result := s.db.WithContext(ctx).Delete(&models.User{}, "id = ?", id)
-	if result.Error != nil{
-		return false, result.Error
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&models.User{}, "id = ?", id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return apperr.NotFound("user %s not found", id)
+		}
+		return tx.Model(&models.RefreshToken{}).
+			Where("user_id = ? AND revoked_at IS NULL", id).
+			Update("revoked_at", time.Now()).Error
+	})
+	if err != nil {
+		return false, err
 	}
-
 	return true, nil
 }
 
+// RestoreUser undoes DeleteUser. It fails with ErrEmailTaken if another
+// account took the user's email meanwhile.
+func (s *UserService) RestoreUser(ctx context.Context, id string) (*models.User, error) {
+	result := s.db.WithContext(ctx).Unscoped().
+		Model(&models.User{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		return nil, emailTaken(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, apperr.NotFound("deleted user %s not found", id)
+	}
+	return s.GetUserByID(ctx, id, false)
+}
+
+// PurgeDeleted permanently removes users deleted before cutoff, along with
+// their refresh tokens, and returns how many users it removed.
+func (s *UserService) PurgeDeleted(ctx context.Context, cutoff time.Time) (int64, error) {
+	var purged int64
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		deleted := tx.Unscoped().Model(&models.User{}).Select("id").Where("deleted_at < ?", cutoff)
+		if err := tx.Where("user_id IN (?)", deleted).Delete(&models.RefreshToken{}).Error; err != nil {
+			return err
+		}
+		result := tx.Unscoped().Where("deleted_at < ?", cutoff).Delete(&models.User{})
+		purged = result.RowsAffected
+		return result.Error
+	})
+	return purged, err
+}
+
+// VerifyPassword checks an email and password and returns the matching user.
+// Legacy plaintext passwords and hashes with an outdated cost are rehashed on
+// a successful check. Any mismatch returns ErrInvalidCredentials.
+func (s *UserService) VerifyPassword(ctx context.Context, email, password string) (*models.User, error) {
+	var user models.User
+	if err := s.db.WithContext(ctx).First(&user, "lower(email) = ?", NormalizeEmail(email)).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Spend the same time as a real comparison
+			bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	ok, rehash := checkPassword(user.PasswordHash, password)
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+
+	if rehash {
+		hashed, err := hashPassword(password)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.db.WithContext(ctx).Model(&user).Update("password", hashed).Error; err != nil {
+			return nil, err
+		}
+	}
+	return &user, nil
+}
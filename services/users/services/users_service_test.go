@@ -10,6 +10,8 @@ import (
 	_ "github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/tagaertner/e-commerce-graphql/pkg/apperr"
+	"github.com/tagaertner/e-commerce-graphql/pkg/pagination"
 	"github.com/tagaertner/e-commerce-graphql/services/users/models"
 	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/wait"
@@ -50,7 +52,7 @@ func setupTestDB(t *testing.T) *gorm.DB {
     db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
     require.NoError(t, err)
 
-    require.NoError(t, db.AutoMigrate(&models.User{}, &models.User{}))
+    require.NoError(t, db.AutoMigrate(&models.User{}, &models.RefreshToken{}))
     return db
 }
 
@@ -93,7 +95,7 @@ func TestGetUserByID_ReturnsUser_WhenExists(t *testing.T){
 	assert.NotEmpty(t, created.ID)
 
 	// Retrieve that user by its generated id
-	found, err := userService.GetUserByID(ctx, created.ID)
+	found, err := userService.GetUserByID(ctx, created.ID, false)
 	assert.NoError(t, err)
 	assert.Equal(t, created.ID, found.ID)
 
@@ -111,7 +113,7 @@ func TestGetUserByID_ReturnsError_WhenUserNotFound(t *testing.T){
 	created, err := userService.GetUserByID(
 		ctx,
 		"",
-		
+		false,
 	)
 	assert.EqualError(t, err,"record not found")
 	assert.Nil(t, created, "user should return error when userID is not found")
@@ -225,6 +227,74 @@ func TestCreateUser_ReturnsError_WhenDatabaseFails(t *testing.T){
 	assert.Error(t, err, "invalid database")
 	assert.Nil(t, created, "user should not be created when userID or user name is invalid")
 }
+// TestCreateUser_HashesPassword ensures the stored password is a hash that
+// verifies, never the plaintext.
+func TestCreateUser_HashesPassword(t *testing.T){
+	db, userService, ctx := setupTestEnv(t)
+
+	created, err := userService.CreateUser(ctx, "Hash Test", "hash@test.com", "password123", models.RoleCustomer, true)
+	require.NoError(t, err)
+
+	var stored models.User
+	require.NoError(t, db.First(&stored, "id = ?", created.ID).Error)
+	assert.NotEqual(t, "password123", stored.PasswordHash, "password must not be stored in plaintext")
+
+	verified, err := userService.VerifyPassword(ctx, "hash@test.com", "password123")
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, verified.ID)
+
+	_, err = userService.VerifyPassword(ctx, "hash@test.com", "wrong")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+}
+
+// TestCreateUser_RejectsDuplicateEmail checks that emails are unique
+// whatever their case, and that lookups ignore case too.
+func TestCreateUser_RejectsDuplicateEmail(t *testing.T){
+	_, userService, ctx := setupTestEnv(t)
+
+	created, err := userService.CreateUser(ctx, "First", "Dup@Test.com", "password123", models.RoleCustomer, true)
+	require.NoError(t, err)
+	assert.Equal(t, "dup@test.com", created.Email, "email should be stored normalized")
+
+	_, err = userService.CreateUser(ctx, "Second", " dup@test.COM", "password123", models.RoleCustomer, true)
+	assert.ErrorIs(t, err, ErrEmailTaken)
+
+	other, err := userService.CreateUser(ctx, "Other", "other@test.com", "password123", models.RoleCustomer, true)
+	require.NoError(t, err)
+	_, err = userService.UpdateUser(ctx, &models.UpdateUserInput{ID: other.ID, Email: StringPointer("DUP@test.com")})
+	assert.ErrorIs(t, err, ErrEmailTaken)
+
+	found, err := userService.GetUserByEmail(ctx, "DUP@TEST.COM")
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, found.ID)
+}
+
+// TestVerifyPassword_RehashesLegacyPlaintext checks that a plaintext row from
+// before hashing still verifies and is rehashed on the spot.
+func TestVerifyPassword_RehashesLegacyPlaintext(t *testing.T){
+	db, userService, ctx := setupTestEnv(t)
+
+	legacy := models.User{
+		ID: "legacy1",
+		Name: "Legacy User",
+		Email: "legacy@test.com",
+		PasswordHash: "password123",
+		Role: models.RoleCustomer,
+		Active: true,
+	}
+	require.NoError(t, db.Create(&legacy).Error)
+
+	_, err := userService.VerifyPassword(ctx, "legacy@test.com", "password123")
+	require.NoError(t, err)
+
+	var stored models.User
+	require.NoError(t, db.First(&stored, "id = ?", "legacy1").Error)
+	assert.NotEqual(t, "password123", stored.PasswordHash, "legacy password should be rehashed after login")
+
+	_, err = userService.VerifyPassword(ctx, "legacy@test.com", "password123")
+	assert.NoError(t, err, "rehashed password should still verify")
+}
+
 // 	7.	TestCreateUser_SetsDefaultRoleAndActiveFields
 func TestCreateUser_SetsDefaultRoleAndActiveFields(t *testing.T){
  //TOdo
@@ -257,7 +327,7 @@ func TestUpdateUser_UpdatesProvidedFields(t *testing.T){
 	// ---Assert ---
 	require.NoError(t, err)
 	require.NotNil(t, update)
-	require.Equal(t, "nancy2Test@email.com ", update.Email)
+	require.Equal(t, "nancy2test@email.com", update.Email, "email should be stored normalized")
 }
 // 	9.	TestUpdateUser_DoesNotUpdateWhenNoFieldsProvided
 func TestUpdateUser_DoesNotUpdateWhenNoFieldsProvided (t *testing.T){
@@ -309,7 +379,86 @@ func TestUpdateUser_ReturnsError_WhenUserDoesNotExist (t *testing.T){
 
 // 🧪 DeleteUser
 // 	12.	TestDeleteUser_ByID_SuccessfullyDeletesUser
-// 	13.	TestDeleteUser_ByName_SuccessfullyDeletesUser
-// 	14.	TestDeleteUser_ReturnsFalse_WhenNoUserFound
-// 	15.	TestDeleteUser_ReturnsError_WhenDatabaseFails
+func TestDeleteUser_ByID_SuccessfullyDeletesUser(t *testing.T){
+	db, userService, ctx := setupTestEnv(t)
+
+	created, err := userService.CreateUser(ctx, "Gone Soon", "gone@test.com", "password123", models.RoleCustomer, true)
+	require.NoError(t, err)
+
+	deleted, err := userService.DeleteUser(ctx, created.ID)
+	require.NoError(t, err)
+	assert.True(t, deleted)
+
+	_, err = userService.GetUserByID(ctx, created.ID, false)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound, "deleted users are hidden by default")
+
+	found, err := userService.GetUserByID(ctx, created.ID, true)
+	require.NoError(t, err, "deleted users are kept until purged")
+	assert.True(t, found.DeletedAt.Valid)
+
+	// The email is free again while the user is deleted
+	_, err = userService.CreateUser(ctx, "Taker", "gone@test.com", "password123", models.RoleCustomer, true)
+	require.NoError(t, err)
+	_, err = userService.RestoreUser(ctx, created.ID)
+	assert.ErrorIs(t, err, ErrEmailTaken)
+
+	// Purging removes only users deleted before the cutoff
+	purged, err := userService.PurgeDeleted(ctx, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), purged)
+	purged, err = userService.PurgeDeleted(ctx, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+
+	var count int64
+	require.NoError(t, db.Unscoped().Model(&models.User{}).Where("id = ?", created.ID).Count(&count).Error)
+	assert.Zero(t, count)
+}
+
+// TestRestoreUser_UndoesDelete checks that a restored user is visible again.
+func TestRestoreUser_UndoesDelete(t *testing.T){
+	_, userService, ctx := setupTestEnv(t)
+
+	created, err := userService.CreateUser(ctx, "Back Again", "back@test.com", "password123", models.RoleCustomer, true)
+	require.NoError(t, err)
+	_, err = userService.DeleteUser(ctx, created.ID)
+	require.NoError(t, err)
+
+	restored, err := userService.RestoreUser(ctx, created.ID)
+	require.NoError(t, err)
+	assert.False(t, restored.DeletedAt.Valid)
+
+	_, err = userService.RestoreUser(ctx, created.ID)
+	assert.Equal(t, apperr.CodeNotFound, apperr.CodeOf(err), "only deleted users can be restored")
+}
+
+func TestGetUsersPage_CountsEveryUser(t *testing.T){
+	_, userService, ctx := setupTestEnv(t)
+
+	for _, email := range []string{"a@test.com", "b@test.com", "c@test.com"} {
+		_, err := userService.CreateUser(ctx, email, email, "password123", models.RoleCustomer, true)
+		require.NoError(t, err)
+	}
+	first := 2
+	page, err := pagination.Args{First: &first}.Page(UserSort)
+	require.NoError(t, err)
+
+	// The count must not pick up the page's order and limit
+	result, err := userService.GetUsersPage(ctx, page, false)
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.TotalCount)
+	require.Len(t, result.Users, 2)
+	assert.Equal(t, "a@test.com", result.Users[0].Email)
+	assert.True(t, result.PageInfo.HasNextPage)
+}
+
+// 	13.	TestDeleteUser_ReturnsNotFound_WhenNoUserFound
+func TestDeleteUser_ReturnsNotFound_WhenNoUserFound(t *testing.T){
+	_, userService, ctx := setupTestEnv(t)
+
+	deleted, err := userService.DeleteUser(ctx, "missing")
+	assert.False(t, deleted)
+	assert.Equal(t, apperr.CodeNotFound, apperr.CodeOf(err))
+}
+// 	14.	TestDeleteUser_ReturnsError_WhenDatabaseFails
 